@@ -0,0 +1,165 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+	"k8s.io/klog/v2"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg"
+	"github.com/ppc64le-cloud/pvsadm/pkg/config"
+)
+
+var Cmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the pvsadm configuration",
+	Long: `Manage the named profiles in the pvsadm configuration file(~/.pvsadm/config.yaml)
+
+Profiles hold the defaults for the global options, the profile is selected by the --profile, PVSADM_PROFILE environment
+variable or the current-profile in the config file in that order. Flags and environment variables override the profile.
+
+IBM Cloud environments can be added under the environments of the config file, e.g. for the private endpoints:
+
+  environments:
+    prod-private:
+      TPEndpoint: https://private.iam.cloud.ibm.com
+      RCEndpoint: https://private.resource-controller.cloud.ibm.com
+      PIEndpoint: private.{region}.power-iaas.cloud.ibm.com
+      GTEndpoint: https://tags.global-search-tagging.cloud.ibm.com
+      COSEndpoint: https://s3.private.{region}.cloud-object-storage.appdomain.cloud
+
+The endpoints of the environment in use can be overridden with the PVSADM_IAM_ENDPOINT, PVSADM_RC_ENDPOINT,
+PVSADM_PI_ENDPOINT, PVSADM_GT_ENDPOINT and PVSADM_COS_ENDPOINT environment variables.
+
+Examples:
+  # Create the ci profile with the default workspace and COS bucket
+  pvsadm config set env prod --profile ci
+  pvsadm config set instance-name upstream-core --profile ci
+  pvsadm config set bucket ci-images --profile ci
+  pvsadm config set bucket-region us-south --profile ci
+
+  # Use the ci profile by default
+  pvsadm config use-profile ci
+
+  # Purge the vms of the workspace configured in the staging profile
+  PVSADM_PROFILE=staging pvsadm purge vms --dry-run
+`,
+	// overrides the root PersistentPreRunE, the profiles aren't applied to the config commands
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return nil
+	},
+}
+
+var viewCmd = &cobra.Command{
+	Use:   "view",
+	Short: "View the pvsadm configuration",
+	Long:  `View the pvsadm configuration, API keys are redacted`,
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, c, err := load()
+		if err != nil {
+			return err
+		}
+		for _, p := range c.Profiles {
+			if p.APIKey != "" {
+				p.APIKey = "REDACTED"
+			}
+		}
+		out, err := yaml.Marshal(c)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(out))
+		return nil
+	},
+}
+
+var setCmd = &cobra.Command{
+	Use:   "set KEY VALUE",
+	Short: "Set a setting of the profile",
+	Long: fmt.Sprintf(`Set a setting of the profile in use, the profile is created if it doesn't exist, empty value unsets the setting
+
+Supported settings: %v
+`, config.Keys()),
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, c, err := load()
+		if err != nil {
+			return err
+		}
+		name := c.ProfileName(pkg.Options.Profile)
+		if name == "" {
+			name = config.DefaultProfile
+		}
+		if c.Profiles == nil {
+			c.Profiles = map[string]*config.Profile{}
+		}
+		p, ok := c.Profiles[name]
+		if !ok {
+			p = &config.Profile{}
+			c.Profiles[name] = p
+		}
+		if err := p.Set(args[0], args[1]); err != nil {
+			return err
+		}
+		if c.CurrentProfile == "" {
+			c.CurrentProfile = name
+		}
+		if err := c.Save(file); err != nil {
+			return err
+		}
+		klog.Infof("%s set for the profile: %s", args[0], name)
+		return nil
+	},
+}
+
+var useProfileCmd = &cobra.Command{
+	Use:   "use-profile NAME",
+	Short: "Set the current profile",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, c, err := load()
+		if err != nil {
+			return err
+		}
+		if _, ok := c.Profiles[args[0]]; !ok {
+			return fmt.Errorf("profile: %s not found in the config file", args[0])
+		}
+		c.CurrentProfile = args[0]
+		if err := c.Save(file); err != nil {
+			return err
+		}
+		klog.Infof("Switched to the profile: %s", args[0])
+		return nil
+	},
+}
+
+func load() (string, *config.Config, error) {
+	file, err := config.File()
+	if err != nil {
+		return "", nil, err
+	}
+	c, err := config.Load(file)
+	return file, c, err
+}
+
+func init() {
+	Cmd.AddCommand(viewCmd)
+	Cmd.AddCommand(setCmd)
+	Cmd.AddCommand(useProfileCmd)
+}
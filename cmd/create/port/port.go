@@ -39,7 +39,7 @@ var Cmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		opt := pkg.Options
 
-		c, err := client.NewClientWithEnv(opt.APIKey, opt.Environment, opt.Debug)
+		c, err := client.NewClientWithEnv(cmd.Context(), opt.APIKey, opt.Environment, opt.Debug)
 		if err != nil {
 			klog.Errorf("failed to create a session with IBM cloud: %v", err)
 			return err
@@ -89,8 +89,7 @@ var Cmd = &cobra.Command{
 		klog.Infof("Successfully created a port, id: %s", *port.PortID)
 
 		table := utils.NewTable()
-		table.Render([]*models.NetworkPort{port}, []string{})
-		return nil
+		return table.Render([]*models.NetworkPort{port}, []string{})
 	},
 }
 
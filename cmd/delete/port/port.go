@@ -37,7 +37,7 @@ var Cmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		opt := pkg.Options
 
-		c, err := client.NewClientWithEnv(opt.APIKey, opt.Environment, opt.Debug)
+		c, err := client.NewClientWithEnv(cmd.Context(), opt.APIKey, opt.Environment, opt.Debug)
 		if err != nil {
 			klog.Errorf("failed to create a session with IBM cloud: %v", err)
 			return err
@@ -15,6 +15,7 @@
 package dhcp
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net"
@@ -55,11 +56,17 @@ authoritative;
 %s
 `
 
-func doEvery(d time.Duration, f func()) {
+// doEvery calls the f immediately and then every d till the ctx is cancelled
+func doEvery(ctx context.Context, d time.Duration, f func()) {
 	ticker := time.NewTicker(d)
 	defer ticker.Stop()
-	for ; true; <-ticker.C {
+	for {
 		f()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
 	}
 }
 
@@ -74,17 +81,7 @@ func (ro RoutersOption) IndentedString(prefix string) string {
 	return s + ro[len(ro)-1].String() + ";\n"
 }
 
-func syncDHCPD() {
-	c, err := client.NewClientWithEnv(pkg.Options.APIKey, pkg.Options.Environment, false)
-	if err != nil {
-		klog.Fatalf("failed to create a session with IBM cloud: %v", err)
-	}
-
-	pvmclient, err := client.NewPVMClientWithEnv(c, pkg.Options.InstanceID, "", "prod")
-	if err != nil {
-		klog.Fatalf("failed to create a PVM client: %v", err)
-	}
-
+func syncDHCPD(pvmclient *client.PVMClient) {
 	n, err := pvmclient.NetworkClient.Get(networkID)
 
 	ipv4Addr, ipv4Net, err := net.ParseCIDR(*n.Cidr)
@@ -144,13 +141,30 @@ var Cmd = &cobra.Command{
 		if pkg.Options.InstanceID == "" {
 			return fmt.Errorf("--instance-id is required")
 		}
-		if pkg.Options.APIKey == "" {
-			return fmt.Errorf("api-key can't be empty, pass the token via --api-key or set IBMCLOUD_API_KEY environment variable")
+		if _, err := client.NewAuthenticator(pkg.Options.APIKey); err != nil {
+			return err
 		}
 		return nil
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		go doEvery(2*time.Minute, syncDHCPD)
+		// clients refresh the IAM access token on their own, hence created once for the lifetime of the command
+		c, err := client.NewClientWithEnv(cmd.Context(), pkg.Options.APIKey, pkg.Options.Environment, false)
+		if err != nil {
+			return fmt.Errorf("failed to create a session with IBM cloud: %v", err)
+		}
+
+		pvmclient, err := client.NewPVMClientWithEnv(c, pkg.Options.InstanceID, "", "prod")
+		if err != nil {
+			return fmt.Errorf("failed to create a PVM client: %v", err)
+		}
+
+		synced := make(chan struct{})
+		go func() {
+			defer close(synced)
+			doEvery(cmd.Context(), 2*time.Minute, func() {
+				syncDHCPD(pvmclient)
+			})
+		}()
 
 		watcher, err := fsnotify.NewWatcher()
 		if err != nil {
@@ -188,7 +202,13 @@ var Cmd = &cobra.Command{
 		if err != nil {
 			klog.Fatal(err)
 		}
-		<-done
+		select {
+		case <-done:
+		case <-cmd.Context().Done():
+			// lets the in-flight sync finish writing the configuration
+			<-synced
+			klog.Info("stopped syncing the dhcpd configuration")
+		}
 		return nil
 	},
 }
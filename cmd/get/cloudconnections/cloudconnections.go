@@ -0,0 +1,94 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudconnections
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/IBM-Cloud/power-go-client/power/models"
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg"
+	"github.com/ppc64le-cloud/pvsadm/pkg/client"
+	"github.com/ppc64le-cloud/pvsadm/pkg/utils"
+)
+
+// row is the cloud connection along with the workspace
+type row struct {
+	client.Workspace
+	*models.CloudConnection
+}
+
+var Cmd = &cobra.Command{
+	Use:   "cloudconnections",
+	Short: "Get the PowerVS cloud connections",
+	Long: `Get the PowerVS cloud connections
+
+Examples:
+  # List all the cloud connections
+  pvsadm get cloudconnections --instance-name upstream-core
+`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if !pkg.Options.InstanceSelected() {
+			return fmt.Errorf("--instance-id, --instance-name, --instance-regexp or --all-instances required")
+		}
+		if pkg.Options.Since != 0 && pkg.Options.Before != 0 {
+			return fmt.Errorf("--since and --before options can not be set at a time")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opt := pkg.Options
+
+		c, err := client.NewClientWithEnv(cmd.Context(), opt.APIKey, opt.Environment, opt.Debug)
+		if err != nil {
+			klog.Errorf("failed to create a session with IBM cloud: %v", err)
+			return err
+		}
+
+		pvmclients, err := client.NewPVMClientsWithEnv(c, opt.Environment)
+		if err != nil {
+			return err
+		}
+
+		results := make([][]row, len(pvmclients))
+		err = client.ForEach(pvmclients, func(i int, pvmclient *client.PVMClient) error {
+			items, err := pvmclient.CloudConnectionClient.GetAllPurgeable(opt.Before, opt.Since, opt.ExprRegexp)
+			if err != nil {
+				return err
+			}
+			for _, item := range items {
+				results[i] = append(results[i], row{Workspace: pvmclient.Workspace(), CloudConnection: item})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		var rows []row
+		for _, r := range results {
+			rows = append(rows, r...)
+		}
+		return utils.NewTable().Render(rows, client.ExcludeWorkspace(pvmclients, []string{"classic", "vpc", "networks"}))
+	},
+}
+
+func init() {
+	Cmd.Flags().StringVar(&pkg.Options.Expr, "regexp", "", "Regular Expressions for filtering the selection")
+	Cmd.Flags().DurationVar(&pkg.Options.Since, "since", 0*time.Second, "List resources since mentioned duration(format: 99h99m00s), mutually exclusive with --before")
+	Cmd.Flags().DurationVar(&pkg.Options.Before, "before", 0*time.Second, "List resources before mentioned duration(format: 99h99m00s), mutually exclusive with --since")
+}
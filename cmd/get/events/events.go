@@ -16,6 +16,7 @@ package events
 
 import (
 	"fmt"
+	"github.com/IBM-Cloud/power-go-client/power/models"
 	"github.com/ppc64le-cloud/pvsadm/pkg"
 	"github.com/ppc64le-cloud/pvsadm/pkg/client"
 	"github.com/ppc64le-cloud/pvsadm/pkg/utils"
@@ -28,37 +29,56 @@ var (
 	since time.Duration
 )
 
+// row is the event along with the workspace
+type row struct {
+	client.Workspace
+	*models.Event
+}
+
 var Cmd = &cobra.Command{
 	Use:   "events",
 	Short: "Get Powervs events",
 	Long:  `Get the PowerVS events`,
 	PreRunE: func(cmd *cobra.Command, args []string) error {
-		if pkg.Options.InstanceID == "" && pkg.Options.InstanceName == "" {
-			return fmt.Errorf("--instance-name or --instance-name required")
+		if !pkg.Options.InstanceSelected() {
+			return fmt.Errorf("--instance-id, --instance-name, --instance-regexp or --all-instances required")
 		}
 		return nil
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		opt := pkg.Options
 
-		c, err := client.NewClientWithEnv(opt.APIKey, opt.Environment, opt.Debug)
+		c, err := client.NewClientWithEnv(cmd.Context(), opt.APIKey, opt.Environment, opt.Debug)
 		if err != nil {
 			klog.Errorf("failed to create a session with IBM cloud: %v", err)
 			return err
 		}
 
-		pvmclient, err := client.NewPVMClientWithEnv(c, opt.InstanceID, opt.InstanceName, opt.Environment)
+		pvmclients, err := client.NewPVMClientsWithEnv(c, opt.Environment)
 		if err != nil {
 			return err
 		}
-		events, err := pvmclient.EventsClient.GetPcloudEventsGetsince(since)
+
+		results := make([][]row, len(pvmclients))
+		err = client.ForEach(pvmclients, func(i int, pvmclient *client.PVMClient) error {
+			events, err := pvmclient.EventsClient.GetPcloudEventsGetsince(since)
+			if err != nil {
+				return err
+			}
+			for _, e := range events.Payload.Events {
+				results[i] = append(results[i], row{Workspace: pvmclient.Workspace(), Event: e})
+			}
+			return nil
+		})
 		if err != nil {
 			return err
 		}
+		var rows []row
+		for _, r := range results {
+			rows = append(rows, r...)
+		}
 		table := utils.NewTable()
-		table.Render(events.Payload.Events, []string{"user", "timestamp"})
-
-		return nil
+		return table.Render(rows, client.ExcludeWorkspace(pvmclients, []string{"user", "timestamp"}))
 	},
 }
 
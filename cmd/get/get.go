@@ -15,22 +15,103 @@
 package get
 
 import (
+	"strings"
+
 	"github.com/spf13/cobra"
 
+	"github.com/ppc64le-cloud/pvsadm/cmd/get/cloudconnections"
 	"github.com/ppc64le-cloud/pvsadm/cmd/get/events"
+	"github.com/ppc64le-cloud/pvsadm/cmd/get/images"
+	"github.com/ppc64le-cloud/pvsadm/cmd/get/instances"
+	"github.com/ppc64le-cloud/pvsadm/cmd/get/networks"
+	"github.com/ppc64le-cloud/pvsadm/cmd/get/placementgroups"
 	"github.com/ppc64le-cloud/pvsadm/cmd/get/ports"
+	"github.com/ppc64le-cloud/pvsadm/cmd/get/sharedprocessorpools"
+	"github.com/ppc64le-cloud/pvsadm/cmd/get/snapshots"
+	"github.com/ppc64le-cloud/pvsadm/cmd/get/sshkeys"
+	"github.com/ppc64le-cloud/pvsadm/cmd/get/vms"
+	"github.com/ppc64le-cloud/pvsadm/cmd/get/volumes"
 	"github.com/ppc64le-cloud/pvsadm/pkg"
+	"github.com/ppc64le-cloud/pvsadm/pkg/utils"
 )
 
 var Cmd = &cobra.Command{
 	Use:   "get",
 	Short: "Get the resources",
-	Long:  `Get the resources`,
+	Long: `Get the resources
+
+Examples:
+  # List all the PowerVS instances in the account
+  pvsadm get instances
+
+  # List the virtual machines created in the last 24hrs
+  pvsadm get vms --instance-name upstream-core --since 24h
+
+  # List the virtual machines across all the PowerVS instances starts with ci- in the account
+  pvsadm get vms --instance-regexp "^ci-.*"
+
+  # List the volumes of the multiple PowerVS instances
+  pvsadm get volumes --instance-name upstream-core,upstream-ci
+
+  # List the volumes tagged with owner:ci and without the tag keep
+  pvsadm get volumes --instance-name upstream-core --selector "owner=ci,!keep"
+
+  # Get the details of a volume by name or ID
+  pvsadm get volumes --instance-name upstream-core k8s-cluster-boot-volume
+
+  # List the vm snapshots created before 72hrs
+  pvsadm get snapshots --instance-name upstream-core --before 72h
+
+  # Get the events in the last 24hrs
+  pvsadm get events --instance-name upstream-core
+
+  # Get the ports of a network in json format
+  pvsadm get ports --instance-name upstream-core --network ocp-net -o json
+
+  # Get only the IP addresses of the ports
+  pvsadm get ports --instance-name upstream-core --network ocp-net -o jsonpath='{.items[*].ipAddress}'
+`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Code block to execute the strict check mentioned in the rootcmd for the environment.
+		// This block is needed as a workaround mentioned in https://github.com/spf13/cobra/issues/252
+		// if multiple PersistentPreRunE present in the code
+		root := cmd
+		for ; root.HasParent(); root = root.Parent() {
+		}
+		if err := root.PersistentPreRunE(cmd, args); err != nil {
+			return err
+		}
+
+		if _, err := utils.ParseOutput(pkg.Options.Output); err != nil {
+			return err
+		}
+		if _, err := pkg.ParseSelector(pkg.Options.Selector); err != nil {
+			return err
+		}
+		var err error
+		if pkg.Options.ExprRegexp, err = pkg.ParseExpr(pkg.Options.Expr); err != nil {
+			return err
+		}
+		return nil
+	},
 }
 
 func init() {
 	Cmd.AddCommand(events.Cmd)
 	Cmd.AddCommand(ports.Cmd)
-	Cmd.PersistentFlags().StringVarP(&pkg.Options.InstanceID, "instance-id", "i", "", "Instance ID of the PowerVS instance")
-	Cmd.PersistentFlags().StringVarP(&pkg.Options.InstanceName, "instance-name", "n", "", "Instance name of the PowerVS")
+	Cmd.AddCommand(vms.Cmd)
+	Cmd.AddCommand(volumes.Cmd)
+	Cmd.AddCommand(images.Cmd)
+	Cmd.AddCommand(networks.Cmd)
+	Cmd.AddCommand(instances.Cmd)
+	Cmd.AddCommand(snapshots.Cmd)
+	Cmd.AddCommand(sshkeys.Cmd)
+	Cmd.AddCommand(cloudconnections.Cmd)
+	Cmd.AddCommand(placementgroups.Cmd)
+	Cmd.AddCommand(sharedprocessorpools.Cmd)
+	Cmd.PersistentFlags().StringVarP(&pkg.Options.InstanceID, "instance-id", "i", "", "Instance ID of the PowerVS instance, comma separated for multiple instances")
+	Cmd.PersistentFlags().StringVarP(&pkg.Options.InstanceName, "instance-name", "n", "", "Instance name of the PowerVS, comma separated for multiple instances")
+	Cmd.PersistentFlags().StringVar(&pkg.Options.InstanceRegexp, "instance-regexp", "", "Regular Expressions for selecting the PowerVS instances by name, not supported by sshkeys as they belong to the account")
+	Cmd.PersistentFlags().BoolVar(&pkg.Options.AllInstances, "all-instances", false, "Select all the PowerVS instances in the account, not supported by sshkeys as they belong to the account")
+	Cmd.PersistentFlags().StringVarP(&pkg.Options.Output, "output", "o", utils.OutputTable, "Output format, supported are: ["+strings.Join(utils.OutputFormats, ", ")+"]")
 }
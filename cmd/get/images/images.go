@@ -0,0 +1,115 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package images
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/IBM-Cloud/power-go-client/power/models"
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg"
+	"github.com/ppc64le-cloud/pvsadm/pkg/client"
+	"github.com/ppc64le-cloud/pvsadm/pkg/utils"
+)
+
+// row is the image along with the workspace
+type row struct {
+	client.Workspace
+	*models.ImageReference
+}
+
+var Cmd = &cobra.Command{
+	Use:   "images [NAME|ID]",
+	Short: "Get the PowerVS images",
+	Long: `Get the PowerVS images, lists all the images if the name or ID is not mentioned
+
+Examples:
+  # List all the images
+  pvsadm get images --instance-name upstream-core
+
+  # List the images created in the last 24hrs
+  pvsadm get images --instance-name upstream-core --since 24h
+
+  # Get the details of an image by name or ID
+  pvsadm get images --instance-name upstream-core rhcos-46
+`,
+	Args: cobra.MaximumNArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if !pkg.Options.InstanceSelected() {
+			return fmt.Errorf("--instance-id, --instance-name, --instance-regexp or --all-instances required")
+		}
+		if pkg.Options.Since != 0 && pkg.Options.Before != 0 {
+			return fmt.Errorf("--since and --before options can not be set at a time")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opt := pkg.Options
+
+		c, err := client.NewClientWithEnv(cmd.Context(), opt.APIKey, opt.Environment, opt.Debug)
+		if err != nil {
+			klog.Errorf("failed to create a session with IBM cloud: %v", err)
+			return err
+		}
+
+		pvmclients, err := client.NewPVMClientsWithEnv(c, opt.Environment)
+		if err != nil {
+			return err
+		}
+
+		table := utils.NewTable()
+		if len(args) == 1 {
+			if len(pvmclients) != 1 {
+				return fmt.Errorf("name or ID can't be used along with the multiple instances")
+			}
+			pvmclient := pvmclients[0]
+			image, err := pvmclient.ImgClient.GetByNameOrID(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to get the image: %v", err)
+			}
+			return table.RenderItem(image, []string{"servers", "volumes", "taskref"})
+		}
+
+		results := make([][]row, len(pvmclients))
+		err = client.ForEach(pvmclients, func(i int, pvmclient *client.PVMClient) error {
+			images, err := pvmclient.ImgClient.GetAllPurgeable(opt.Before, opt.Since, opt.Expr)
+			if err != nil {
+				return fmt.Errorf("failed to get the list of images: %v", err)
+			}
+			for _, img := range images {
+				results[i] = append(results[i], row{Workspace: pvmclient.Workspace(), ImageReference: img})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		var rows []row
+		for _, r := range results {
+			rows = append(rows, r...)
+		}
+		return table.Render(rows, client.ExcludeWorkspace(pvmclients, []string{"href", "specifications"}))
+	},
+}
+
+func init() {
+	Cmd.Flags().StringVar(&pkg.Options.Expr, "regexp", "", "Regular Expressions for filtering the selection")
+	Cmd.Flags().StringVar(&pkg.Options.Selector, "selector", "", "Tag selector for filtering the selection, supports key=value, key!=value, key and !key separated by comma")
+	Cmd.Flags().DurationVar(&pkg.Options.Since, "since", 0*time.Second, "List resources since mentioned duration(format: 99h99m00s), mutually exclusive with --before")
+	Cmd.Flags().DurationVar(&pkg.Options.Before, "before", 0*time.Second, "List resources before mentioned duration(format: 99h99m00s), mutually exclusive with --since")
+}
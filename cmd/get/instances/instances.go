@@ -0,0 +1,132 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instances
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/IBM-Cloud/bluemix-go/models"
+	"github.com/go-openapi/strfmt"
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg"
+	"github.com/ppc64le-cloud/pvsadm/pkg/client"
+	"github.com/ppc64le-cloud/pvsadm/pkg/utils"
+)
+
+// Instance is the summary of the PowerVS service instance
+type Instance struct {
+	Name            string          `json:"name"`
+	GUID            string          `json:"guid"`
+	Zone            string          `json:"zone"`
+	State           string          `json:"state"`
+	ResourceGroupID string          `json:"resourceGroupID"`
+	CRN             string          `json:"crn"`
+	CreationDate    strfmt.DateTime `json:"creationDate"`
+}
+
+func newInstance(svc models.ServiceInstanceV2) *Instance {
+	ins := &Instance{
+		Name:            svc.Name,
+		GUID:            svc.Guid,
+		Zone:            svc.RegionID,
+		State:           svc.State,
+		ResourceGroupID: svc.ResourceGroupID,
+		CRN:             svc.Crn.String(),
+	}
+	if svc.MetadataType != nil && svc.CreatedAt != nil {
+		ins.CreationDate = strfmt.DateTime(*svc.CreatedAt)
+	}
+	return ins
+}
+
+var Cmd = &cobra.Command{
+	Use:   "instances [NAME|ID]",
+	Short: "Get the PowerVS instances",
+	Long: `Get the PowerVS service instances in the account, lists all the instances if the name or ID is not mentioned
+
+Examples:
+  # List all the PowerVS instances
+  pvsadm get instances
+
+  # List the PowerVS instances starts with upstream-
+  pvsadm get instances --regexp "^upstream-.*"
+
+  # Get the details of a PowerVS instance by name or ID
+  pvsadm get instances upstream-core
+`,
+	Args: cobra.MaximumNArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if pkg.Options.Since != 0 && pkg.Options.Before != 0 {
+			return fmt.Errorf("--since and --before options can not be set at a time")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opt := pkg.Options
+
+		c, err := client.NewClientWithEnv(cmd.Context(), opt.APIKey, opt.Environment, opt.Debug)
+		if err != nil {
+			klog.Errorf("failed to create a session with IBM cloud: %v", err)
+			return err
+		}
+
+		svcs, err := c.ListServiceInstancesByType(client.PowerVSServiceType)
+		if err != nil {
+			return fmt.Errorf("failed to list the PowerVS instances: %v", err)
+		}
+
+		table := utils.NewTable()
+		if len(args) == 1 {
+			var matched []*Instance
+			for _, svc := range svcs {
+				if svc.Guid == args[0] {
+					return table.RenderItem(newInstance(svc), nil)
+				}
+				if svc.Name == args[0] {
+					matched = append(matched, newInstance(svc))
+				}
+			}
+			switch len(matched) {
+			case 0:
+				return fmt.Errorf("instance: %s not found", args[0])
+			case 1:
+				return table.RenderItem(matched[0], nil)
+			}
+			return table.Render(matched, []string{"crn"})
+		}
+
+		var instances []*Instance
+		for _, svc := range svcs {
+			if opt.ExprRegexp != nil && !opt.ExprRegexp.MatchString(svc.Name) {
+				continue
+			}
+			ins := newInstance(svc)
+			if !pkg.IsPurgeable(time.Time(ins.CreationDate), opt.Before, opt.Since) {
+				continue
+			}
+			instances = append(instances, ins)
+		}
+		return table.Render(instances, []string{"crn"})
+	},
+}
+
+func init() {
+	Cmd.Flags().StringVar(&pkg.Options.Expr, "regexp", "", "Regular Expressions for filtering the selection")
+	Cmd.Flags().DurationVar(&pkg.Options.Since, "since", 0*time.Second, "List resources since mentioned duration(format: 99h99m00s), mutually exclusive with --before")
+	Cmd.Flags().DurationVar(&pkg.Options.Before, "before", 0*time.Second, "List resources before mentioned duration(format: 99h99m00s), mutually exclusive with --since")
+}
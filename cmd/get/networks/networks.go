@@ -0,0 +1,109 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networks
+
+import (
+	"fmt"
+
+	"github.com/IBM-Cloud/power-go-client/power/models"
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg"
+	"github.com/ppc64le-cloud/pvsadm/pkg/client"
+	"github.com/ppc64le-cloud/pvsadm/pkg/utils"
+)
+
+// row is the network along with the workspace
+type row struct {
+	client.Workspace
+	*models.NetworkReference
+}
+
+var Cmd = &cobra.Command{
+	Use:   "networks [NAME|ID]",
+	Short: "Get the PowerVS networks",
+	Long: `Get the PowerVS networks, lists all the networks if the name or ID is not mentioned
+
+Examples:
+  # List all the networks
+  pvsadm get networks --instance-name upstream-core
+
+  # List the networks starts with ocp-
+  pvsadm get networks --instance-name upstream-core --regexp "^ocp-.*"
+
+  # Get the details of a network by name or ID
+  pvsadm get networks --instance-name upstream-core ocp-net
+`,
+	Args: cobra.MaximumNArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if !pkg.Options.InstanceSelected() {
+			return fmt.Errorf("--instance-id, --instance-name, --instance-regexp or --all-instances required")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opt := pkg.Options
+
+		c, err := client.NewClientWithEnv(cmd.Context(), opt.APIKey, opt.Environment, opt.Debug)
+		if err != nil {
+			klog.Errorf("failed to create a session with IBM cloud: %v", err)
+			return err
+		}
+
+		pvmclients, err := client.NewPVMClientsWithEnv(c, opt.Environment)
+		if err != nil {
+			return err
+		}
+
+		table := utils.NewTable()
+		if len(args) == 1 {
+			if len(pvmclients) != 1 {
+				return fmt.Errorf("name or ID can't be used along with the multiple instances")
+			}
+			pvmclient := pvmclients[0]
+			network, err := pvmclient.NetworkClient.GetByNameOrID(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to get the network: %v", err)
+			}
+			return table.RenderItem(network, []string{"ipaddressmetrics"})
+		}
+
+		results := make([][]row, len(pvmclients))
+		err = client.ForEach(pvmclients, func(i int, pvmclient *client.PVMClient) error {
+			networks, err := pvmclient.NetworkClient.GetAllPurgeable(0, 0, opt.Expr)
+			if err != nil {
+				return fmt.Errorf("failed to get the list of networks: %v", err)
+			}
+			for _, n := range networks {
+				results[i] = append(results[i], row{Workspace: pvmclient.Workspace(), NetworkReference: n})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		var rows []row
+		for _, r := range results {
+			rows = append(rows, r...)
+		}
+		return table.Render(rows, client.ExcludeWorkspace(pvmclients, []string{"href"}))
+	},
+}
+
+func init() {
+	Cmd.Flags().StringVar(&pkg.Options.Expr, "regexp", "", "Regular Expressions for filtering the selection")
+	Cmd.Flags().StringVar(&pkg.Options.Selector, "selector", "", "Tag selector for filtering the selection, supports key=value, key!=value, key and !key separated by comma")
+}
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/IBM-Cloud/power-go-client/power/models"
 	"github.com/spf13/cobra"
 	"k8s.io/klog/v2"
 
@@ -30,64 +31,89 @@ var (
 	network string
 )
 
+// row is the network port along with the workspace
+type row struct {
+	client.Workspace
+	*models.NetworkPort
+}
+
 var Cmd = &cobra.Command{
 	Use:   "ports",
 	Short: "Get PowerVS network ports",
-	Long:  `Get PowerVS network ports`,
+	Long: `Get PowerVS network ports
+
+The network is looked up by ID or name in every selected instance, i.e. use the name along with the multiple
+instances.
+`,
 	PreRunE: func(cmd *cobra.Command, args []string) error {
-		if pkg.Options.InstanceID == "" && pkg.Options.InstanceName == "" {
-			return fmt.Errorf("--instance-id or --instance-name required")
+		if !pkg.Options.InstanceSelected() {
+			return fmt.Errorf("--instance-id, --instance-name, --instance-regexp or --all-instances required")
 		}
 		return nil
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		opt := pkg.Options
 
-		c, err := client.NewClientWithEnv(opt.APIKey, opt.Environment, opt.Debug)
+		c, err := client.NewClientWithEnv(cmd.Context(), opt.APIKey, opt.Environment, opt.Debug)
 		if err != nil {
 			klog.Errorf("failed to create a session with IBM cloud: %v", err)
 			return err
 		}
 
-		pvmclient, err := client.NewPVMClientWithEnv(c, opt.InstanceID, opt.InstanceName, opt.Environment)
+		pvmclients, err := client.NewPVMClientsWithEnv(c, opt.Environment)
 		if err != nil {
 			return err
 		}
 
-		networks, err := pvmclient.NetworkClient.GetAll()
+		results := make([][]row, len(pvmclients))
+		err = client.ForEach(pvmclients, func(i int, pvmclient *client.PVMClient) error {
+			netID, err := networkID(pvmclient, network)
+			if err != nil {
+				return err
+			}
+			ports, err := pvmclient.NetworkClient.GetAllPort(netID)
+			if err != nil {
+				return fmt.Errorf("failed to get the ports of the instance %s, err: %v", pvmclient.InstanceName, err)
+			}
+			for _, port := range ports.Ports {
+				results[i] = append(results[i], row{Workspace: pvmclient.Workspace(), NetworkPort: port})
+			}
+			return nil
+		})
 		if err != nil {
-			return fmt.Errorf("failed to get the networks, err: %v", err)
+			return err
 		}
-
-		var networkNames, networkIDs []string
-		for _, net := range networks.Networks {
-			networkIDs = append(networkIDs, *net.NetworkID)
-			networkNames = append(networkNames, *net.Name)
+		var rows []row
+		for _, r := range results {
+			rows = append(rows, r...)
 		}
+		table := utils.NewTable()
+		return table.Render(rows, client.ExcludeWorkspace(pvmclients, []string{"href", "pvminstance"}))
+	},
+}
 
-		var netID string
+// networkID returns the ID of the network in the instance by ID or name, preference will be given to the ID over name
+func networkID(pvmclient *client.PVMClient, network string) (string, error) {
+	networks, err := pvmclient.NetworkClient.GetAll()
+	if err != nil {
+		return "", fmt.Errorf("failed to get the networks of the instance %s, err: %v", pvmclient.InstanceName, err)
+	}
 
-		if utils.Contains(networkIDs, network) {
-			netID = network
-		} else if utils.Contains(networkNames, network) {
-			for _, n := range networks.Networks {
-				if *n.Name == network {
-					netID = *n.NetworkID
-				}
-			}
-		} else {
-			return fmt.Errorf("not able to find network: \"%s\" by ID or name in the list: ids:[%s], names: [%s]", network, strings.Join(networkIDs, ","), strings.Join(networkNames, ","))
-		}
+	var networkNames, networkIDs []string
+	for _, net := range networks.Networks {
+		networkIDs = append(networkIDs, *net.NetworkID)
+		networkNames = append(networkNames, *net.Name)
+	}
 
-		ports, err := pvmclient.NetworkClient.GetAllPort(netID)
-		if err != nil {
-			return fmt.Errorf("failed to get the ports, err: %v", err)
+	if utils.Contains(networkIDs, network) {
+		return network, nil
+	}
+	for _, n := range networks.Networks {
+		if *n.Name == network {
+			return *n.NetworkID, nil
 		}
-
-		table := utils.NewTable()
-		table.Render(ports.Ports, []string{"href", "pvminstance"})
-		return nil
-	},
+	}
+	return "", fmt.Errorf("not able to find network: \"%s\" by ID or name in the instance %s, list: ids:[%s], names: [%s]", network, pvmclient.InstanceName, strings.Join(networkIDs, ","), strings.Join(networkNames, ","))
 }
 
 func init() {
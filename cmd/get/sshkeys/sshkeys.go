@@ -0,0 +1,79 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sshkeys
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg"
+	"github.com/ppc64le-cloud/pvsadm/pkg/client"
+	"github.com/ppc64le-cloud/pvsadm/pkg/utils"
+)
+
+var Cmd = &cobra.Command{
+	Use:   "sshkeys",
+	Short: "Get the PowerVS SSH keys",
+	Long: `Get the PowerVS SSH keys
+
+Examples:
+  # List all the SSH keys of the account
+  pvsadm get sshkeys --instance-name upstream-core
+
+  # List the SSH keys starts with ci-
+  pvsadm get sshkeys --instance-name upstream-core --regexp "^ci-.*"
+`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if pkg.Options.MultipleInstances() {
+			return fmt.Errorf("ssh keys belong to the account, use a single --instance-id or --instance-name")
+		}
+		if pkg.Options.InstanceID == "" && pkg.Options.InstanceName == "" {
+			return fmt.Errorf("--instance-id or --instance-name required")
+		}
+		if pkg.Options.Since != 0 && pkg.Options.Before != 0 {
+			return fmt.Errorf("--since and --before options can not be set at a time")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opt := pkg.Options
+
+		c, err := client.NewClientWithEnv(cmd.Context(), opt.APIKey, opt.Environment, opt.Debug)
+		if err != nil {
+			klog.Errorf("failed to create a session with IBM cloud: %v", err)
+			return err
+		}
+
+		pvmclient, err := client.NewPVMClientWithEnv(c, opt.InstanceID, opt.InstanceName, opt.Environment)
+		if err != nil {
+			return err
+		}
+
+		items, err := pvmclient.SSHKeyClient.GetAllPurgeable(opt.Before, opt.Since, opt.ExprRegexp)
+		if err != nil {
+			return err
+		}
+		return utils.NewTable().Render(items, []string{"sshkey"})
+	},
+}
+
+func init() {
+	Cmd.Flags().StringVar(&pkg.Options.Expr, "regexp", "", "Regular Expressions for filtering the selection")
+	Cmd.Flags().DurationVar(&pkg.Options.Since, "since", 0*time.Second, "List resources since mentioned duration(format: 99h99m00s), mutually exclusive with --before")
+	Cmd.Flags().DurationVar(&pkg.Options.Before, "before", 0*time.Second, "List resources before mentioned duration(format: 99h99m00s), mutually exclusive with --since")
+}
@@ -0,0 +1,120 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vms
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/IBM-Cloud/power-go-client/power/models"
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg"
+	"github.com/ppc64le-cloud/pvsadm/pkg/client"
+	"github.com/ppc64le-cloud/pvsadm/pkg/utils"
+)
+
+// Fields excluded from the table format, use -o wide to list all the fields
+var exclude = []string{"addresses", "fault", "health", "href", "licenserepositorycapacity", "maxmem", "maxproc",
+	"minmem", "minproc", "networks", "pinpolicy", "progress", "sapprofile", "softwarelicenses", "srcs", "storagepool",
+	"storagepoolaffinity", "virtualcores", "volumeids", "console", "migratable", "placementgroup"}
+
+// row is the virtual machine along with the workspace
+type row struct {
+	client.Workspace
+	*models.PVMInstanceReference
+}
+
+var Cmd = &cobra.Command{
+	Use:   "vms [NAME|ID]",
+	Short: "Get the PowerVS virtual machines",
+	Long: `Get the PowerVS virtual machines, lists all the virtual machines if the name or ID is not mentioned
+
+Examples:
+  # List all the virtual machines
+  pvsadm get vms --instance-name upstream-core
+
+  # List the virtual machines starts with k8s-cluster- and created before 4hrs
+  pvsadm get vms --instance-name upstream-core --regexp "^k8s-cluster-.*" --before 4h
+
+  # Get the details of a virtual machine by name or ID
+  pvsadm get vms --instance-name upstream-core k8s-cluster-master-0
+`,
+	Args: cobra.MaximumNArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if !pkg.Options.InstanceSelected() {
+			return fmt.Errorf("--instance-id, --instance-name, --instance-regexp or --all-instances required")
+		}
+		if pkg.Options.Since != 0 && pkg.Options.Before != 0 {
+			return fmt.Errorf("--since and --before options can not be set at a time")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opt := pkg.Options
+
+		c, err := client.NewClientWithEnv(cmd.Context(), opt.APIKey, opt.Environment, opt.Debug)
+		if err != nil {
+			klog.Errorf("failed to create a session with IBM cloud: %v", err)
+			return err
+		}
+
+		pvmclients, err := client.NewPVMClientsWithEnv(c, opt.Environment)
+		if err != nil {
+			return err
+		}
+
+		table := utils.NewTable()
+		if len(args) == 1 {
+			if len(pvmclients) != 1 {
+				return fmt.Errorf("name or ID can't be used along with the multiple instances")
+			}
+			pvmclient := pvmclients[0]
+			instance, err := pvmclient.InstanceClient.GetByNameOrID(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to get the vm: %v", err)
+			}
+			return table.RenderItem(instance, nil)
+		}
+
+		results := make([][]row, len(pvmclients))
+		err = client.ForEach(pvmclients, func(i int, pvmclient *client.PVMClient) error {
+			instances, err := pvmclient.InstanceClient.GetAllPurgeable(opt.Before, opt.Since, opt.Expr)
+			if err != nil {
+				return err
+			}
+			for _, ins := range instances {
+				results[i] = append(results[i], row{Workspace: pvmclient.Workspace(), PVMInstanceReference: ins})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		var rows []row
+		for _, r := range results {
+			rows = append(rows, r...)
+		}
+		return table.Render(rows, client.ExcludeWorkspace(pvmclients, exclude))
+	},
+}
+
+func init() {
+	Cmd.Flags().StringVar(&pkg.Options.Expr, "regexp", "", "Regular Expressions for filtering the selection")
+	Cmd.Flags().StringVar(&pkg.Options.Selector, "selector", "", "Tag selector for filtering the selection, supports key=value, key!=value, key and !key separated by comma")
+	Cmd.Flags().DurationVar(&pkg.Options.Since, "since", 0*time.Second, "List resources since mentioned duration(format: 99h99m00s), mutually exclusive with --before")
+	Cmd.Flags().DurationVar(&pkg.Options.Before, "before", 0*time.Second, "List resources before mentioned duration(format: 99h99m00s), mutually exclusive with --since")
+}
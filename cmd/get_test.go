@@ -0,0 +1,139 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGet(t *testing.T) {
+	s := fakeCloud(t)
+	ws := s.AddWorkspace("ws", "dal12")
+	now := time.Now()
+	ws.AddInstance("vm-1", now.Add(-time.Hour))
+	ws.AddVolume("vol-1", "available", now.Add(-time.Hour))
+	ws.AddImage("rhcos-49", now.Add(-time.Hour))
+	ws.AddNetwork("ocp-net", "192.168.10.0/24")
+	ws.AddEvent("create", "pvm-instance", "vm-1 created", now.Add(-time.Minute))
+	ws.AddSharedProcessorPool("ci-pool")
+
+	tests := []struct {
+		resource string
+		want     string
+	}{
+		{"vms", "vm-1"},
+		{"volumes", "vol-1"},
+		{"images", "rhcos-49"},
+		{"networks", "ocp-net"},
+		{"events", "vm-1 created"},
+		{"sharedprocessorpools", "ci-pool"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.resource, func(t *testing.T) {
+			out, err := pvsadm(t, "get", tt.resource, "--instance-name", "ws", "-o", "json")
+			if err != nil {
+				t.Fatalf("get %s failed: %v", tt.resource, err)
+			}
+			if !json.Valid([]byte(out)) {
+				t.Fatalf("get %s output is not json: %s", tt.resource, out)
+			}
+			if !strings.Contains(out, tt.want) {
+				t.Errorf("get %s output = %s, want it to contain %s", tt.resource, out, tt.want)
+			}
+		})
+	}
+}
+
+func TestPorts(t *testing.T) {
+	s := fakeCloud(t)
+	ws := s.AddWorkspace("ws", "dal12")
+	network := ws.AddNetwork("ocp-net", "192.168.10.0/24")
+
+	if _, err := pvsadm(t, "create", "port", "--instance-id", ws.ID, "--network", "ocp-net", "--description", "bastion"); err != nil {
+		t.Fatalf("create port failed: %v", err)
+	}
+	ports := ws.Ports(*network.NetworkID)
+	if len(ports) != 1 || ports[0].Description == nil || *ports[0].Description != "bastion" {
+		t.Fatalf("ports after the create = %+v, want the bastion port", ports)
+	}
+
+	out, err := pvsadm(t, "get", "ports", "--instance-name", "ws", "--network", *network.NetworkID, "-o", "json")
+	if err != nil {
+		t.Fatalf("get ports failed: %v", err)
+	}
+	if !strings.Contains(out, *ports[0].PortID) {
+		t.Errorf("get ports output = %s, want it to contain the port %s", out, *ports[0].PortID)
+	}
+
+	if _, err := pvsadm(t, "delete", "port", "--instance-id", ws.ID, "--network", "ocp-net", "--port-id", *ports[0].PortID); err != nil {
+		t.Fatalf("delete port failed: %v", err)
+	}
+	if ports := ws.Ports(*network.NetworkID); len(ports) != 0 {
+		t.Errorf("ports after the delete = %+v, want none", ports)
+	}
+}
+
+func TestGetInstancesInvalidRegexp(t *testing.T) {
+	s := fakeCloud(t)
+	s.AddWorkspace("ws", "dal12")
+
+	if _, err := pvsadm(t, "get", "instances", "--regexp", "ws-("); err == nil || !strings.Contains(err.Error(), "--regexp") {
+		t.Errorf("get instances --regexp ws-( error = %v, want the invalid --regexp error", err)
+	}
+	out, err := pvsadm(t, "get", "instances", "--regexp", "^w", "-o", "json")
+	if err != nil {
+		t.Fatalf("get instances --regexp ^w failed: %v", err)
+	}
+	if !strings.Contains(out, `"ws"`) {
+		t.Errorf("get instances --regexp ^w output = %s, want it to contain the ws", out)
+	}
+}
+
+func TestGetMultipleInstances(t *testing.T) {
+	s := fakeCloud(t)
+	ws1, ws2 := s.AddWorkspace("ws-1", "dal12"), s.AddWorkspace("ws-2", "dal10")
+	ws1.AddEvent("create", "pvm-instance", "vm-1 created", time.Now().Add(-time.Minute))
+	ws2.AddEvent("create", "pvm-instance", "vm-2 created", time.Now().Add(-time.Minute))
+	ws1.AddSharedProcessorPool("pool-1")
+	ws2.AddSharedProcessorPool("pool-2")
+
+	tests := []struct {
+		resource string
+		want     []string
+	}{
+		{"events", []string{"vm-1 created", "vm-2 created"}},
+		{"sharedprocessorpools", []string{"pool-1", "pool-2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.resource, func(t *testing.T) {
+			out, err := pvsadm(t, "get", tt.resource, "--instance-regexp", "^ws-", "-o", "json")
+			if err != nil {
+				t.Fatalf("get %s failed: %v", tt.resource, err)
+			}
+			for _, want := range append(tt.want, "ws-1", "ws-2") {
+				if !strings.Contains(out, want) {
+					t.Errorf("get %s output = %s, want it to contain %s", tt.resource, out, want)
+				}
+			}
+		})
+	}
+
+	if _, err := pvsadm(t, "get", "sshkeys", "--all-instances"); err == nil || !strings.Contains(err.Error(), "single") {
+		t.Errorf("get sshkeys --all-instances error = %v, want the multiple instances rejected", err)
+	}
+}
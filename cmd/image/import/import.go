@@ -16,26 +16,19 @@ package _import
 
 import (
 	"fmt"
-	"os"
 	"strings"
-	"time"
 
-	"github.com/IBM-Cloud/bluemix-go/api/resource/resourcev1/controller"
-	"github.com/IBM-Cloud/bluemix-go/api/resource/resourcev2/controllerv2"
-	"github.com/IBM-Cloud/bluemix-go/crn"
-	"github.com/IBM-Cloud/bluemix-go/models"
 	"github.com/spf13/cobra"
-	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/klog/v2"
 
 	"github.com/ppc64le-cloud/pvsadm/pkg"
 	"github.com/ppc64le-cloud/pvsadm/pkg/client"
+	"github.com/ppc64le-cloud/pvsadm/pkg/image"
 	"github.com/ppc64le-cloud/pvsadm/pkg/utils"
 )
 
-const (
-	serviceCredPrefix = "pvsadm-service-cred"
-)
+// checksum is the expected hex sha256 digest of the object from the --image-checksum
+var checksum string
 
 var Cmd = &cobra.Command{
 	Use:   "import",
@@ -59,162 +52,61 @@ pvsadm image import -n upstream-core-lon04 -b <BUCKETNAME> --pvs-storagetype <ST
 
 # If user wants to specify the type of OS
 pvsadm image import -n upstream-core-lon04 -b <BUCKETNAME> --object rhel-83-10032020.ova.gz --pvs-image-name test-image -r <REGION>
+
+# import only if the checksum stored with the object by the pvsadm image upload matches
+pvsadm image import -n upstream-core-lon04 -b <BUCKETNAME> --object rhel-83-10032020.ova.gz --pvs-image-name test-image -r <REGION> --image-checksum sha256:$(cut -d' ' -f1 rhel-83-10032020.ova.gz.sha256)
 `,
 	PreRunE: func(cmd *cobra.Command, args []string) error {
 		if pkg.ImageCMDOptions.InstanceID == "" && pkg.ImageCMDOptions.InstanceName == "" {
 			return fmt.Errorf("--pvs-instance-name or --pvs-instance-id required")
 		}
+		if !utils.Contains(image.StorageTypes, strings.ToLower(pkg.ImageCMDOptions.StorageType)) {
+			return fmt.Errorf("provide valid StorageType.. allowable values are [%s]", strings.Join(image.StorageTypes, ", "))
+		}
+		checksum = ""
+		if pkg.ImageCMDOptions.ImageChecksum != "" {
+			var err error
+			if checksum, err = utils.ParseChecksum(pkg.ImageCMDOptions.ImageChecksum); err != nil {
+				return fmt.Errorf("--image-checksum: %v", err)
+			}
+		}
 		return nil
 	},
 
 	RunE: func(cmd *cobra.Command, args []string) error {
-		var s3client *client.S3Client
 		opt := pkg.ImageCMDOptions
-		apikey := pkg.Options.APIKey
-		//validate inputs
-		validStorageType := []string{"tier3", "tier1"}
 
-		if !utils.Contains(validStorageType, strings.ToLower(opt.StorageType)) {
-			klog.Errorf("Provide valid StorageType.. allowable values are [tier1, tier3]")
-			os.Exit(1)
-		}
-
-		bxCli, err := client.NewClientWithEnv(apikey, pkg.Options.Environment, pkg.Options.Debug)
+		bxCli, err := client.NewClientWithEnv(cmd.Context(), pkg.Options.APIKey, pkg.Options.Environment, pkg.Options.Debug)
 		if err != nil {
 			return err
 		}
 
-		var svcs []models.ServiceInstanceV2
-		if opt.COSInstanceName != "" {
-			svcs, err = bxCli.ResourceClientV2.ListInstances(controllerv2.ServiceInstanceQuery{
-				Type: "service_instance",
-				Name: opt.COSInstanceName,
-			})
-		} else {
-			svcs, err = bxCli.ResourceClientV2.ListInstances(controllerv2.ServiceInstanceQuery{
-				Type: "service_instance",
-			})
-		}
+		result, err := image.Import(cmd.Context(), bxCli, image.ImportRequest{
+			InstanceID:      opt.InstanceID,
+			InstanceName:    opt.InstanceName,
+			COSInstanceName: opt.COSInstanceName,
+			Bucket:          opt.BucketName,
+			Region:          opt.Region,
+			Object:          opt.ImageFilename,
+			ImageName:       opt.ImageName,
+			StorageType:     opt.StorageType,
+			AccessKey:       opt.AccessKey,
+			SecretKey:       opt.SecretKey,
+			ServiceCredName: opt.ServiceCredName,
+			Watch:           opt.Watch,
+			WatchTimeout:    opt.WatchTimeout,
+			Checksum:        checksum,
+		})
 		if err != nil {
-			return err
-		}
-
-		// Step 1: Find where COS for the bucket
-		cosOfBucket, id, crn := func(resources []models.ServiceInstanceV2) (string, string, crn.CRN) {
-			for _, resource := range resources {
-				if resource.Crn.ServiceName == "cloud-object-storage" {
-					s3client, err = client.NewS3Client(bxCli, resource.Name, opt.Region)
-					if err != nil {
-						continue
-					}
-					buckets, err := s3client.S3Session.ListBuckets(nil)
-					if err != nil {
-						continue
-					}
-					for _, bucket := range buckets.Buckets {
-						if *bucket.Name == opt.BucketName {
-							return resource.Name, resource.Guid, resource.Crn
-						}
-					}
-				}
+			if result != nil {
+				return fmt.Errorf("failed to import the image, err: %v\n\nRun this command to get more information for the failure: pvsadm get events -i %s", err, result.InstanceID)
 			}
-			return "", "", crn.CRN{}
-		}(svcs)
-
-		if cosOfBucket == "" {
-			return fmt.Errorf("failed to find the COS instance for the bucket mentioned: %s", opt.BucketName)
-		}
-		klog.Infof("%s bucket found in the %s[ID:%s] COS instance", opt.BucketName, cosOfBucket, id)
-
-		//Step 2: Check if s3 object exists
-		objectExists := s3client.CheckIfObjectExists(opt.BucketName, opt.ImageFilename)
-		if !objectExists {
-			return fmt.Errorf("failed to found the object %s in %s bucket", opt.ImageFilename, opt.BucketName)
-		}
-		klog.Infof("%s object found in the %s bucket\n", opt.ImageFilename, opt.BucketName)
-
-		if opt.AccessKey == "" || opt.SecretKey == "" {
-			// Step 3: Check if Service Credential exists for the found COS instance
-
-			// frame the unique name for the service credential
-			if opt.ServiceCredName == "" {
-				opt.ServiceCredName = serviceCredPrefix + "-" + cosOfBucket
-			}
-
-			keys, err := bxCli.GetResourceKeys(id)
-			if err != nil {
-				return fmt.Errorf("failed to list the service credentials: %v", err)
-			}
-
-			var cred map[string]interface{}
-			var ok bool
-			if len(keys) == 0 {
-				// Create the service credential if does not exist
-				klog.Infof("Auto Generating the COS Service credential for importing the image with name: %s", opt.ServiceCredName)
-				CreateServiceKeyRequest := controller.CreateServiceKeyRequest{
-					Name:       opt.ServiceCredName,
-					SourceCRN:  crn,
-					Parameters: map[string]interface{}{"HMAC": true},
-				}
-				newKey, err := bxCli.ResourceServiceKey.CreateKey(CreateServiceKeyRequest)
-				if err != nil {
-					return err
-				}
-				cred, ok = newKey.Credentials["cos_hmac_keys"].(map[string]interface{})
-			} else {
-				// Use the service credential already created
-				klog.Infof("Reading the existing service credential: %s", opt.ServiceCredName)
-				cred, ok = keys[0].Credentials["cos_hmac_keys"].(map[string]interface{})
-			}
-
-			if !ok {
-				return fmt.Errorf("failed to get the accessKey and secretKey from service credential")
-			}
-
-			// Step 4: Assign the Access Key and Secret Key for further operation
-			opt.AccessKey = cred["access_key_id"].(string)
-			opt.SecretKey = cred["secret_access_key"].(string)
-
-		}
-
-		pvmclient, err := client.NewPVMClientWithEnv(bxCli, opt.InstanceID, opt.InstanceName, pkg.Options.Environment)
-		if err != nil {
-			return err
-		}
-
-		image, err := pvmclient.ImgClient.ImportImage(pvmclient.InstanceID, opt.ImageName, opt.ImageFilename, opt.Region,
-			opt.AccessKey, opt.SecretKey, opt.BucketName, strings.ToLower(opt.StorageType))
-		if err != nil {
 			return err
 		}
 
 		if !opt.Watch {
-			klog.Infof("Importing Image %s is currently in %s state, Please check the Progress in the IBM Cloud UI\n", *image.Name, image.State)
-			return nil
-		}
-
-		start := time.Now()
-		pollErr := wait.PollImmediate(2*time.Minute, opt.WatchTimeout, func() (bool, error) {
-			img, err := pvmclient.ImgClient.Get(*image.ImageID)
-			if err != nil {
-				return false, err
-			}
-			if img.State == "active" {
-				return true, nil
-			}
-			klog.Infof("Import in-progress, current state: %s", img.State)
-			return false, nil
-		})
-		if pollErr == wait.ErrWaitTimeout {
-			pollErr = fmt.Errorf("timed out while waiting for image to become ready state")
-		}
-
-		if pollErr != nil {
-			return fmt.Errorf("failed to import the image, err: %v\n\nRun this command to get more information for the failure: pvsadm get events -i %s", pollErr, pvmclient.InstanceID)
+			klog.Infof("Importing Image %s is currently in %s state, Please check the Progress in the IBM Cloud UI\n", *result.Image.Name, result.Image.State)
 		}
-
-		klog.Infof("Successfully imported the image: %s with ID: %s within %s", *image.Name, *image.ImageID, time.Since(start))
-
 		return nil
 	},
 }
@@ -230,9 +122,10 @@ func init() {
 	Cmd.Flags().StringVar(&pkg.ImageCMDOptions.SecretKey, "secretkey", "", "Cloud Object Storage HMAC secret key.")
 	Cmd.Flags().StringVar(&pkg.ImageCMDOptions.ImageName, "pvs-image-name", "", "Name to PowerVS imported image.")
 	Cmd.Flags().BoolVarP(&pkg.ImageCMDOptions.Watch, "watch", "w", false, "After image import watch for image to be published and ready to use")
-	Cmd.Flags().DurationVar(&pkg.ImageCMDOptions.WatchTimeout, "watch-timeout", 1*time.Hour, "watch timeout")
-	Cmd.Flags().StringVar(&pkg.ImageCMDOptions.StorageType, "pvs-storagetype", "tier3", "PowerVS Storage type, accepted values are [tier1, tier3].")
-	Cmd.Flags().StringVar(&pkg.ImageCMDOptions.ServiceCredName, "cos-service-cred", "", "IBM COS Service Credential name to be auto generated(default \""+serviceCredPrefix+"-<COS Name>\")")
+	Cmd.Flags().DurationVar(&pkg.ImageCMDOptions.WatchTimeout, "watch-timeout", image.DefaultWatchTimeout, "watch timeout")
+	Cmd.Flags().StringVar(&pkg.ImageCMDOptions.StorageType, "pvs-storagetype", image.StorageTypes[0], "PowerVS Storage type, accepted values are ["+strings.Join(image.StorageTypes, ", ")+"].")
+	Cmd.Flags().StringVar(&pkg.ImageCMDOptions.ImageChecksum, "image-checksum", "", "Expected checksum of the object, sha256:<hex digest>, the import is refused if the checksum stored with the object by the pvsadm image upload is missing or doesn't match. The stored checksum isn't verified without it")
+	Cmd.Flags().StringVar(&pkg.ImageCMDOptions.ServiceCredName, "cos-service-cred", "", "IBM COS Service Credential name to be auto generated(default \""+image.ServiceCredPrefix+"-<COS Name>\")")
 
 	_ = Cmd.MarkFlagRequired("bucket")
 	_ = Cmd.MarkFlagRequired("bucket-region")
@@ -0,0 +1,403 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publish
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+	"k8s.io/klog/v2"
+
+	"github.com/ppc64le-cloud/pvsadm/cmd/image/qcow2ova"
+	"github.com/ppc64le-cloud/pvsadm/pkg"
+	"github.com/ppc64le-cloud/pvsadm/pkg/client"
+	"github.com/ppc64le-cloud/pvsadm/pkg/image"
+	"github.com/ppc64le-cloud/pvsadm/pkg/utils"
+)
+
+const (
+	actionImported = "imported"
+	actionSkipped  = "skipped"
+	actionFailed   = "failed"
+)
+
+var Cmd = &cobra.Command{
+	Use:   "publish",
+	Short: "Convert, upload and import the image into the PowerVS workspaces",
+	Long: `Convert, upload and import the image into the PowerVS workspaces
+pvsadm image publish --help for information
+
+The qcow2 image is converted into the OVA image(pvsadm image qcow2ova), uploaded to the bucket(pvsadm image upload) and
+imported into the workspaces in parallel(pvsadm image import). Every stage is skipped if its output is already present,
+rerun the same command to carry on after a failure:
+  convert: skipped if the OVA image along with its checksum file is present in the outputDir, or uploaded already
+  upload:  skipped if the object is present in the bucket, an interrupted upload is resumed
+  import:  skipped for the workspaces having the image with the same name, the images still importing are watched
+
+# Set the API key or feed the --api-key commandline argument
+export IBMCLOUD_API_KEY=<IBM_CLOUD_API_KEY>
+
+Examples:
+
+# publish the image described in the spec file and wait for the images to be active
+pvsadm image publish --spec-file publish.yaml --watch
+
+Sample publish.yaml file:
+---
+name: rhcos-461
+convert:
+  url: https://mirror.openshift.com/pub/openshift-v4/ppc64le/dependencies/rhcos/4.6/4.6.1/rhcos-4.6.1-ppc64le-openstack.ppc64le.qcow2.gz
+  checksum: https://mirror.openshift.com/pub/openshift-v4/ppc64le/dependencies/rhcos/4.6/4.6.1/sha256sum.txt
+  dist: coreos
+  size: 16
+upload:
+  cos: pvsadm-cos-instance
+  bucket: bucket0711
+  region: us-south
+import:
+  storageType: tier3
+  workspaces:
+  - name: upstream-core-lon04
+  - id: 7845d372-d4e1-46b8-91fc-41051c984601
+
+Use file: <path of the OVA image> instead of the convert to publish an already converted image.
+`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if pkg.ImageCMDOptions.WatchTimeout <= 0 {
+			return fmt.Errorf("--watch-timeout must be positive")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opt := pkg.ImageCMDOptions
+		ctx := cmd.Context()
+
+		spec, err := readSpec(opt.SpecYAML)
+		if err != nil {
+			return err
+		}
+
+		bxCli, err := client.NewClientWithEnv(ctx, pkg.Options.APIKey, pkg.Options.Environment, pkg.Options.Debug)
+		if err != nil {
+			return err
+		}
+
+		// workspaces are resolved upfront, fails before the long running conversion and upload
+		workspaces := make([]*client.Instance, len(spec.Import.Workspaces))
+		for i, ws := range spec.Import.Workspaces {
+			if workspaces[i], err = bxCli.LookupInstance(ws.ID, ws.Name); err != nil {
+				return err
+			}
+		}
+
+		file := spec.File
+		if file == "" {
+			file = filepath.Join(spec.Convert.OutputDir, spec.Name+".ova.gz")
+		}
+		up := spec.Upload
+		if up.Object == "" {
+			up.Object = filepath.Base(file)
+		}
+
+		// Step 1: Check if the image is uploaded already
+		if up.Cos == "" {
+			if up.Cos, err = image.FindBucket(bxCli, up.Bucket, up.Region); err != nil {
+				return err
+			}
+			if up.Cos == "" {
+				return fmt.Errorf("bucket %s not found in any of the Cloud Object Storage instances, set the upload.cos to create it", up.Bucket)
+			}
+		}
+		s3Cli, err := client.NewS3Client(bxCli, up.Cos, up.Region)
+		if err != nil {
+			return err
+		}
+		bucketExists, err := s3Cli.CheckBucketExists(up.Bucket)
+		if err != nil {
+			return err
+		}
+		uploaded := bucketExists && s3Cli.CheckIfObjectExists(up.Bucket, up.Object)
+		var objectSum string
+		if uploaded {
+			if objectSum, err = s3Cli.ObjectChecksum(up.Bucket, up.Object); err != nil {
+				return err
+			}
+		}
+
+		// Step 2: Convert
+		sum, err := convertedChecksum(file)
+		if err != nil {
+			return err
+		}
+		switch {
+		case spec.Convert == nil:
+			if _, err := os.Stat(file); err != nil && !uploaded {
+				return err
+			}
+		case sum != "":
+			klog.Infof("Skipping the conversion, %s is already converted", file)
+		case uploaded:
+			klog.Infof("Skipping the conversion, %s is already uploaded to the %s bucket", up.Object, up.Bucket)
+		default:
+			if file, sum, err = convert(ctx, spec); err != nil {
+				return err
+			}
+		}
+
+		// Step 3: Upload
+		if uploaded {
+			if sum != "" && objectSum != "" && sum != objectSum {
+				return fmt.Errorf("%s object in the %s bucket has the checksum %s:%s, not the %s:%s of the %s, delete the object to publish the image again",
+					up.Object, up.Bucket, utils.ChecksumSHA256, objectSum, utils.ChecksumSHA256, sum, file)
+			}
+			klog.Infof("Skipping the upload, %s object is already present in the %s bucket", up.Object, up.Bucket)
+			if sum == "" {
+				sum = objectSum
+			}
+		} else {
+			result, err := image.Upload(ctx, bxCli, image.UploadRequest{
+				File:         file,
+				InstanceName: up.Cos,
+				Bucket:       up.Bucket,
+				Region:       up.Region,
+				ObjectName:   up.Object,
+				PartSize:     up.PartSize * 1024 * 1024,
+				Concurrency:  up.Concurrency,
+				Checksum:     sum,
+			})
+			if err != nil {
+				return err
+			}
+			sum = result.Checksum
+		}
+
+		// Step 4: Import into the workspaces in parallel, the workspaces having the image already are skipped unless
+		// the image is to be watched
+		results := make([]*image.ImportResult, len(workspaces))
+		errs := make([]error, len(workspaces))
+		var targets []int
+		var importing bool
+		for i, ws := range workspaces {
+			existing, err := image.FindImage(bxCli, ws, spec.Import.ImageName)
+			switch {
+			case err != nil:
+				errs[i] = err
+			case existing == nil:
+				targets = append(targets, i)
+				importing = true
+			case existing.State != image.ImageStateActive && opt.Watch:
+				targets = append(targets, i)
+			default:
+				klog.Infof("Skipping the import, image %s is already present in the workspace %s", spec.Import.ImageName, ws.Name)
+				results[i] = &image.ImportResult{Image: existing, InstanceID: ws.ID, COSInstanceName: up.Cos, Existing: true}
+			}
+		}
+
+		if len(targets) != 0 {
+			// resolved once for all the imports
+			cos, err := image.FindCOSInstance(bxCli, up.Cos, up.Bucket, up.Region)
+			if err != nil {
+				return err
+			}
+			var accessKey, secretKey string
+			if importing {
+				if accessKey, secretKey, err = cos.HMACKeys(bxCli, spec.Import.ServiceCredName); err != nil {
+					return err
+				}
+			}
+			var wg sync.WaitGroup
+			for _, i := range targets {
+				wg.Add(1)
+				go func(i int, ws *client.Instance) {
+					defer wg.Done()
+					results[i], errs[i] = image.Import(ctx, bxCli, image.ImportRequest{
+						InstanceID:      ws.ID,
+						COSInstanceName: up.Cos,
+						COS:             cos,
+						Bucket:          up.Bucket,
+						Region:          up.Region,
+						Object:          up.Object,
+						ImageName:       spec.Import.ImageName,
+						StorageType:     spec.Import.StorageType,
+						AccessKey:       accessKey,
+						SecretKey:       secretKey,
+						Watch:           opt.Watch,
+						WatchTimeout:    opt.WatchTimeout,
+						SkipExisting:    true,
+						Checksum:        sum,
+					})
+				}(i, workspaces[i])
+			}
+			wg.Wait()
+		}
+
+		t := utils.NewTable()
+		t.SetHeader([]string{"Workspace", "Zone", "Image", "Image ID", "State", "Action", "Error"})
+		var failed []string
+		for i, ws := range workspaces {
+			action, imageID, state, errMsg := actionImported, "", "", ""
+			if r := results[i]; r != nil && r.Image != nil {
+				imageID, state = *r.Image.ImageID, r.Image.State
+				if r.Existing {
+					action = actionSkipped
+				}
+			}
+			if errs[i] != nil {
+				action, errMsg = actionFailed, errs[i].Error()
+				failed = append(failed, ws.Name)
+			}
+			t.Append([]string{ws.Name, ws.Zone, spec.Import.ImageName, imageID, state, action, errMsg})
+		}
+		t.Table.Render()
+		if len(failed) != 0 {
+			return fmt.Errorf("failed to publish the image into the workspaces: [%s], rerun the command to retry", strings.Join(failed, ", "))
+		}
+		return nil
+	},
+}
+
+// readSpec reads the spec file and sets the defaults
+func readSpec(file string) (*pkg.PublishSpec, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the spec file: %v", err)
+	}
+	spec := &pkg.PublishSpec{}
+	if err := yaml.UnmarshalStrict(content, spec); err != nil {
+		return nil, fmt.Errorf("failed to parse the spec file %s: %v", file, err)
+	}
+	if err := validateSpec(spec); err != nil {
+		return nil, fmt.Errorf("invalid spec file %s: %v", file, err)
+	}
+	return spec, nil
+}
+
+// validateSpec validates the spec and sets the defaults of the optional fields
+func validateSpec(spec *pkg.PublishSpec) error {
+	if spec.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	switch {
+	case spec.File == "" && spec.Convert == nil:
+		return fmt.Errorf("either file or convert is required")
+	case spec.File != "" && spec.Convert != nil:
+		return fmt.Errorf("file and convert are mutually exclusive")
+	case spec.Convert != nil:
+		if spec.Convert.URL == "" || spec.Convert.Dist == "" {
+			return fmt.Errorf("convert.url and convert.dist are required")
+		}
+		if spec.Convert.Size == 0 {
+			spec.Convert.Size = 11
+		}
+		if spec.Convert.TargetDiskSize == 0 {
+			spec.Convert.TargetDiskSize = 120
+		}
+		if spec.Convert.TempDir == "" {
+			spec.Convert.TempDir = os.TempDir()
+		}
+		if spec.Convert.OutputDir == "" {
+			spec.Convert.OutputDir = "."
+		}
+	}
+
+	if spec.Upload.Bucket == "" {
+		return fmt.Errorf("upload.bucket is required")
+	}
+	if spec.Upload.Region == "" {
+		spec.Upload.Region = "us-south"
+	}
+	if spec.Upload.PartSize == 0 {
+		spec.Upload.PartSize = client.DefaultPartSize / 1024 / 1024
+	}
+	if spec.Upload.PartSize*1024*1024 < client.MinPartSize {
+		return fmt.Errorf("upload.partSize must be at least %d MiB", client.MinPartSize/1024/1024)
+	}
+
+	if len(spec.Import.Workspaces) == 0 {
+		return fmt.Errorf("import.workspaces is required")
+	}
+	for i, ws := range spec.Import.Workspaces {
+		if ws.Name == "" && ws.ID == "" {
+			return fmt.Errorf("import.workspaces[%d]: name or id is required", i)
+		}
+	}
+	if spec.Import.ImageName == "" {
+		spec.Import.ImageName = spec.Name
+	}
+	if spec.Import.StorageType == "" {
+		spec.Import.StorageType = image.StorageTypes[0]
+	}
+	if !utils.Contains(image.StorageTypes, strings.ToLower(spec.Import.StorageType)) {
+		return fmt.Errorf("unsupported import.storageType: %s, supported are: [%s]", spec.Import.StorageType, strings.Join(image.StorageTypes, ", "))
+	}
+	return nil
+}
+
+// convertedChecksum returns the checksum of the converted OVA image, empty if the image or its checksum file isn't
+// present. The checksum file is written last by the conversion, an image without it is an interrupted conversion.
+func convertedChecksum(file string) (string, error) {
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		return "", nil
+	}
+	return utils.ReadChecksumFile(file)
+}
+
+// convert converts the qcow2 image of the spec into the OVA image, returns the path and the checksum of the image
+func convert(ctx context.Context, spec *pkg.PublishSpec) (string, string, error) {
+	c := spec.Convert
+	opt := &pkg.ImageOptions{
+		ImageName:      spec.Name,
+		ImageURL:       c.URL,
+		ImageChecksum:  c.Checksum,
+		ImageDist:      c.Dist,
+		ImageSize:      c.Size,
+		TargetDiskSize: c.TargetDiskSize,
+		OSPassword:     c.OSPassword,
+		RHNUser:        c.RHNUser,
+		RHNPassword:    c.RHNPassword,
+		PrepTemplate:   c.PrepTemplate,
+		TempDir:        c.TempDir,
+	}
+	if err := qcow2ova.Setup(opt); err != nil {
+		return "", "", err
+	}
+	if err := os.MkdirAll(c.OutputDir, 0755); err != nil {
+		return "", "", err
+	}
+	file, sum, err := qcow2ova.Convert(ctx, opt, c.OutputDir)
+	if err != nil {
+		return "", "", err
+	}
+	klog.Infof("Converted the image into %s, checksum: %s:%s", file, utils.ChecksumSHA256, sum)
+	if opt.OSPassword != "" {
+		fmt.Printf("OS root password of the image %s: %s\n", spec.Name, opt.OSPassword)
+	}
+	return file, sum, nil
+}
+
+func init() {
+	Cmd.Flags().StringVarP(&pkg.ImageCMDOptions.SpecYAML, "spec-file", "s", "", "The PATH to the spec file describing the image to be published")
+	Cmd.Flags().BoolVarP(&pkg.ImageCMDOptions.Watch, "watch", "w", false, "Watch the imported images until they are active")
+	Cmd.Flags().DurationVar(&pkg.ImageCMDOptions.WatchTimeout, "watch-timeout", image.DefaultWatchTimeout, "Time to wait for each of the imported images to be active")
+	_ = Cmd.MarkFlagRequired("spec-file")
+	Cmd.Flags().SortFlags = false
+}
@@ -0,0 +1,65 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package qcow2ova
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg/utils"
+)
+
+// maxChecksumFileSize limits the size of the checksum files read
+const maxChecksumFileSize = 1024 * 1024
+
+// expectedChecksum returns the hex sha256 digest of the image from the checksum, the checksum is either a
+// sha256:<hex digest> or the URL or the path of a sha256sum formatted file listing the image, e.g. SHA256SUMS
+func expectedChecksum(checksum, imageURL string) (string, error) {
+	if strings.HasPrefix(checksum, utils.ChecksumSHA256+":") {
+		return utils.ParseChecksum(checksum)
+	}
+
+	var sums []byte
+	if isURL(checksum) {
+		client := http.Client{Timeout: DefaultGetTimeout}
+		resp, err := client.Get(checksum)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("failed to download the checksum file: %s, status code: %d", checksum, resp.StatusCode)
+		}
+		if sums, err = ioutil.ReadAll(io.LimitReader(resp.Body, maxChecksumFileSize)); err != nil {
+			return "", err
+		}
+	} else if fileExists(checksum) {
+		var err error
+		if sums, err = ioutil.ReadFile(checksum); err != nil {
+			return "", err
+		}
+	} else {
+		return "", fmt.Errorf("--image-checksum: %s is neither %s:<hex digest> nor a valid URL or file", checksum, utils.ChecksumSHA256)
+	}
+	sum, err := utils.LookupChecksum(sums, path.Base(imageURL))
+	if err != nil {
+		return "", fmt.Errorf("%v in %s", err, checksum)
+	}
+	return sum, nil
+}
@@ -0,0 +1,69 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package qcow2ova
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_expectedChecksum(t *testing.T) {
+	sum := strings.Repeat("ab", 32)
+	sums := fmt.Sprintf("%s  rhcos.qcow2.gz\n%s  centos.qcow2\n", sum, strings.Repeat("0", 64))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/SHA256SUMS", func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, sums)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	file := filepath.Join(t.TempDir(), "SHA256SUMS")
+	if err := ioutil.WriteFile(file, []byte(sums), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		checksum string
+		imageURL string
+		want     string
+		wantErr  bool
+	}{
+		{"digest", "sha256:" + sum, "https://example.com/rhcos.qcow2.gz", sum, false},
+		{"invalid digest", "sha256:xyz", "https://example.com/rhcos.qcow2.gz", "", true},
+		{"checksum URL", ts.URL + "/SHA256SUMS", "https://example.com/rhcos.qcow2.gz", sum, false},
+		{"checksum file", file, "/images/rhcos.qcow2.gz", sum, false},
+		{"image not listed", ts.URL + "/SHA256SUMS", "https://example.com/fedora.qcow2", "", true},
+		{"checksum URL not found", ts.URL + "/missing", "https://example.com/rhcos.qcow2.gz", "", true},
+		{"neither digest nor file", "/file/doesnot/exist", "https://example.com/rhcos.qcow2.gz", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expectedChecksum(tt.checksum, tt.imageURL)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expectedChecksum() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("expectedChecksum() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
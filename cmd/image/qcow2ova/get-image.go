@@ -21,9 +21,12 @@ import (
 	"net/url"
 	"os"
 	"path"
+	"path/filepath"
 	"time"
 
 	"k8s.io/klog/v2"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg/utils"
 )
 
 const (
@@ -65,7 +68,9 @@ func getImage(downloadDir string, srcUrl string, timeout time.Duration) (string,
 			return "", fmt.Errorf("failed to download the file: %s, status code: %d", srcUrl, resp.StatusCode)
 		}
 
-		_, err = io.Copy(out, resp.Body)
+		progress := utils.NewProgress("Downloading "+path.Base(srcUrl), resp.ContentLength)
+		_, err = io.Copy(out, progress.Reader(resp.Body))
+		progress.Done(err)
 		if err != nil {
 			return "", err
 		}
@@ -86,7 +91,14 @@ func cp(src, dest string) error {
 		return err
 	}
 	defer out.Close()
-	if _, err = io.Copy(out, in); err != nil {
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	progress := utils.NewProgress("Copying "+filepath.Base(src), info.Size())
+	_, err = io.Copy(out, progress.Reader(in))
+	progress.Done(err)
+	if err != nil {
 		return err
 	}
 	return out.Sync()
@@ -15,45 +15,70 @@
 package qcow2ova
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
 
 	gzip "github.com/klauspost/pgzip"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg/utils"
 )
 
 // gzipIt compresses the source file to dest
-func gzipIt(src, dest string) error {
+func gzipIt(src, dest string) (string, error) {
 	reader, err := os.Open(src)
 	if err != nil {
-		return err
+		return "", err
 	}
+	defer reader.Close()
 
 	filename := filepath.Base(src)
 	writer, err := os.Create(dest)
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer writer.Close()
 
-	archiver := gzip.NewWriter(writer)
-	defer archiver.Close()
+	// sha256 of the compressed file computed along with the write
+	h := sha256.New()
+	archiver := gzip.NewWriter(io.MultiWriter(writer, h))
 	archiver.Name = filename
 
-	_, err = io.Copy(archiver, reader)
-	return err
+	info, err := reader.Stat()
+	if err != nil {
+		return "", err
+	}
+	progress := utils.NewProgress("Compressing "+filename, info.Size())
+	_, err = io.Copy(archiver, progress.Reader(reader))
+	progress.Done(err)
+	if err != nil {
+		return "", err
+	}
+	if err := archiver.Close(); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
 // gunzipIt the source file to target
-func gunzipIt(src, dest string) error {
+func gunzipIt(src, dest string) (err error) {
 	reader, err := os.Open(src)
 	if err != nil {
 		return err
 	}
 	defer reader.Close()
+	info, err := reader.Stat()
+	if err != nil {
+		return err
+	}
+	// progress of the compressed bytes read, the size of the decompressed image isn't known upfront
+	progress := utils.NewProgress("Decompressing "+filepath.Base(src), info.Size())
+	defer func() { progress.Done(err) }()
 
-	archive, err := gzip.NewReader(reader)
+	archive, err := gzip.NewReader(progress.Reader(reader))
 	if err != nil {
 		return err
 	}
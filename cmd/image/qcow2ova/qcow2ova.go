@@ -15,13 +15,12 @@
 package qcow2ova
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
-	"os/signal"
 	"path/filepath"
 	"strings"
-	"syscall"
 
 	"github.com/manifoldco/promptui"
 	"github.com/ppc64le-cloud/pvsadm/cmd/image/qcow2ova/ova"
@@ -33,6 +32,9 @@ import (
 	"k8s.io/klog/v2"
 )
 
+// imageChecksum is the expected hex sha256 digest of the image from the --image-checksum
+var imageChecksum string
+
 var Cmd = &cobra.Command{
 	Use:   "qcow2ova",
 	Short: "Convert the qcow2 image to ova format",
@@ -52,6 +54,10 @@ Examples:
   # Converts the CentOS image from the local filesystem with OS password set
   pvsadm image qcow2ova --image-name centos-82 --image-dist centos --os-password s0meC0mplexPassword --image-url /root/CentOS-8-GenericCloud-8.2.2004-20200611.2.ppc64le.qcow2
 
+  # Verifies the downloaded image with the checksum listed in the SHA256SUMS file, the sha256 checksum of the resultant
+  # OVA image is written to rhcos-461.ova.gz.sha256
+  pvsadm image qcow2ova --image-name rhcos-461 --image-dist coreos --image-url https://mirror.openshift.com/pub/openshift-v4/ppc64le/dependencies/rhcos/4.6/4.6.1/rhcos-4.6.1-ppc64le-openstack.ppc64le.qcow2.gz --image-checksum https://mirror.openshift.com/pub/openshift-v4/ppc64le/dependencies/rhcos/4.6/4.6.1/sha256sum.txt
+
   # Customize the image preparation script for RHEL/CentOS distro, e.g: add additional yum repository or packages, change name servers etc. 
   # Step 1 - Dump the default image preparation template
   pvsadm image qcow2ova --prep-template-default > image-prep.template
@@ -66,185 +72,231 @@ Examples:
 			fmt.Println(prep.SetupTemplate)
 			os.Exit(0)
 		}
-
-		// Override the prep.SetupTemplate if --prep-template supplied
-		if opt.PrepTemplate != "" {
-			if strings.ToLower(opt.ImageDist) == "coreos" {
-				return fmt.Errorf("--prep-template option is not supported for coreos distro")
-			} else {
-				klog.Info("Overriding with the user defined image preparation template.")
-				content, err := ioutil.ReadFile(opt.PrepTemplate)
-				if err != nil {
-					return err
-				}
-				prep.SetupTemplate = string(content)
-			}
-		}
-
-		if !utils.Contains([]string{"rhel", "centos", "coreos"}, strings.ToLower(opt.ImageDist)) {
-			klog.Errorln("--image-dist is a mandatory flag and one of these [rhel, centos, coreos]")
-			os.Exit(1)
-		}
-
-		//Read the RHNUser and RHNPassword if empty
-		if opt.ImageDist == "rhel" && (opt.RHNUser == "" || opt.RHNPassword == "") {
-			var err error
-			klog.Warning("rhn-user and rhn-password options are mandatory when image-dist is rhel, please enter the details")
-
-			//Validates and make sure input is not an empty string
-			validate := func(input string) error {
-				if len(strings.TrimSpace(input)) == 0 {
-					return fmt.Errorf("input can't be empty string")
-				}
-				return nil
-			}
-			if opt.RHNUser == "" {
-				prompt := promptui.Prompt{
-					Label:    "Enter the RHN Username",
-					Validate: validate,
-				}
-
-				opt.RHNUser, err = prompt.Run()
-				if err != nil {
-					return err
-				}
-			}
-
-			if opt.RHNPassword == "" {
-				prompt := promptui.Prompt{
-					Label:    "Enter the RHN Password",
-					Mask:     '•',
-					Validate: validate,
-				}
-
-				opt.RHNPassword, err = prompt.Run()
-				if err != nil {
-					return err
-				}
-			}
-		}
-
-		if opt.ImageDist != "coreos" && opt.OSPassword == "" {
-			var err error
-			opt.OSPassword, err = GeneratePassword(12)
-			klog.Infof("Autogenerated OS root password is: %s", opt.OSPassword)
-			if err != nil {
-				return err
-			}
-		}
-
-		// preflight checks validations
-		return validate.Validate()
+		return Setup(pkg.ImageCMDOptions)
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		opt := pkg.ImageCMDOptions
 
-		tmpDir, err := ioutil.TempDir(opt.TempDir, "qcow2ova")
-		if err != nil {
-			return fmt.Errorf("failed to create a temprory directory: %v", err)
-		}
-		defer os.RemoveAll(tmpDir)
-
-		mnt := filepath.Join(tmpDir, "mnt")
-		err = os.Mkdir(mnt, 0755)
+		cwd, err := os.Getwd()
 		if err != nil {
 			return err
 		}
-
-		cwd, err := os.Getwd()
+		ovaGZfile, sum, err := Convert(cmd.Context(), opt, cwd)
 		if err != nil {
 			return err
 		}
 
-		// Block for handling the interrupt and perform the cleanup
-		c := make(chan os.Signal, 1)
-		signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
-		go func() {
-			<-c
-			klog.Infof("Received an interrupt, exiting...")
-			_ = os.RemoveAll(tmpDir)
-			prep.UmountHostPartitions(mnt)
-			_ = prep.Umount(mnt)
-			os.Exit(1)
-		}()
+		fmt.Printf("\n\nSuccessfully converted Qcow2 image to OVA format, find at %s\nChecksum: %s:%s(saved in %s)\nOS root password: %s\n",
+			ovaGZfile, utils.ChecksumSHA256, sum, ovaGZfile+utils.ChecksumSuffix, opt.OSPassword)
+		return nil
+	},
+}
 
-		image, err := getImage(tmpDir, opt.ImageURL, 0)
-		if err != nil {
-			return fmt.Errorf("failed to download the %s into %s, error: %v", opt.ImageURL, tmpDir, err)
+// Setup validates the options for the Convert, prompts for the missing RHN credentials and runs the
+// preflight checks
+func Setup(opt *pkg.ImageOptions) error {
+	// Override the prep.SetupTemplate if --prep-template supplied
+	if opt.PrepTemplate != "" {
+		if strings.ToLower(opt.ImageDist) == "coreos" {
+			return fmt.Errorf("--prep-template option is not supported for coreos distro")
+		} else {
+			klog.Info("Overriding with the user defined image preparation template.")
+			content, err := ioutil.ReadFile(opt.PrepTemplate)
+			if err != nil {
+				return err
+			}
+			prep.SetupTemplate = string(content)
 		}
+	}
 
-		klog.Infof("downloaded/copied the file at: %s", image)
+	if !utils.Contains([]string{"rhel", "centos", "coreos"}, strings.ToLower(opt.ImageDist)) {
+		return fmt.Errorf("--image-dist is a mandatory flag and one of these [rhel, centos, coreos]")
+	}
 
-		var qcow2Img string
+	//Read the RHNUser and RHNPassword if empty
+	if opt.ImageDist == "rhel" && (opt.RHNUser == "" || opt.RHNPassword == "") {
+		var err error
+		klog.Warning("rhn-user and rhn-password options are mandatory when image-dist is rhel, please enter the details")
 
-		checkGzip, err := isGzip(image)
-		if err != nil {
-			return fmt.Errorf("failed to detect the image filetype: %v", err)
+		//Validates and make sure input is not an empty string
+		validate := func(input string) error {
+			if len(strings.TrimSpace(input)) == 0 {
+				return fmt.Errorf("input can't be empty string")
+			}
+			return nil
 		}
-		if checkGzip {
-			klog.Infof("Image %s is in gzip format, extracting it", image)
-			qcow2Img = filepath.Join(tmpDir, ova.VolName+".qcow2")
-			err = gunzipIt(image, qcow2Img)
+		if opt.RHNUser == "" {
+			prompt := promptui.Prompt{
+				Label:    "Enter the RHN Username",
+				Validate: validate,
+			}
+
+			opt.RHNUser, err = prompt.Run()
 			if err != nil {
 				return err
 			}
-			klog.Infof("Extract complete")
-		} else {
-			qcow2Img = image
 		}
 
-		ovaImgDir := filepath.Join(tmpDir, "ova-img-dir")
-		err = os.Mkdir(ovaImgDir, 0755)
-		if err != nil {
-			return err
-		}
+		if opt.RHNPassword == "" {
+			prompt := promptui.Prompt{
+				Label:    "Enter the RHN Password",
+				Mask:     '•',
+				Validate: validate,
+			}
 
-		rawImg := filepath.Join(ovaImgDir, ova.VolNameRaw)
+			opt.RHNPassword, err = prompt.Run()
+			if err != nil {
+				return err
+			}
+		}
+	}
 
-		klog.Infof("Converting Qcow2(%s) image to raw(%s) format", qcow2Img, rawImg)
-		err = qemuImgConvertQcow2Raw(qcow2Img, rawImg)
+	if opt.ImageDist != "coreos" && opt.OSPassword == "" {
+		var err error
+		opt.OSPassword, err = GeneratePassword(12)
+		klog.Infof("Autogenerated OS root password is: %s", opt.OSPassword)
 		if err != nil {
 			return err
 		}
-		klog.Infof("Conversion completed")
+	}
 
-		klog.Infof("Resizing the image %s to %dG", rawImg, opt.ImageSize)
-		err = qemuImgResize("-f", "raw", rawImg, fmt.Sprintf("%dG", opt.ImageSize))
-		if err != nil {
+	// resolved upfront, the download isn't started if the checksum isn't found
+	imageChecksum = ""
+	if opt.ImageChecksum != "" {
+		var err error
+		if imageChecksum, err = expectedChecksum(opt.ImageChecksum, opt.ImageURL); err != nil {
 			return err
 		}
-		klog.Infof("Resize completed")
+	}
 
-		klog.Infof("Preparing the image")
-		err = prep.Prepare4capture(mnt, rawImg, opt.ImageDist, opt.RHNUser, opt.RHNPassword, opt.OSPassword)
-		if err != nil {
-			return fmt.Errorf("failed while preparing the image for %s distro, err: %v", opt.ImageDist, err)
-		}
-		klog.Infof("Preparation completed")
+	// preflight checks validations
+	return validate.Validate(opt)
+}
 
-		klog.Infof("Creating an OVA bundle")
-		ovafile := filepath.Join(tmpDir, opt.ImageName+".ova")
-		if err := ova.CreateTarArchive(ovaImgDir, ovafile, opt.TargetDiskSize); err != nil {
-			return fmt.Errorf("failed to create ova bundle, err: %v", err)
+// Convert converts the qcow2 image of the options into the <image name>.ova.gz in the dir along with its
+// checksum file, returns the path and the hex sha256 digest of the OVA image. Options are validated by the Setup.
+// Returns the ctx error once interrupted, after unmounting the image and removing the temporary files.
+func Convert(ctx context.Context, opt *pkg.ImageOptions, dir string) (string, string, error) {
+
+	tmpDir, err := ioutil.TempDir(opt.TempDir, "qcow2ova")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create a temprory directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	mnt := filepath.Join(tmpDir, "mnt")
+	err = os.Mkdir(mnt, 0755)
+	if err != nil {
+		return "", "", err
+	}
+
+	// image may be left mounted if interrupted while preparing, unmounted before removing the tmpDir
+	defer func() {
+		if ctx.Err() != nil {
+			klog.Infof("Received an interrupt, cleaning up...")
+			prep.UmountHostPartitions(mnt)
+			_ = prep.Umount(mnt)
 		}
-		klog.Infof("OVA bundle creation completed: %s", ovafile)
+	}()
+
+	image, err := getImage(tmpDir, opt.ImageURL, 0)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to download the %s into %s, error: %v", opt.ImageURL, tmpDir, err)
+	}
 
-		klog.Infof("Compressing an OVA file")
-		ovaGZfile := filepath.Join(cwd, opt.ImageName+".ova.gz")
-		err = gzipIt(ovafile, ovaGZfile)
+	klog.Infof("downloaded/copied the file at: %s", image)
+	if imageChecksum != "" {
+		if err := utils.VerifyChecksum(image, imageChecksum); err != nil {
+			return "", "", err
+		}
+		klog.Infof("Verified the checksum of the image: %s:%s", utils.ChecksumSHA256, imageChecksum)
+	}
+	// steps don't watch the ctx, checked in between for stopping after the interrupt
+	if err := ctx.Err(); err != nil {
+		return "", "", err
+	}
+
+	var qcow2Img string
+
+	checkGzip, err := isGzip(image)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to detect the image filetype: %v", err)
+	}
+	if checkGzip {
+		klog.Infof("Image %s is in gzip format, extracting it", image)
+		qcow2Img = filepath.Join(tmpDir, ova.VolName+".qcow2")
+		err = gunzipIt(image, qcow2Img)
 		if err != nil {
-			return err
+			return "", "", err
 		}
-		klog.Infof("OVA file Compression completed")
-
-		fmt.Printf("\n\nSuccessfully converted Qcow2 image to OVA format, find at %s\nOS root password: %s\n", ovaGZfile, opt.OSPassword)
-		return nil
-	},
+		klog.Infof("Extract complete")
+	} else {
+		qcow2Img = image
+	}
+
+	ovaImgDir := filepath.Join(tmpDir, "ova-img-dir")
+	err = os.Mkdir(ovaImgDir, 0755)
+	if err != nil {
+		return "", "", err
+	}
+
+	rawImg := filepath.Join(ovaImgDir, ova.VolNameRaw)
+
+	klog.Infof("Converting Qcow2(%s) image to raw(%s) format", qcow2Img, rawImg)
+	err = qemuImgConvertQcow2Raw(qcow2Img, rawImg)
+	if err != nil {
+		return "", "", err
+	}
+	klog.Infof("Conversion completed")
+
+	klog.Infof("Resizing the image %s to %dG", rawImg, opt.ImageSize)
+	err = qemuImgResize("-f", "raw", rawImg, fmt.Sprintf("%dG", opt.ImageSize))
+	if err != nil {
+		return "", "", err
+	}
+	klog.Infof("Resize completed")
+	if err := ctx.Err(); err != nil {
+		return "", "", err
+	}
+
+	klog.Infof("Preparing the image")
+	err = prep.Prepare4capture(mnt, rawImg, opt.ImageDist, opt.RHNUser, opt.RHNPassword, opt.OSPassword)
+	if err := ctx.Err(); err != nil {
+		return "", "", err
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed while preparing the image for %s distro, err: %v", opt.ImageDist, err)
+	}
+	klog.Infof("Preparation completed")
+
+	klog.Infof("Creating an OVA bundle")
+	ovafile := filepath.Join(tmpDir, opt.ImageName+".ova")
+	if err := ova.CreateTarArchive(ovaImgDir, ovafile, opt.TargetDiskSize); err != nil {
+		return "", "", fmt.Errorf("failed to create ova bundle, err: %v", err)
+	}
+	klog.Infof("OVA bundle creation completed: %s", ovafile)
+	if err := ctx.Err(); err != nil {
+		return "", "", err
+	}
+
+	klog.Infof("Compressing an OVA file")
+	ovaGZfile := filepath.Join(dir, opt.ImageName+".ova.gz")
+	sum, err := gzipIt(ovafile, ovaGZfile)
+	if err != nil {
+		return "", "", err
+	}
+	klog.Infof("OVA file Compression completed")
+	// written last, marks the completed conversion
+	if err := utils.WriteChecksumFile(ovaGZfile, sum); err != nil {
+		return "", "", fmt.Errorf("failed to write the checksum file, err: %v", err)
+	}
+	return ovaGZfile, sum, nil
 }
 
 func init() {
 	Cmd.Flags().StringVar(&pkg.ImageCMDOptions.ImageName, "image-name", "", "Name of the resultant OVA image")
 	Cmd.Flags().StringVar(&pkg.ImageCMDOptions.ImageURL, "image-url", "", "URL or absolute local file path to the <QCOW2>.gz image")
+	Cmd.Flags().StringVar(&pkg.ImageCMDOptions.ImageChecksum, "image-checksum", "", "Checksum of the image from the --image-url verified before the conversion, sha256:<hex digest> or the URL or file path of a sha256sum formatted file(e.g. SHA256SUMS) listing the image")
 	Cmd.Flags().StringVar(&pkg.ImageCMDOptions.ImageDist, "image-dist", "", "Image Distribution(supported: rhel, centos, coreos)")
 	Cmd.Flags().Uint64Var(&pkg.ImageCMDOptions.ImageSize, "image-size", 11, "Size (in GB) of the resultant OVA image")
 	Cmd.Flags().Int64Var(&pkg.ImageCMDOptions.TargetDiskSize, "target-disk-size", 120, "Size (in GB) of the target disk volume where OVA will be copied")
@@ -37,8 +37,7 @@ func (p *Rule) String() string {
 	return "diskspace"
 }
 
-func (p *Rule) Verify() error {
-	opt := pkg.ImageCMDOptions
+func (p *Rule) Verify(opt *pkg.ImageOptions) error {
 	var stat syscall.Statfs_t
 	err := syscall.Statfs(opt.TempDir, &stat)
 	if err != nil {
@@ -53,6 +52,6 @@ func (p *Rule) Verify() error {
 	return nil
 }
 
-func (p *Rule) Hint() string {
-	return "make some space in the " + pkg.ImageCMDOptions.TempDir
+func (p *Rule) Hint(opt *pkg.ImageOptions) string {
+	return "make some space in the " + opt.TempDir
 }
@@ -16,6 +16,8 @@ package diskspace
 
 import (
 	"fmt"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg"
 )
 
 type Rule struct {
@@ -25,10 +27,10 @@ func (p *Rule) String() string {
 	return "diskspace"
 }
 
-func (p *Rule) Verify() error {
+func (p *Rule) Verify(opt *pkg.ImageOptions) error {
 	return fmt.Errorf("Not supported on Windows platform")
 }
 
-func (p *Rule) Hint() string {
+func (p *Rule) Hint(opt *pkg.ImageOptions) string {
 	return "Please retry on linux/ppc64le platform"
 }
@@ -28,13 +28,13 @@ func (p *Rule) String() string {
 	return "image-name"
 }
 
-func (p *Rule) Verify() error {
-	if _, err := os.Stat(pkg.ImageCMDOptions.ImageName + ".ova.gz"); !os.IsNotExist(err) {
-		return fmt.Errorf("file already exist with name: %s", pkg.ImageCMDOptions.ImageName+".ova.gz")
+func (p *Rule) Verify(opt *pkg.ImageOptions) error {
+	if _, err := os.Stat(opt.ImageName + ".ova.gz"); !os.IsNotExist(err) {
+		return fmt.Errorf("file already exist with name: %s", opt.ImageName+".ova.gz")
 	}
 	return nil
 }
 
-func (p *Rule) Hint() string {
+func (p *Rule) Hint(opt *pkg.ImageOptions) string {
 	return "Please choose a different image-name and retry"
 }
@@ -17,6 +17,8 @@ package platform
 import (
 	"fmt"
 	"runtime"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg"
 )
 
 type Rule struct {
@@ -26,13 +28,13 @@ func (p *Rule) String() string {
 	return "platform"
 }
 
-func (p *Rule) Verify() error {
+func (p *Rule) Verify(opt *pkg.ImageOptions) error {
 	if runtime.GOOS != "linux" && runtime.GOARCH != "ppc64le" {
 		return fmt.Errorf("unsupported os: %s, platform: %s", runtime.GOOS, runtime.GOARCH)
 	}
 	return nil
 }
 
-func (p *Rule) Hint() string {
+func (p *Rule) Hint(opt *pkg.ImageOptions) string {
 	return "supported only on linux/ppc64le platform, please run it on RHEL/CentOS(ppc64le)"
 }
@@ -18,6 +18,8 @@ import (
 	"os/exec"
 
 	"k8s.io/klog/v2"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg"
 )
 
 var commands = map[string]string{
@@ -33,7 +35,7 @@ func (p *Rule) String() string {
 	return "tools"
 }
 
-func (p *Rule) Verify() error {
+func (p *Rule) Verify(opt *pkg.ImageOptions) error {
 	for command := range commands {
 		path, err := exec.LookPath(command)
 		if err != nil {
@@ -45,7 +47,7 @@ func (p *Rule) Verify() error {
 	return nil
 }
 
-func (p *Rule) Hint() string {
+func (p *Rule) Hint(opt *pkg.ImageOptions) string {
 	if p.failedCommand != "" {
 		return commands[p.failedCommand]
 	}
@@ -17,6 +17,8 @@ package user
 import (
 	"fmt"
 	"os"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg"
 )
 
 type Rule struct {
@@ -26,13 +28,13 @@ func (p *Rule) String() string {
 	return "user"
 }
 
-func (p *Rule) Verify() error {
+func (p *Rule) Verify(opt *pkg.ImageOptions) error {
 	if os.Geteuid() != 0 {
 		return fmt.Errorf("non-root user is executing the qcow2ova sub-command")
 	}
 	return nil
 }
 
-func (p *Rule) Hint() string {
+func (p *Rule) Hint(opt *pkg.ImageOptions) string {
 	return "Expected root user to execute the qcow2ova subcommand"
 }
@@ -25,8 +25,8 @@ import (
 var rules []Rule
 
 type Rule interface {
-	Verify() error
-	Hint() string
+	Verify(opt *pkg.ImageOptions) error
+	Hint(opt *pkg.ImageOptions) string
 	String() string
 }
 
@@ -34,17 +34,18 @@ func AddRule(r Rule) {
 	rules = append(rules, r)
 }
 
-func Validate() error {
+// Validate runs the preflight checks for the options, the checks in the opt.PreflightSkip are skipped
+func Validate(opt *pkg.ImageOptions) error {
 	for _, rule := range rules {
 		ruleStr := rule.String()
 		klog.Infof("Checking: %s\n", ruleStr)
-		if utils.Contains(pkg.ImageCMDOptions.PreflightSkip, ruleStr) {
+		if utils.Contains(opt.PreflightSkip, ruleStr) {
 			klog.Infof("SKIPPED!")
 			continue
 		}
-		err := rule.Verify()
+		err := rule.Verify(opt)
 		if err != nil {
-			return fmt.Errorf("check failed: %v \nHint: %v", err, rule.Hint())
+			return fmt.Errorf("check failed: %v \nHint: %v", err, rule.Hint(opt))
 		}
 	}
 	return nil
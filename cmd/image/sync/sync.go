@@ -16,12 +16,14 @@ package sync
 
 import (
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"strings"
 	"time"
 
 	"github.com/ppc64le-cloud/pvsadm/pkg"
 	"github.com/ppc64le-cloud/pvsadm/pkg/client"
+	"github.com/ppc64le-cloud/pvsadm/pkg/utils"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v2"
 	"k8s.io/klog/v2"
@@ -33,6 +35,7 @@ type copyWorkload struct {
 	srcBucket string
 	tgtBucket string
 	srcObject string
+	size      int64
 }
 
 // sync constants
@@ -94,7 +97,7 @@ Sample spec.yaml file:
 		start := time.Now()
 
 		//Create bluemix client
-		bxCli, err := client.NewClientWithEnv(apikey, pkg.Options.Environment, pkg.Options.Debug)
+		bxCli, err := client.NewClientWithEnv(cmd.Context(), apikey, pkg.Options.Environment, pkg.Options.Debug)
 		if err != nil {
 			return err
 		}
@@ -113,24 +116,35 @@ Sample spec.yaml file:
 			return err
 		}
 
+		var progress *utils.Progress
 		copyWorker := func(copyJobs <-chan copyWorkload, results chan<- bool, workerId int) {
 			for copyJob := range copyJobs {
+				// no new copies are started once interrupted
+				if cmd.Context().Err() != nil {
+					klog.Infof("Skipping the copy of object: %s to bucket: %s, interrupted", copyJob.srcObject, copyJob.tgtBucket)
+					results <- false
+					continue
+				}
 				start := time.Now()
 				klog.Infof("Copying object: %s src bucket: %s dest bucket: %s", copyJob.srcObject, copyJob.srcBucket, copyJob.tgtBucket)
-				err = copyJob.s3Cli.CopyObjectToBucket(copyJob.srcBucket, copyJob.tgtBucket, copyJob.srcObject)
+				err := copyJob.s3Cli.CopyObjectToBucket(copyJob.srcBucket, copyJob.tgtBucket, copyJob.srcObject)
 				if err != nil {
 					klog.Errorf("ERROR: %v, Copy object %s failed", err, copyJob.srcObject)
 					results <- false
+					continue
 				}
 				duration := time.Since(start)
 				klog.Infof("Copying object: %s from bucket: %s to bucket: %s took %v", copyJob.srcObject, copyJob.srcBucket, copyJob.tgtBucket, duration)
+				progress.Add(copyJob.size)
 				results <- true
 			}
 		}
 
 		// Calculating total channels required
 		totalChannels := 0
-		for _, item := range spec {
+		var totalBytes int64
+		sizes := make([]map[string]int64, len(spec))
+		for i, item := range spec {
 			s3Cli, err = client.NewS3Client(bxCli, item.Source.Cos, item.Source.Region)
 			if err != nil {
 				return err
@@ -142,16 +156,22 @@ Sample spec.yaml file:
 				return err
 			}
 
-			selectedObjects, err = s3Cli.SelectObjects(item.Source.Bucket, item.Source.Object)
+			sizes[i], err = s3Cli.ObjectSizes(item.Source.Bucket, item.Source.Object)
 			if err != nil {
 				klog.Errorf("Select Objects failed: %v", err)
 				return err
 			}
 
 			noOfTargets := len(item.Target)
-			totalChannelsForSrc := noOfTargets * len(selectedObjects)
+			totalChannelsForSrc := noOfTargets * len(sizes[i])
 			totalChannels = totalChannels + totalChannelsForSrc
+			for _, size := range sizes[i] {
+				totalBytes += size * int64(noOfTargets)
+			}
 		}
+		progress = utils.NewProgress(fmt.Sprintf("Copying %d objects", totalChannels), totalBytes)
+		// no-op once done, stops the reporting on the early returns
+		defer progress.Done(errors.New("copy objects stopped"))
 
 		// Creating workers and channels
 		copyJobs := make(chan copyWorkload, totalChannels)
@@ -160,7 +180,7 @@ Sample spec.yaml file:
 			go copyWorker(copyJobs, results, w)
 		}
 
-		for _, item := range spec {
+		for i, item := range spec {
 			// Creating S3 client
 			s3Cli, err = client.NewS3Client(bxCli, item.Source.Cos, item.Source.Region)
 			if err != nil {
@@ -192,6 +212,7 @@ Sample spec.yaml file:
 						srcBucket: item.Source.Bucket,
 						tgtBucket: targetItem.Bucket,
 						srcObject: srcObject,
+						size:      sizes[i][srcObject],
 					}
 					copyJobs <- copyJob
 				}
@@ -210,8 +231,17 @@ Sample spec.yaml file:
 		}
 		close(copyJobs)
 
+		var copyErr error
+		if failedCopies > 0 {
+			copyErr = errors.New("copy objects failed")
+		}
+		progress.Done(copyErr)
+
 		duration := time.Since(start)
 		klog.Infof("No of copies passed: %d No of copies failed: %d Total elapsed time: %v", passedCopies, failedCopies, duration)
+		if err := cmd.Context().Err(); err != nil {
+			return fmt.Errorf("copy objects interrupted: %v", err)
+		}
 		if failedCopies > 0 {
 			return errors.New("copy objects failed")
 		}
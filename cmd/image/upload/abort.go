@@ -0,0 +1,73 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package upload
+
+import (
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg"
+	"github.com/ppc64le-cloud/pvsadm/pkg/client"
+	"github.com/ppc64le-cloud/pvsadm/pkg/image"
+	"github.com/ppc64le-cloud/pvsadm/pkg/utils"
+)
+
+var abortCmd = &cobra.Command{
+	Use:   "abort",
+	Short: "Abort the incomplete uploads",
+	Long: `Abort the incomplete multipart uploads left behind by the failed or interrupted uploads, the parts of the
+incomplete uploads are billed as the storage till aborted. The local upload state files are removed along with them.
+
+Examples:
+
+# Abort all the incomplete uploads of the bucket
+pvsadm image upload abort --bucket bucket1320
+
+# Abort the incomplete uploads of an object
+pvsadm image upload abort --bucket bucket1320 -o centos-8-latest.ova.gz
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opt := pkg.ImageCMDOptions
+
+		bxCli, err := client.NewClientWithEnv(cmd.Context(), pkg.Options.APIKey, pkg.Options.Environment, pkg.Options.Debug)
+		if err != nil {
+			return err
+		}
+
+		aborted, err := image.AbortUploads(bxCli, opt.InstanceName, opt.BucketName, opt.Region, opt.ObjectName)
+		if len(aborted) == 0 && err == nil {
+			klog.Infof("No incomplete uploads found in the bucket %s", opt.BucketName)
+			return nil
+		}
+		// the uploads aborted before a failure are listed as well
+		if len(aborted) != 0 {
+			klog.Infof("Aborted %d incomplete uploads", len(aborted))
+			if rerr := utils.NewTable().Render(aborted, nil); rerr != nil && err == nil {
+				err = rerr
+			}
+		}
+		return err
+	},
+}
+
+func init() {
+	abortCmd.Flags().StringVarP(&pkg.ImageCMDOptions.InstanceName, "cos-instance-name", "n", "", "Cloud Object Storage instance name, the bucket is looked up in all the instances if not set.")
+	abortCmd.Flags().StringVarP(&pkg.ImageCMDOptions.BucketName, "bucket", "b", "", "Cloud Object Storage bucket name.")
+	abortCmd.Flags().StringVarP(&pkg.ImageCMDOptions.ObjectName, "cos-object-name", "o", "", "Cloud Object Storage Object Name, all the objects of the bucket if not set.")
+	abortCmd.Flags().StringVarP(&pkg.ImageCMDOptions.Region, "bucket-region", "r", "us-south", "Cloud Object Storage bucket region.")
+	_ = abortCmd.MarkFlagRequired("bucket")
+	abortCmd.Flags().SortFlags = false
+}
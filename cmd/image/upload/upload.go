@@ -16,23 +16,25 @@ package upload
 
 import (
 	"fmt"
-	"path/filepath"
 
 	"github.com/IBM-Cloud/bluemix-go/api/resource/resourcev1/management"
 	"github.com/ppc64le-cloud/pvsadm/pkg"
 	"github.com/ppc64le-cloud/pvsadm/pkg/client"
+	"github.com/ppc64le-cloud/pvsadm/pkg/image"
 	"github.com/ppc64le-cloud/pvsadm/pkg/utils"
 	"github.com/spf13/cobra"
 	"k8s.io/klog/v2"
 )
 
 const (
-	ServiceType              = "cloud-object-storage"
 	UseExistingPromptMessage = "Would You Like to use Available COS Instance for creating bucket?"
 	CreatePromptMessage      = "Would you like to create new COS Instance?"
 	ResourceGroupAPIRegion   = "global"
 )
 
+// checksum is the expected hex sha256 digest of the file from the --image-checksum
+var checksum string
+
 var Cmd = &cobra.Command{
 	Use:   "upload",
 	Short: "Upload the image to the IBM COS",
@@ -58,70 +60,67 @@ pvsadm image upload --bucket bucket1320 -f centos-8-latest.ova.gz --region <Regi
 
 #If user likes to give different name to s3 Object
 pvsadm image upload --bucket bucket1320 -f centos-8-latest.ova.gz -o centos8latest.ova.gz
+
+#Uploads are resumed from ~/.pvsadm/uploads by rerunning the same command, e.g. after a network failure
+pvsadm image upload --bucket bucket1320 -f centos-8-latest.ova.gz --part-size 128 --concurrency 8
+
+#The checksum of the file is stored with the object, the file is verified against the rhcos-461.ova.gz.sha256 written by
+#the pvsadm image qcow2ova if present, or against the --image-checksum
+pvsadm image upload --bucket bucket1320 -f rhcos-461.ova.gz --image-checksum sha256:<hex digest>
+
+#Abort the incomplete uploads of the bucket
+pvsadm image upload abort --bucket bucket1320
 `,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if pkg.ImageCMDOptions.PartSize*1024*1024 < client.MinPartSize {
+			return fmt.Errorf("--part-size must be at least %d MiB", client.MinPartSize/1024/1024)
+		}
+		if pkg.ImageCMDOptions.Concurrency < 1 {
+			return fmt.Errorf("--concurrency must be at least 1")
+		}
+		checksum = ""
+		if pkg.ImageCMDOptions.ImageChecksum != "" {
+			var err error
+			if checksum, err = utils.ParseChecksum(pkg.ImageCMDOptions.ImageChecksum); err != nil {
+				return fmt.Errorf("--image-checksum: %v", err)
+			}
+		}
+		return nil
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		var s3Cli *client.S3Client
-		var bucketExists bool = false
-		var apikey string = pkg.Options.APIKey
 		opt := pkg.ImageCMDOptions
 
 		//Create bluemix client
-		bxCli, err := client.NewClientWithEnv(apikey, pkg.Options.Environment, pkg.Options.Debug)
-
+		bxCli, err := client.NewClientWithEnv(cmd.Context(), pkg.Options.APIKey, pkg.Options.Environment, pkg.Options.Debug)
 		if err != nil {
 			return err
 		}
 
-		instances, err := bxCli.ListServiceInstances(ServiceType)
-		if err != nil {
-			return err
-		}
-
-		//check if bucket exists
-		if opt.InstanceName != "" {
-			s3Cli, err = client.NewS3Client(bxCli, opt.InstanceName, opt.Region)
-			if err != nil {
+		//check for bucket across the instances
+		if opt.InstanceName == "" {
+			if opt.InstanceName, err = image.FindBucket(bxCli, opt.BucketName, opt.Region); err != nil {
 				return err
 			}
+		}
 
-			bucketExists, err = s3Cli.CheckBucketExists(opt.BucketName)
+		// Ask if user likes to use existing instance
+		if opt.InstanceName == "" {
+			instances, err := bxCli.ListServiceInstances(image.ServiceType)
 			if err != nil {
 				return err
 			}
-		} else if len(instances) != 0 {
-			//check for bucket across the instances
-			for instanceName, _ := range instances {
-				s3Cli, err = client.NewS3Client(bxCli, instanceName, opt.Region)
-				if err != nil {
-					return err
-				}
-
-				bucketExists, err = s3Cli.CheckBucketExists(opt.BucketName)
-				if err != nil {
-					return err
-				}
-
-				if bucketExists {
-					opt.InstanceName = instanceName
-					klog.Infof("Found bucket %s in the %s instance", opt.BucketName, opt.InstanceName)
-					break
-				}
-			}
-		} else if len(instances) == 0 {
-			klog.Infof("No active Cloud Object Storage instances were found in the account\n")
-		}
-
-		// Ask if user likes to use existing instance
-		if opt.InstanceName == "" && len(instances) != 0 {
-			klog.Infof("Bucket %s not found in the account provided\n", opt.BucketName)
-			if utils.AskConfirmation(UseExistingPromptMessage) {
-				availableInstances := []string{}
-				for name, _ := range instances {
-					availableInstances = append(availableInstances, name)
+			if len(instances) == 0 {
+				klog.Infof("No active Cloud Object Storage instances were found in the account\n")
+			} else {
+				klog.Infof("Bucket %s not found in the account provided\n", opt.BucketName)
+				if utils.AskConfirmation(UseExistingPromptMessage) {
+					availableInstances := []string{}
+					for name := range instances {
+						availableInstances = append(availableInstances, name)
+					}
+					opt.InstanceName = utils.SelectItem("Select Cloud Object Storage Instance:", availableInstances)
+					klog.Infof("Selected InstanceName is %s\n", opt.InstanceName)
 				}
-				selectedInstance := utils.SelectItem("Select Cloud Object Storage Instance:", availableInstances)
-				opt.InstanceName = selectedInstance
-				klog.Infof("Selected InstanceName is %s\n", opt.InstanceName)
 			}
 		}
 
@@ -151,48 +150,25 @@ pvsadm image upload --bucket bucket1320 -f centos-8-latest.ova.gz -o centos8late
 			opt.InstanceName = utils.ReadUserInput("Type Name of the Cloud Object Storage instance:")
 			klog.Infof("Creating a new cos %s instance\n", opt.InstanceName)
 
-			_, err = bxCli.CreateServiceInstance(opt.InstanceName, ServiceType, opt.ServicePlan,
+			_, err = bxCli.CreateServiceInstance(opt.InstanceName, image.ServiceType, opt.ServicePlan,
 				opt.ResourceGrp, ResourceGroupAPIRegion)
 			if err != nil {
 				return err
 			}
 		}
 
-		//create s3 client
-		s3Cli, err = client.NewS3Client(bxCli, opt.InstanceName, opt.Region)
-		if err != nil {
-			return err
-		}
-
-		//Check if object exists or not
-		if opt.ObjectName == "" {
-			opt.ObjectName = filepath.Base(opt.ImageName)
-		}
-
-		if s3Cli.CheckIfObjectExists(opt.BucketName, opt.ObjectName) {
-			return fmt.Errorf("%s object already exists in the %s bucket", opt.ObjectName, opt.BucketName)
-		}
-
-		//Create a new bucket
-		if !bucketExists {
-			klog.Infof("Creating a new bucket %s\n", opt.BucketName)
-			s3Cli, err = client.NewS3Client(bxCli, opt.InstanceName, opt.Region)
-			if err != nil {
-				return err
-			}
-
-			err = s3Cli.CreateBucket(opt.BucketName)
-			if err != nil {
-				return err
-			}
-		}
-
 		//upload the Image to S3 bucket
-		err = s3Cli.UploadObject(opt.ImageName, opt.ObjectName, opt.BucketName)
-		if err != nil {
-			return err
-		}
-		return nil
+		_, err = image.Upload(cmd.Context(), bxCli, image.UploadRequest{
+			File:         opt.ImageName,
+			InstanceName: opt.InstanceName,
+			Bucket:       opt.BucketName,
+			Region:       opt.Region,
+			ObjectName:   opt.ObjectName,
+			PartSize:     opt.PartSize * 1024 * 1024,
+			Concurrency:  opt.Concurrency,
+			Checksum:     checksum,
+		})
+		return err
 	},
 }
 
@@ -204,7 +180,11 @@ func init() {
 	Cmd.Flags().StringVarP(&pkg.ImageCMDOptions.ImageName, "file", "f", "", "The PATH to the file to upload.")
 	Cmd.Flags().StringVarP(&pkg.ImageCMDOptions.ObjectName, "cos-object-name", "o", "", "Cloud Object Storage Object Name(Default: filename from --file|-f option)")
 	Cmd.Flags().StringVarP(&pkg.ImageCMDOptions.Region, "bucket-region", "r", "us-south", "Cloud Object Storage bucket region.")
+	Cmd.Flags().Int64Var(&pkg.ImageCMDOptions.PartSize, "part-size", client.DefaultPartSize/1024/1024, "Size of the parts of the multipart upload in MiB, the parts are uploaded in parallel and the completed parts aren't uploaded again on the rerun.")
+	Cmd.Flags().IntVar(&pkg.ImageCMDOptions.Concurrency, "concurrency", client.DefaultConcurrency, "Number of the parts uploaded in parallel.")
+	Cmd.Flags().StringVar(&pkg.ImageCMDOptions.ImageChecksum, "image-checksum", "", "Expected checksum of the file, sha256:<hex digest>, the checksum is stored with the object for the pvsadm image import to verify when run with the --image-checksum.")
 	_ = Cmd.MarkFlagRequired("bucket")
 	_ = Cmd.MarkFlagRequired("file")
+	Cmd.AddCommand(abortCmd)
 	Cmd.Flags().SortFlags = false
 }
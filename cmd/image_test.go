@@ -0,0 +1,406 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg/client"
+	"github.com/ppc64le-cloud/pvsadm/pkg/client/fake"
+	pkgimage "github.com/ppc64le-cloud/pvsadm/pkg/image"
+)
+
+func TestImageUpload(t *testing.T) {
+	s := fakeCloud(t)
+	s.AddCOSInstance("cos-images")
+	data := []byte("rhcos image")
+	file := filepath.Join(t.TempDir(), "rhcos.ova.gz")
+	if err := ioutil.WriteFile(file, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	args := []string{"image", "upload", "--cos-instance-name", "cos-images", "--bucket", "images", "--file", file, "--bucket-region", "us-south"}
+	if _, err := pvsadm(t, args...); err != nil {
+		t.Fatalf("image upload failed: %v", err)
+	}
+	obj := s.Object("images", "rhcos.ova.gz")
+	if obj == nil || !bytes.Equal(obj.Data, data) {
+		t.Fatalf("uploaded object = %+v, want the content of the file", obj)
+	}
+	if sum := sha256.Sum256(data); obj.Metadata["Sha256"] != hex.EncodeToString(sum[:]) {
+		t.Errorf("checksum metadata of the uploaded object = %q, want %x", obj.Metadata["Sha256"], sum)
+	}
+
+	// the bucket is found in the account without the --cos-instance-name, the existing object is not overwritten
+	if _, err := pvsadm(t, "image", "upload", "--bucket", "images", "--file", file, "--bucket-region", "us-south"); err == nil {
+		t.Errorf("image upload of the existing object succeeded, want an error")
+	}
+}
+
+func TestImageUploadChecksum(t *testing.T) {
+	data := []byte("rhcos image")
+	sum := sha256.Sum256(data)
+	other := sha256.Sum256([]byte("centos image"))
+	tests := []struct {
+		name     string
+		sidecar  string
+		checksum string
+		wantErr  bool
+	}{
+		{name: "checksum file", sidecar: fmt.Sprintf("%x  rhcos.ova.gz\n", sum)},
+		{name: "--image-checksum", checksum: fmt.Sprintf("sha256:%x", sum)},
+		{name: "checksum file mismatch", sidecar: fmt.Sprintf("%x  rhcos.ova.gz\n", other), wantErr: true},
+		{name: "--image-checksum mismatch", checksum: fmt.Sprintf("sha256:%x", other), wantErr: true},
+		{name: "invalid --image-checksum", checksum: "md5:abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := fakeCloud(t)
+			s.AddCOSInstance("cos-images")
+			file := filepath.Join(t.TempDir(), "rhcos.ova.gz")
+			if err := ioutil.WriteFile(file, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if tt.sidecar != "" {
+				if err := ioutil.WriteFile(file+".sha256", []byte(tt.sidecar), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			args := []string{"image", "upload", "--cos-instance-name", "cos-images", "--bucket", "images", "--file", file}
+			if tt.checksum != "" {
+				args = append(args, "--image-checksum", tt.checksum)
+			}
+			_, err := pvsadm(t, args...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("image upload error = %v, wantErr %v", err, tt.wantErr)
+			}
+			// nothing is uploaded if the file doesn't match the checksum
+			if uploaded := s.Object("images", "rhcos.ova.gz") != nil; uploaded == tt.wantErr {
+				t.Errorf("object uploaded = %v, want %v", uploaded, !tt.wantErr)
+			}
+		})
+	}
+}
+
+// interruptedUpload fails the upload of the 11MiB file in the 5MiB parts at the second part, returns the upload args
+// and the content of the file
+func interruptedUpload(t *testing.T, s *fake.Server) ([]string, []byte) {
+	t.Helper()
+	s.AddCOSInstance("cos-images")
+	data := make([]byte, 11*1024*1024)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	file := filepath.Join(t.TempDir(), "rhcos.ova.gz")
+	if err := ioutil.WriteFile(file, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Fail(http.MethodPut, "partNumber=2", http.StatusBadRequest, 1)
+	args := []string{"image", "upload", "--cos-instance-name", "cos-images", "--bucket", "images", "--file", file, "--part-size", "5", "--concurrency", "1"}
+	if _, err := pvsadm(t, args...); err == nil {
+		t.Fatalf("image upload succeeded, want the failure of the second part")
+	}
+	if uploads := s.Uploads("images"); len(uploads) != 1 {
+		t.Fatalf("incomplete uploads = %v, want 1", uploads)
+	}
+	return args, data
+}
+
+func uploadState(t *testing.T) *client.UploadState {
+	t.Helper()
+	file, err := client.UploadStateFile("images", "rhcos.ova.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := client.ReadUploadState(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
+
+func TestImageUploadResume(t *testing.T) {
+	s := fakeCloud(t)
+	args, data := interruptedUpload(t, s)
+	state := uploadState(t)
+	if state == nil || len(state.Parts) != 1 {
+		t.Fatalf("upload state = %+v, want the first part uploaded", state)
+	}
+
+	if _, err := pvsadm(t, args...); err != nil {
+		t.Fatalf("resumed image upload failed: %v", err)
+	}
+	obj := s.Object("images", "rhcos.ova.gz")
+	if obj == nil || !bytes.Equal(obj.Data, data) {
+		t.Fatalf("uploaded object doesn't match the content of the file")
+	}
+	if !strings.HasSuffix(obj.ETag, `-3"`) {
+		t.Errorf("ETag of the uploaded object = %s, want the ETag of 3 parts", obj.ETag)
+	}
+	if uploads := s.Uploads("images"); len(uploads) != 0 {
+		t.Errorf("incomplete uploads after the resume = %v, want none", uploads)
+	}
+	if state := uploadState(t); state != nil {
+		t.Errorf("upload state after the resume = %+v, want removed", state)
+	}
+}
+
+func TestImageUploadAbort(t *testing.T) {
+	s := fakeCloud(t)
+	interruptedUpload(t, s)
+
+	if _, err := pvsadm(t, "image", "upload", "abort", "--bucket", "images"); err != nil {
+		t.Fatalf("image upload abort failed: %v", err)
+	}
+	if uploads := s.Uploads("images"); len(uploads) != 0 {
+		t.Errorf("incomplete uploads after the abort = %v, want none", uploads)
+	}
+	if state := uploadState(t); state != nil {
+		t.Errorf("upload state after the abort = %+v, want removed", state)
+	}
+}
+
+func TestImageImport(t *testing.T) {
+	interval := pkgimage.WatchInterval
+	pkgimage.WatchInterval = 10 * time.Millisecond
+	t.Cleanup(func() { pkgimage.WatchInterval = interval })
+
+	s := fakeCloud(t)
+	ws := s.AddWorkspace("ws", "dal12")
+	cos := s.AddCOSInstance("cos-images")
+	cos.AddBucket("images", "us-south-standard")
+	sum := sha256.Sum256([]byte("rhcos image"))
+	cos.PutObjectWithMetadata("images", "rhcos.ova.gz", []byte("rhcos image"), map[string]string{"Sha256": hex.EncodeToString(sum[:])})
+	cos.PutObject("images", "fedora.ova.gz", []byte("fedora image"))
+	checksum := fmt.Sprintf("sha256:%x", sum)
+
+	tests := []struct {
+		name     string
+		object   string
+		checksum string
+		wantErr  bool
+	}{
+		{name: "import", object: "rhcos.ova.gz"},
+		{name: "missing object", object: "centos.ova.gz", wantErr: true},
+		{name: "object without the checksum", object: "fedora.ova.gz"},
+		{name: "verified checksum", object: "rhcos.ova.gz", checksum: checksum},
+		{name: "checksum mismatch", object: "rhcos.ova.gz", checksum: fmt.Sprintf("sha256:%x", sha256.Sum256(nil)), wantErr: true},
+		{name: "checksum required", object: "fedora.ova.gz", checksum: checksum, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imageName := "img-" + tt.name
+			args := []string{"image", "import", "--pvs-instance-name", "ws", "--bucket", "images", "--bucket-region", "us-south",
+				"--object", tt.object, "--pvs-image-name", imageName, "--watch"}
+			if tt.checksum != "" {
+				args = append(args, "--image-checksum", tt.checksum)
+			}
+			_, err := pvsadm(t, args...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("image import error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var state string
+			for _, img := range ws.Images() {
+				if *img.Name == imageName {
+					state = img.State
+				}
+			}
+			if tt.wantErr {
+				// refused before the import is started
+				if state != "" {
+					t.Errorf("image %s imported, want refused", imageName)
+				}
+				return
+			}
+			if state != fake.ImageStateActive {
+				t.Errorf("state of the imported image = %q, want %q", state, fake.ImageStateActive)
+			}
+		})
+	}
+}
+
+func TestImageSync(t *testing.T) {
+	s := fakeCloud(t)
+	cos := s.AddCOSInstance("cos-images")
+	cos.AddBucket("src", "us-south-smart")
+	cos.AddBucket("dst-1", "us-south-smart")
+	cos.AddBucket("dst-2", "us-east-standard")
+	cos.PutObject("src", "rhcos.ova.gz", []byte("rhcos image"))
+	cos.PutObject("src", "centos.ova.gz", []byte("centos image"))
+	cos.PutObject("src", "README.txt", []byte("readme"))
+
+	spec := filepath.Join(t.TempDir(), "spec.yaml")
+	if err := ioutil.WriteFile(spec, []byte(`
+- source:
+    bucket: src
+    cos: cos-images
+    object: ".ova.gz"
+    storageClass: smart
+    region: us-south
+  target:
+  - bucket: dst-1
+    storageClass: smart
+    region: us-south
+  - bucket: dst-2
+    storageClass: standard
+    region: us-east
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := pvsadm(t, "image", "sync", "--spec-file", spec); err != nil {
+		t.Fatalf("image sync failed: %v", err)
+	}
+	for _, bucket := range []string{"dst-1", "dst-2"} {
+		for _, key := range []string{"rhcos.ova.gz", "centos.ova.gz"} {
+			if s.Object(bucket, key) == nil {
+				t.Errorf("object %s not copied to the bucket %s", key, bucket)
+			}
+		}
+		if obj := s.Object(bucket, "README.txt"); obj != nil {
+			t.Errorf("object README.txt not matching the spec copied to the bucket %s", bucket)
+		}
+	}
+}
+
+// images returns the names of the images in the workspace
+func images(ws *fake.Workspace) map[string]int {
+	names := map[string]int{}
+	for _, img := range ws.Images() {
+		names[*img.Name]++
+	}
+	return names
+}
+
+func TestImagePublish(t *testing.T) {
+	interval := pkgimage.WatchInterval
+	pkgimage.WatchInterval = 10 * time.Millisecond
+	t.Cleanup(func() { pkgimage.WatchInterval = interval })
+
+	s := fakeCloud(t)
+	ws1 := s.AddWorkspace("ws-1", "dal12")
+	ws2 := s.AddWorkspace("ws-2", "lon04")
+	ws2.AddImage("rhcos-461", time.Now())
+	cos := s.AddCOSInstance("cos-images")
+	cos.AddBucket("images", "us-south-standard")
+
+	// converted already, the checksum file marks the completed conversion
+	dir := t.TempDir()
+	data := []byte("rhcos image")
+	file := filepath.Join(dir, "rhcos-461.ova.gz")
+	if err := ioutil.WriteFile(file, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+	if err := ioutil.WriteFile(file+".sha256", []byte(fmt.Sprintf("%x  rhcos-461.ova.gz\n", sum)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	spec := filepath.Join(t.TempDir(), "publish.yaml")
+	if err := ioutil.WriteFile(spec, []byte(`
+name: rhcos-461
+convert:
+  url: https://example.com/rhcos.qcow2.gz
+  dist: coreos
+  outputDir: `+dir+`
+upload:
+  bucket: images
+import:
+  workspaces:
+  - name: ws-1
+  - id: `+ws2.ID+`
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, run := range []string{"publish", "rerun"} {
+		if run == "rerun" {
+			// nothing is left to import, the HMAC keys aren't read
+			s.Fail(http.MethodGet, "/v1/resource_keys", http.StatusBadRequest, 100)
+		}
+		out, err := pvsadm(t, "image", "publish", "--spec-file", spec, "--watch")
+		if err != nil {
+			t.Fatalf("image publish %s failed: %v", run, err)
+		}
+		obj := s.Object("images", "rhcos-461.ova.gz")
+		if obj == nil || !bytes.Equal(obj.Data, data) {
+			t.Fatalf("%s: uploaded object = %+v, want the content of the OVA image", run, obj)
+		}
+		if obj.Metadata["Sha256"] != hex.EncodeToString(sum[:]) {
+			t.Errorf("%s: checksum metadata of the object = %q, want %x", run, obj.Metadata["Sha256"], sum)
+		}
+		// imported once into the ws-1, the image already present in the ws-2 is skipped
+		for _, ws := range []*fake.Workspace{ws1, ws2} {
+			if n := images(ws)["rhcos-461"]; n != 1 {
+				t.Errorf("%s: images named rhcos-461 in the workspace %s = %d, want 1", run, ws.Name, n)
+			}
+		}
+		if got := strings.Count(out, "skipped"); run == "publish" && got != 1 || run == "rerun" && got != 2 {
+			t.Errorf("%s: skipped imports in the output = %d\n%s", run, got, out)
+		}
+	}
+
+	// the object in the bucket doesn't match the image
+	if err := ioutil.WriteFile(file+".sha256", []byte(fmt.Sprintf("%x  rhcos-461.ova.gz\n", sha256.Sum256(nil))), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pvsadm(t, "image", "publish", "--spec-file", spec); err == nil {
+		t.Errorf("image publish of the mismatched object succeeded, want an error")
+	}
+}
+
+func TestImagePublishInvalidSpec(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+	}{
+		{"missing name", "file: rhcos.ova.gz\nupload:\n  bucket: images\nimport:\n  workspaces:\n  - name: ws-1\n"},
+		{"missing file and convert", "name: rhcos\nupload:\n  bucket: images\nimport:\n  workspaces:\n  - name: ws-1\n"},
+		{"file and convert", "name: rhcos\nfile: rhcos.ova.gz\nconvert:\n  url: rhcos.qcow2\n  dist: coreos\nupload:\n  bucket: images\nimport:\n  workspaces:\n  - name: ws-1\n"},
+		{"missing bucket", "name: rhcos\nfile: rhcos.ova.gz\nimport:\n  workspaces:\n  - name: ws-1\n"},
+		{"missing workspaces", "name: rhcos\nfile: rhcos.ova.gz\nupload:\n  bucket: images\n"},
+		{"unknown workspace", "name: rhcos\nfile: rhcos.ova.gz\nupload:\n  bucket: images\nimport:\n  workspaces:\n  - name: ws-2\n"},
+		{"unsupported storage type", "name: rhcos\nfile: rhcos.ova.gz\nupload:\n  bucket: images\nimport:\n  storageType: tier0\n  workspaces:\n  - name: ws-1\n"},
+		{"unknown field", "name: rhcos\nfile: rhcos.ova.gz\nupload:\n  bucket: images\n  storageClass: smart\nimport:\n  workspaces:\n  - name: ws-1\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := fakeCloud(t)
+			s.AddWorkspace("ws-1", "dal12")
+			s.AddCOSInstance("cos-images").AddBucket("images", "us-south-standard")
+			spec := filepath.Join(t.TempDir(), "publish.yaml")
+			if err := ioutil.WriteFile(spec, []byte(tt.spec), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := pvsadm(t, "image", "publish", "--spec-file", spec); err == nil {
+				t.Errorf("image publish succeeded, want an error")
+			}
+			if obj := s.Object("images", "rhcos.ova.gz"); obj != nil {
+				t.Errorf("object uploaded for the invalid spec")
+			}
+		})
+	}
+}
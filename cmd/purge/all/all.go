@@ -0,0 +1,502 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package all
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IBM-Cloud/power-go-client/power/models"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg"
+	"github.com/ppc64le-cloud/pvsadm/pkg/audit"
+	"github.com/ppc64le-cloud/pvsadm/pkg/client"
+	"github.com/ppc64le-cloud/pvsadm/pkg/purge"
+	"github.com/ppc64le-cloud/pvsadm/pkg/utils"
+)
+
+const (
+	deletePromptMessage = "Deleting all the above resources in the listed order, resources can't be claimed back once deleted. Do you really want to continue?"
+	pollInterval        = 15 * time.Second
+)
+
+var Cmd = &cobra.Command{
+	Use:   "all",
+	Short: "Purge all the powervs resources",
+	Long: `Purge all the powervs resources of the instances in the dependency order
+
+The vms are deleted first, followed by the volumes, network ports, networks and images. A stage
+starts once the resources deleted by the earlier stages are actually gone(up to --wait-timeout), the
+resources of a stage are deleted the same way as the other purge commands, i.e. --parallel, --wait
+and --ignore-errors apply and the summary is printed per stage. With --ignore-errors, the resources
+depending on the ones failed to delete(e.g. the volumes attached to a vm) are skipped. Volumes
+attached to, networks and images in use by the vms which are not part of the selection are skipped,
+so are the resources protected by --exclude-regexp, --exclude-ids-file or the do-not-delete tag.
+With multiple instances, the plans are listed together and the instances are purged one after the
+other, a failure in an instance doesn't stop the purge of the remaining ones.
+
+Examples:
+  # Tear down the whole workspace
+  pvsadm purge all --instance-name upstream-core
+
+  # Delete all the resources starts with k8s-cluster- and created before 4hrs
+  pvsadm purge all --instance-name upstream-core --regexp "^k8s-cluster-.*" --before 4h
+
+  # Tear down all the workspaces starts with ci-
+  pvsadm purge all --instance-regexp "^ci-"
+
+  # List the deletion plan and exit without deleting
+  pvsadm purge all --instance-name upstream-core --dry-run
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opt := pkg.Options
+
+		c, err := client.NewClientWithEnv(cmd.Context(), opt.APIKey, opt.Environment, opt.Debug)
+		if err != nil {
+			return err
+		}
+
+		pvmclients, err := client.NewPVMClientsWithEnv(c, opt.Environment)
+		if err != nil {
+			return err
+		}
+
+		plans := make([]*plan, len(pvmclients))
+		err = client.ForEach(pvmclients, func(i int, pvmclient *client.PVMClient) (err error) {
+			plans[i], err = newPlan(pvmclient)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		var rows []row
+		empty := true
+		for i, p := range plans {
+			for _, r := range p.resources() {
+				rows = append(rows, row{Workspace: pvmclients[i].Workspace(), Resource: r})
+			}
+			empty = empty && p.empty()
+		}
+		if err := utils.NewTable().Render(rows, client.ExcludeWorkspace(pvmclients, nil)); err != nil {
+			return err
+		}
+		if opt.DryRun || empty {
+			return nil
+		}
+		if !opt.NoPrompt && !utils.AskYesOrNo(deletePromptMessage) {
+			return nil
+		}
+
+		// instances are purged one after the other, a failure doesn't stop the purge of the other instances
+		var failedInstances []string
+		for i, pvmclient := range pvmclients {
+			if len(pvmclients) > 1 {
+				klog.Infof("Purging the instance: %s", pvmclient.InstanceName)
+			}
+			if err := purgeInstance(cmd.Context(), pvmclient, plans[i]); err != nil {
+				if len(pvmclients) == 1 || cmd.Context().Err() != nil {
+					return err
+				}
+				klog.Errorf("Failed to purge the instance: %s, err: %v", pvmclient.InstanceName, err)
+				failedInstances = append(failedInstances, pvmclient.InstanceName)
+			}
+		}
+		if len(failedInstances) != 0 {
+			return fmt.Errorf("failed to purge the instances: %s", strings.Join(failedInstances, ", "))
+		}
+		return nil
+	},
+}
+
+// row is the entry of the plan along with the workspace
+type row struct {
+	client.Workspace
+	Resource
+}
+
+// newPlan lists the resources of the instance selected by the user filters and builds the plan
+func newPlan(pvmclient *client.PVMClient) (*plan, error) {
+	opt := pkg.Options
+	vms, err := pvmclient.InstanceClient.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the list of vms of the instance %s: %v", pvmclient.InstanceName, err)
+	}
+
+	var cand candidates
+	if cand.vms, err = pvmclient.InstanceClient.GetAllPurgeable(opt.Before, opt.Since, opt.Expr); err != nil {
+		return nil, err
+	}
+	if cand.volumes, err = pvmclient.VolumeClient.GetAllPurgeableByLastUpdateDate(opt.Before, opt.Since, opt.Expr); err != nil {
+		return nil, fmt.Errorf("failed to get the list of volumes of the instance %s: %v", pvmclient.InstanceName, err)
+	}
+	if cand.networks, err = pvmclient.NetworkClient.GetAllPurgeable(opt.Before, opt.Since, opt.Expr); err != nil {
+		return nil, fmt.Errorf("failed to get the list of networks of the instance %s: %v", pvmclient.InstanceName, err)
+	}
+	if cand.images, err = pvmclient.ImgClient.GetAllPurgeable(opt.Before, opt.Since, opt.Expr); err != nil {
+		return nil, err
+	}
+
+	ports := map[string][]*models.NetworkPort{}
+	for _, network := range cand.networks {
+		p, err := pvmclient.NetworkClient.GetAllPort(*network.NetworkID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the ports of the network %s: %v", *network.Name, err)
+		}
+		ports[*network.NetworkID] = p.Ports
+	}
+
+	protected, err := protect(pvmclient, cand)
+	if err != nil {
+		return nil, err
+	}
+	return buildPlan(vms.PvmInstances, ports, cand, protected), nil
+}
+
+// purgeInstance deletes the resources of the plan stage by stage
+func purgeInstance(ctx context.Context, pvmclient *client.PVMClient, p *plan) error {
+	for _, r := range p.skipped {
+		audit.Log(r.Kind, "skip", pvmclient.InstanceName+":"+r.Name)
+	}
+	all := stages(pvmclient, p)
+	// failed holds the resources failed to delete keyed by the ID, resources depending on them are skipped
+	failed := map[string]string{}
+	for i, s := range all {
+		if len(s.resources) == 0 {
+			continue
+		}
+		// resources deleted by the earlier stages must be gone before starting the stage
+		for _, prev := range all[:i] {
+			if err := prev.waitForDeleted(ctx); err != nil {
+				return err
+			}
+			for _, r := range prev.failed {
+				failed[r.ID] = r.Kind + ": " + r.Name
+			}
+		}
+		if err := s.run(ctx, pvmclient.InstanceName, dependents(s.resources, p.deps, failed)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dependents returns the skip reasons keyed by the IDs of the resources depending on the failed ones, such resources
+// are added to the failed as well since the resources depending on them can't be deleted either
+func dependents(resources []Resource, deps map[string][]string, failed map[string]string) map[string]string {
+	skip := map[string]string{}
+	for _, r := range resources {
+		for _, id := range deps[r.ID] {
+			if name, ok := failed[id]; ok {
+				skip[r.ID] = fmt.Sprintf("depends on the %s which failed to delete", name)
+				failed[r.ID] = r.Kind + ": " + r.Name
+				break
+			}
+		}
+	}
+	return skip
+}
+
+// protect returns the reasons keyed by the IDs of the candidates protected from the deletion
+func protect(pvmclient *client.PVMClient, c candidates) (map[string]string, error) {
+	protection, err := purge.NewProtectionWithOptions(pvmclient)
+	if err != nil {
+		return nil, err
+	}
+	protected := map[string]string{}
+	add := func(resourceType, name, id string) error {
+		reason, err := protection.Reason(resourceType, name, id)
+		if err != nil {
+			return err
+		}
+		if reason != "" {
+			protected[id] = reason
+		}
+		return nil
+	}
+	for _, vm := range c.vms {
+		if err := add("pvm-instance", *vm.ServerName, *vm.PvmInstanceID); err != nil {
+			return nil, err
+		}
+	}
+	for _, vol := range c.volumes {
+		if err := add("volume", *vol.Name, *vol.VolumeID); err != nil {
+			return nil, err
+		}
+	}
+	for _, network := range c.networks {
+		if err := add("network", *network.Name, *network.NetworkID); err != nil {
+			return nil, err
+		}
+	}
+	for _, image := range c.images {
+		if err := add("image", *image.Name, *image.ImageID); err != nil {
+			return nil, err
+		}
+	}
+	return protected, nil
+}
+
+// stage is a set of resources of the same kind, deleted together
+type stage struct {
+	kind      string
+	resources []Resource
+	// list returns the IDs of the resources present in the workspace, value tells whether the resource is ready for the deletion
+	list   func() (map[string]bool, error)
+	delete func(r Resource) error
+
+	mutex sync.Mutex
+	// deleted holds the resources deleted by the run, not yet confirmed to be gone
+	deleted []Resource
+	// failed holds the resources failed to delete, or not gone within the --wait-timeout with --ignore-errors
+	failed []Resource
+}
+
+func stages(pvmclient *client.PVMClient, p *plan) []*stage {
+	networkIDs := map[string]bool{}
+	for _, port := range p.ports {
+		networkIDs[port.Network] = true
+	}
+	return []*stage{
+		{
+			kind:      "vms",
+			resources: p.vms,
+			list: func() (map[string]bool, error) {
+				vms, err := pvmclient.InstanceClient.GetAll()
+				if err != nil {
+					return nil, err
+				}
+				ids := map[string]bool{}
+				for _, vm := range vms.PvmInstances {
+					ids[*vm.PvmInstanceID] = true
+				}
+				return ids, nil
+			},
+			delete: func(r Resource) error {
+				return pvmclient.InstanceClient.Delete(r.ID)
+			},
+		},
+		{
+			kind:      "volumes",
+			resources: p.volumes,
+			list: func() (map[string]bool, error) {
+				volumes, err := pvmclient.VolumeClient.GetAll()
+				if err != nil {
+					return nil, err
+				}
+				// volumes get detached from the deleted vms asynchronously
+				ids := map[string]bool{}
+				for _, vol := range volumes.Volumes {
+					ids[*vol.VolumeID] = len(vol.PvmInstanceIds) == 0
+				}
+				return ids, nil
+			},
+			delete: func(r Resource) error {
+				return pvmclient.VolumeClient.DeleteVolume(r.ID)
+			},
+		},
+		{
+			kind:      "ports",
+			resources: p.ports,
+			list: func() (map[string]bool, error) {
+				ids := map[string]bool{}
+				for id := range networkIDs {
+					ports, err := pvmclient.NetworkClient.GetAllPort(id)
+					if err != nil {
+						return nil, err
+					}
+					for _, port := range ports.Ports {
+						ids[*port.PortID] = true
+					}
+				}
+				return ids, nil
+			},
+			delete: func(r Resource) error {
+				_, err := pvmclient.NetworkClient.DeletePort(r.Network, r.ID)
+				return err
+			},
+		},
+		{
+			kind:      "networks",
+			resources: p.networks,
+			list: func() (map[string]bool, error) {
+				networks, err := pvmclient.NetworkClient.GetAll()
+				if err != nil {
+					return nil, err
+				}
+				ids := map[string]bool{}
+				for _, network := range networks.Networks {
+					ids[*network.NetworkID] = true
+				}
+				return ids, nil
+			},
+			delete: func(r Resource) error {
+				return pvmclient.NetworkClient.Delete(r.ID)
+			},
+		},
+		{
+			kind:      "images",
+			resources: p.images,
+			list: func() (map[string]bool, error) {
+				images, err := pvmclient.ImgClient.GetAll()
+				if err != nil {
+					return nil, err
+				}
+				ids := map[string]bool{}
+				for _, image := range images.Images {
+					ids[*image.ImageID] = true
+				}
+				return ids, nil
+			},
+			delete: func(r Resource) error {
+				return pvmclient.ImgClient.Delete(r.ID)
+			},
+		},
+	}
+}
+
+// run waits for the resources to be ready and deletes them with the purge.DeleteAllWithList, resources in the skip
+// are reported with the reason instead
+func (s *stage) run(ctx context.Context, instanceName string, skip map[string]string) error {
+	klog.Infof("Purging the %s", s.kind)
+	var candidates []Resource
+	for _, r := range s.resources {
+		if _, ok := skip[r.ID]; !ok {
+			candidates = append(candidates, r)
+		}
+	}
+	pending, err := s.waitFor(ctx, "to be ready for the deletion", candidates, func(present map[string]bool, r Resource) bool {
+		ready, ok := present[r.ID]
+		return !ok || ready
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.timedOut("to be ready for the deletion", pending); err != nil {
+		return err
+	}
+	for _, r := range pending {
+		skip[r.ID] = fmt.Sprintf("not ready for the deletion within %s", pkg.Options.WaitTimeout)
+	}
+
+	present, err := s.list()
+	if err != nil {
+		return fmt.Errorf("failed to get the list of %s: %v", s.kind, err)
+	}
+	var items []purge.Item
+	for _, r := range s.resources {
+		if _, ok := present[r.ID]; !ok {
+			klog.Infof("The %s, and ID: %s is already deleted", r.Name, r.ID)
+			continue
+		}
+		r := r
+		items = append(items, purge.Item{
+			Name: r.Name,
+			ID:   r.ID,
+			Delete: func() error {
+				err := s.delete(r)
+				s.mutex.Lock()
+				defer s.mutex.Unlock()
+				if err != nil {
+					s.failed = append(s.failed, r)
+					return err
+				}
+				s.deleted = append(s.deleted, r)
+				return nil
+			},
+			SkipReason: skip[r.ID],
+		})
+	}
+	return purge.DeleteAllWithList(ctx, s.kind, instanceName, items, s.list)
+}
+
+// waitForDeleted waits till the resources deleted by the run are gone, resources failed to delete are not waited for
+func (s *stage) waitForDeleted(ctx context.Context) error {
+	if len(s.deleted) == 0 {
+		return nil
+	}
+	pending, err := s.waitFor(ctx, "to be deleted", s.deleted, func(present map[string]bool, r Resource) bool {
+		_, ok := present[r.ID]
+		return !ok
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.timedOut("to be deleted", pending); err != nil {
+		return err
+	}
+	s.deleted = nil
+	return nil
+}
+
+// timedOut returns the error for the resources still pending after the --wait-timeout, with --ignore-errors such
+// resources are added to the failed instead so that the resources depending on them are skipped
+func (s *stage) timedOut(msg string, pending []Resource) error {
+	if len(pending) == 0 {
+		return nil
+	}
+	if !pkg.Options.IgnoreErrors {
+		return fmt.Errorf("timed out while waiting for the %s %s: %s", s.kind, msg, names(pending))
+	}
+	klog.Infof("Timed out while waiting for the %s %s: %s, ignoring", s.kind, msg, names(pending))
+	s.failed = append(s.failed, pending...)
+	return nil
+}
+
+// waitFor polls the list till all the resources are done or the --wait-timeout, returns the resources not done by
+// the timeout
+func (s *stage) waitFor(ctx context.Context, msg string, resources []Resource, done func(present map[string]bool, r Resource) bool) ([]Resource, error) {
+	var pending []Resource
+	timeout, cancel := context.WithTimeout(ctx, pkg.Options.WaitTimeout)
+	defer cancel()
+	err := wait.PollImmediateUntil(pollInterval, func() (bool, error) {
+		present, err := s.list()
+		if err != nil {
+			return false, fmt.Errorf("failed to get the list of %s: %v", s.kind, err)
+		}
+		pending = nil
+		for _, r := range resources {
+			if !done(present, r) {
+				pending = append(pending, r)
+			}
+		}
+		if len(pending) != 0 {
+			klog.Infof("Waiting for the %s %s: %s", s.kind, msg, names(pending))
+			return false, nil
+		}
+		return true, nil
+	}, timeout.Done())
+	if err == wait.ErrWaitTimeout {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("interrupted while waiting for the %s %s: %s", s.kind, msg, names(pending))
+		}
+		return pending, nil
+	}
+	return nil, err
+}
+
+func names(resources []Resource) string {
+	var n []string
+	for _, r := range resources {
+		n = append(n, r.Name)
+	}
+	return strings.Join(n, ", ")
+}
@@ -0,0 +1,185 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package all
+
+import (
+	"fmt"
+
+	"github.com/IBM-Cloud/power-go-client/power/models"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg/purge"
+)
+
+// Resource is an entry in the purge plan
+type Resource struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	ID   string `json:"id"`
+	// Network is the network ID, set only for the ports
+	Network string `json:"network,omitempty"`
+	Action  string `json:"action"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// candidates are the resources selected for the deletion by the user filters
+type candidates struct {
+	vms      []*models.PVMInstanceReference
+	volumes  []*models.VolumeReference
+	networks []*models.NetworkReference
+	images   []*models.ImageReference
+}
+
+// plan holds the resources to be deleted per stage, stages are executed in the order of
+// vms, volumes, ports, networks and images
+type plan struct {
+	vms      []Resource
+	volumes  []Resource
+	ports    []Resource
+	networks []Resource
+	images   []Resource
+	skipped  []Resource
+	// deps holds the IDs of the resources of the earlier stages keyed by the ID of the resource depending on them,
+	// e.g. the vms a volume is attached to
+	deps map[string][]string
+}
+
+// resources returns all the entries of the plan in the deletion order followed by the skipped ones
+func (p *plan) resources() []Resource {
+	var r []Resource
+	for _, stage := range [][]Resource{p.vms, p.volumes, p.ports, p.networks, p.images, p.skipped} {
+		r = append(r, stage...)
+	}
+	return r
+}
+
+func (p *plan) empty() bool {
+	return len(p.vms)+len(p.volumes)+len(p.ports)+len(p.networks)+len(p.images) == 0
+}
+
+// buildPlan builds the dependency graph between the candidates and the rest of the workspace.
+// vms is the complete list of the vms in the workspace and ports are the network ports keyed by
+// the network ID. protected holds the reasons keyed by the IDs of the candidates which must not be
+// deleted. A candidate protected or still in use by a vm which is not going to be deleted is skipped.
+func buildPlan(vms []*models.PVMInstanceReference, ports map[string][]*models.NetworkPort, c candidates, protected map[string]string) *plan {
+	p := &plan{deps: map[string][]string{}}
+
+	deleting := map[string]bool{}
+	for _, vm := range c.vms {
+		r := Resource{Kind: "vm", Name: *vm.ServerName, ID: *vm.PvmInstanceID, Action: purge.ActionDelete}
+		if reason, ok := protected[r.ID]; ok {
+			r.Action, r.Reason = purge.ActionSkip, reason
+			p.skipped = append(p.skipped, r)
+			continue
+		}
+		deleting[r.ID] = true
+		p.vms = append(p.vms, r)
+	}
+
+	// names of the vms which are going to stay, keyed by vm ID and the resources used by them
+	remaining := map[string]string{}
+	networkUsers := map[string]string{}
+	imageUsers := map[string]string{}
+	for _, vm := range vms {
+		if deleting[*vm.PvmInstanceID] {
+			for _, n := range vm.Networks {
+				if n != nil && n.NetworkID != "" {
+					p.deps[n.NetworkID] = append(p.deps[n.NetworkID], *vm.PvmInstanceID)
+				}
+			}
+			if vm.ImageID != nil {
+				p.deps[*vm.ImageID] = append(p.deps[*vm.ImageID], *vm.PvmInstanceID)
+			}
+			continue
+		}
+		remaining[*vm.PvmInstanceID] = *vm.ServerName
+		for _, n := range vm.Networks {
+			if n != nil && n.NetworkID != "" {
+				networkUsers[n.NetworkID] = *vm.ServerName
+			}
+		}
+		if vm.ImageID != nil {
+			imageUsers[*vm.ImageID] = *vm.ServerName
+		}
+	}
+
+	for _, vol := range c.volumes {
+		r := Resource{Kind: "volume", Name: *vol.Name, ID: *vol.VolumeID, Action: purge.ActionDelete}
+		if reason, ok := protected[r.ID]; ok {
+			r.Action, r.Reason = purge.ActionSkip, reason
+		}
+		for _, id := range vol.PvmInstanceIds {
+			if r.Action == purge.ActionSkip {
+				break
+			}
+			if name, ok := remaining[id]; ok {
+				r.Action, r.Reason = purge.ActionSkip, fmt.Sprintf("attached to the vm: %s", name)
+				break
+			}
+		}
+		if r.Action == purge.ActionSkip {
+			p.skipped = append(p.skipped, r)
+		} else {
+			p.deps[r.ID] = vol.PvmInstanceIds
+			p.volumes = append(p.volumes, r)
+		}
+	}
+
+	for _, network := range c.networks {
+		r := Resource{Kind: "network", Name: *network.Name, ID: *network.NetworkID, Action: purge.ActionDelete}
+		if reason, ok := protected[r.ID]; ok {
+			r.Action, r.Reason = purge.ActionSkip, reason
+		} else if name, ok := networkUsers[r.ID]; ok {
+			r.Action, r.Reason = purge.ActionSkip, fmt.Sprintf("in use by the vm: %s", name)
+		}
+		for _, port := range ports[r.ID] {
+			if r.Action == purge.ActionSkip {
+				break
+			}
+			if port.PvmInstance == nil {
+				continue
+			}
+			if name, ok := remaining[port.PvmInstance.PvmInstanceID]; ok {
+				r.Action, r.Reason = purge.ActionSkip, fmt.Sprintf("port: %s in use by the vm: %s", *port.PortID, name)
+			}
+		}
+		if r.Action == purge.ActionSkip {
+			p.skipped = append(p.skipped, r)
+			continue
+		}
+		for _, port := range ports[r.ID] {
+			if port.PvmInstance != nil {
+				p.deps[*port.PortID] = []string{port.PvmInstance.PvmInstanceID}
+			}
+			p.deps[r.ID] = append(p.deps[r.ID], *port.PortID)
+			p.ports = append(p.ports, Resource{Kind: "port", Name: *port.IPAddress, ID: *port.PortID, Network: r.ID, Action: purge.ActionDelete})
+		}
+		p.networks = append(p.networks, r)
+	}
+
+	for _, image := range c.images {
+		r := Resource{Kind: "image", Name: *image.Name, ID: *image.ImageID, Action: purge.ActionDelete}
+		if reason, ok := protected[r.ID]; ok {
+			r.Action, r.Reason = purge.ActionSkip, reason
+		} else if name, ok := imageUsers[r.ID]; ok {
+			r.Action, r.Reason = purge.ActionSkip, fmt.Sprintf("in use by the vm: %s", name)
+		}
+		if r.Action == purge.ActionSkip {
+			p.skipped = append(p.skipped, r)
+			continue
+		}
+		p.images = append(p.images, r)
+	}
+	return p
+}
@@ -0,0 +1,187 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package all
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/IBM-Cloud/power-go-client/power/models"
+)
+
+func vm(id, image string, networks ...string) *models.PVMInstanceReference {
+	v := &models.PVMInstanceReference{PvmInstanceID: &id, ServerName: &id, ImageID: &image}
+	for _, n := range networks {
+		v.Networks = append(v.Networks, &models.PVMInstanceNetwork{NetworkID: n})
+	}
+	return v
+}
+
+func volume(id string, vms ...string) *models.VolumeReference {
+	return &models.VolumeReference{VolumeID: &id, Name: &id, PvmInstanceIds: vms}
+}
+
+func network(id string) *models.NetworkReference {
+	return &models.NetworkReference{NetworkID: &id, Name: &id}
+}
+
+func port(id, vm string) *models.NetworkPort {
+	p := &models.NetworkPort{PortID: &id, IPAddress: &id}
+	if vm != "" {
+		p.PvmInstance = &models.NetworkPortPvmInstance{PvmInstanceID: vm}
+	}
+	return p
+}
+
+func image(id string) *models.ImageReference {
+	return &models.ImageReference{ImageID: &id, Name: &id}
+}
+
+func ids(resources []Resource) []string {
+	var r []string
+	for _, res := range resources {
+		r = append(r, res.ID)
+	}
+	return r
+}
+
+func Test_buildPlan(t *testing.T) {
+	vms := []*models.PVMInstanceReference{
+		vm("vm-1", "img-1", "net-1"),
+		vm("vm-2", "img-2", "net-2"),
+		vm("keep", "img-3", "net-3"),
+	}
+	tests := []struct {
+		name         string
+		ports        map[string][]*models.NetworkPort
+		c            candidates
+		protected    map[string]string
+		wantVMs      []string
+		wantVolumes  []string
+		wantPorts    []string
+		wantNetworks []string
+		wantImages   []string
+		wantSkipped  []string
+	}{
+		{
+			name: "whole workspace",
+			ports: map[string][]*models.NetworkPort{
+				"net-1": {port("port-1", "vm-1"), port("port-2", "")},
+			},
+			c: candidates{
+				vms:      vms,
+				volumes:  []*models.VolumeReference{volume("vol-1", "vm-1"), volume("vol-2")},
+				networks: []*models.NetworkReference{network("net-1"), network("net-3")},
+				images:   []*models.ImageReference{image("img-1"), image("img-3")},
+			},
+			wantVMs:      []string{"vm-1", "vm-2", "keep"},
+			wantVolumes:  []string{"vol-1", "vol-2"},
+			wantPorts:    []string{"port-1", "port-2"},
+			wantNetworks: []string{"net-1", "net-3"},
+			wantImages:   []string{"img-1", "img-3"},
+		},
+		{
+			name: "resources in use by the remaining vms are skipped",
+			ports: map[string][]*models.NetworkPort{
+				"net-1": {port("port-1", "vm-1")},
+				"net-4": {port("port-4", "keep")},
+			},
+			c: candidates{
+				vms:      vms[:2],
+				volumes:  []*models.VolumeReference{volume("vol-1", "vm-1"), volume("vol-3", "keep")},
+				networks: []*models.NetworkReference{network("net-1"), network("net-3"), network("net-4")},
+				images:   []*models.ImageReference{image("img-1"), image("img-3")},
+			},
+			wantVMs:      []string{"vm-1", "vm-2"},
+			wantVolumes:  []string{"vol-1"},
+			wantPorts:    []string{"port-1"},
+			wantNetworks: []string{"net-1"},
+			wantImages:   []string{"img-1"},
+			wantSkipped:  []string{"vol-3", "net-3", "net-4", "img-3"},
+		},
+		{
+			name: "protected resources and the resources in use by them are skipped",
+			ports: map[string][]*models.NetworkPort{
+				"net-1": {port("port-1", "vm-1")},
+				"net-2": {port("port-2", "vm-2")},
+			},
+			c: candidates{
+				vms:      vms[:2],
+				volumes:  []*models.VolumeReference{volume("vol-1", "vm-1"), volume("vol-2", "vm-2"), volume("vol-3")},
+				networks: []*models.NetworkReference{network("net-1"), network("net-2")},
+				images:   []*models.ImageReference{image("img-1"), image("img-2")},
+			},
+			protected: map[string]string{
+				"vm-2":  "tagged with do-not-delete",
+				"vol-3": "listed in the exclude IDs file",
+				"img-1": "matches the exclude regular expression",
+			},
+			wantVMs:      []string{"vm-1"},
+			wantVolumes:  []string{"vol-1"},
+			wantPorts:    []string{"port-1"},
+			wantNetworks: []string{"net-1"},
+			wantSkipped:  []string{"vm-2", "vol-2", "vol-3", "net-2", "img-1", "img-2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := buildPlan(vms, tt.ports, tt.c, tt.protected)
+			for _, got := range []struct {
+				stage string
+				got   []string
+				want  []string
+			}{
+				{"vms", ids(p.vms), tt.wantVMs},
+				{"volumes", ids(p.volumes), tt.wantVolumes},
+				{"ports", ids(p.ports), tt.wantPorts},
+				{"networks", ids(p.networks), tt.wantNetworks},
+				{"images", ids(p.images), tt.wantImages},
+				{"skipped", ids(p.skipped), tt.wantSkipped},
+			} {
+				if !reflect.DeepEqual(got.got, got.want) {
+					t.Errorf("buildPlan() %s = %v, want %v", got.stage, got.got, got.want)
+				}
+			}
+		})
+	}
+}
+
+func Test_dependents(t *testing.T) {
+	vms := []*models.PVMInstanceReference{vm("vm-1", "img-1", "net-1"), vm("vm-2", "img-2", "net-2")}
+	ports := map[string][]*models.NetworkPort{
+		"net-1": {port("port-1", "vm-1")},
+		"net-2": {port("port-2", "vm-2")},
+	}
+	c := candidates{
+		vms:      vms,
+		volumes:  []*models.VolumeReference{volume("vol-1", "vm-1"), volume("vol-2", "vm-2")},
+		networks: []*models.NetworkReference{network("net-1"), network("net-2")},
+		images:   []*models.ImageReference{image("img-1"), image("img-2")},
+	}
+	p := buildPlan(vms, ports, c, nil)
+
+	// vm-1 failed to delete, the resources depending on it directly or through the ports are skipped
+	failed := map[string]string{"vm-1": "vm: vm-1"}
+	var skipped []string
+	for _, stage := range [][]Resource{p.volumes, p.ports, p.networks, p.images} {
+		for id := range dependents(stage, p.deps, failed) {
+			skipped = append(skipped, id)
+		}
+	}
+	want := []string{"vol-1", "port-1", "net-1", "img-1"}
+	if !reflect.DeepEqual(skipped, want) {
+		t.Errorf("dependents() = %v, want %v", skipped, want)
+	}
+}
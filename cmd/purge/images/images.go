@@ -16,9 +16,10 @@ package images
 
 import (
 	"fmt"
+	"github.com/IBM-Cloud/power-go-client/power/models"
 	"github.com/ppc64le-cloud/pvsadm/pkg"
-	"github.com/ppc64le-cloud/pvsadm/pkg/audit"
 	"github.com/ppc64le-cloud/pvsadm/pkg/client"
+	"github.com/ppc64le-cloud/pvsadm/pkg/purge"
 	"github.com/ppc64le-cloud/pvsadm/pkg/utils"
 	"github.com/spf13/cobra"
 	"k8s.io/klog/v2"
@@ -26,6 +27,14 @@ import (
 
 const deletePromptMessage = "Deleting all the above images, images can't be claimed back once deleted. Do you really want to continue?"
 
+// candidate is the image along with the purge action
+type candidate struct {
+	client.Workspace
+	*models.ImageReference
+	Action string `json:"action"`
+	Reason string `json:"reason,omitempty"`
+}
+
 var Cmd = &cobra.Command{
 	Use:   "images",
 	Short: "Purge the powervs images",
@@ -36,38 +45,66 @@ pvsadm purge --help for information
 		klog.Infof("Purge the images for the instance: %v", pkg.Options.InstanceID)
 		opt := pkg.Options
 
-		c, err := client.NewClientWithEnv(opt.APIKey, opt.Environment, opt.Debug)
+		c, err := client.NewClientWithEnv(cmd.Context(), opt.APIKey, opt.Environment, opt.Debug)
 
 		if err != nil {
 			return err
 		}
 
-		pvmclient, err := client.NewPVMClientWithEnv(c, opt.InstanceID, opt.InstanceName, pkg.Options.Environment)
+		pvmclients, err := client.NewPVMClientsWithEnv(c, opt.Environment)
 		if err != nil {
 			return err
 		}
 
-		images, err := pvmclient.ImgClient.GetAllPurgeable(opt.Before, opt.Since, opt.Expr)
+		rows := make([][]candidate, len(pvmclients))
+		items := make([][]purge.Item, len(pvmclients))
+		err = client.ForEach(pvmclients, func(i int, pvmclient *client.PVMClient) error {
+			images, err := pvmclient.ImgClient.GetAllPurgeable(opt.Before, opt.Since, opt.Expr)
+			if err != nil {
+				return fmt.Errorf("failed to get the list of images for the instance %s: %v", pvmclient.InstanceName, err)
+			}
+			protection, err := purge.NewProtectionWithOptions(pvmclient)
+			if err != nil {
+				return err
+			}
+
+			for _, image := range images {
+				id := *image.ImageID
+				reason, err := protection.Reason("image", *image.Name, id)
+				if err != nil {
+					return err
+				}
+				rows[i] = append(rows[i], candidate{Workspace: pvmclient.Workspace(), ImageReference: image, Action: purge.Action(reason), Reason: reason})
+				items[i] = append(items[i], purge.Item{
+					Name: *image.Name,
+					ID:   id,
+					Delete: func() error {
+						return pvmclient.ImgClient.Delete(id)
+					},
+					Get: func() error {
+						_, err := pvmclient.ImgClient.Get(id)
+						return err
+					},
+					SkipReason: reason,
+				})
+			}
+			return nil
+		})
 		if err != nil {
-			return fmt.Errorf("failed to get the list of images: %v", err)
+			return err
 		}
-		table := utils.NewTable()
 
-		table.Render(images, []string{"href", "specifications"})
-		if !opt.DryRun && len(images) != 0 {
+		var all []candidate
+		for _, r := range rows {
+			all = append(all, r...)
+		}
+		table := utils.NewTable()
+		if err := table.Render(all, client.ExcludeWorkspace(pvmclients, []string{"href", "specifications"})); err != nil {
+			return err
+		}
+		if !opt.DryRun && purge.Deletable(items...) != 0 {
 			if opt.NoPrompt || utils.AskYesOrNo(deletePromptMessage) {
-				for _, image := range images {
-					klog.Infof("Deleting the %s, and ID: %s", *image.Name, *image.ImageID)
-					err = pvmclient.ImgClient.Delete(*image.ImageID)
-					if err != nil {
-						if opt.IgnoreErrors {
-							klog.Infof("error occurred while deleting the image: %v", err)
-						} else {
-							return err
-						}
-					}
-					audit.Log("images", "delete", pvmclient.InstanceName+":"+*image.Name)
-				}
+				return purge.DeleteAllInstances(cmd.Context(), "images", pvmclients, items)
 			}
 		}
 		return nil
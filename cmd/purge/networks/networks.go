@@ -16,9 +16,10 @@ package networks
 
 import (
 	"fmt"
+	"github.com/IBM-Cloud/power-go-client/power/models"
 	"github.com/ppc64le-cloud/pvsadm/pkg"
-	"github.com/ppc64le-cloud/pvsadm/pkg/audit"
 	"github.com/ppc64le-cloud/pvsadm/pkg/client"
+	"github.com/ppc64le-cloud/pvsadm/pkg/purge"
 	"github.com/ppc64le-cloud/pvsadm/pkg/utils"
 	"github.com/spf13/cobra"
 	"k8s.io/klog/v2"
@@ -26,6 +27,14 @@ import (
 
 const deletePromptMessage = "Deleting all the above networks, networks can't be claimed back once deleted. Do you really want to continue?"
 
+// candidate is the network along with the purge action
+type candidate struct {
+	client.Workspace
+	*models.NetworkReference
+	Action string `json:"action"`
+	Reason string `json:"reason,omitempty"`
+}
+
 var Cmd = &cobra.Command{
 	Use:   "networks",
 	Short: "Purge the powervs networks",
@@ -35,38 +44,66 @@ pvsadm purge --help for information
 	RunE: func(cmd *cobra.Command, args []string) error {
 		opt := pkg.Options
 
-		c, err := client.NewClientWithEnv(opt.APIKey, opt.Environment, opt.Debug)
+		c, err := client.NewClientWithEnv(cmd.Context(), opt.APIKey, opt.Environment, opt.Debug)
 		if err != nil {
 			return err
 		}
 
-		pvmclient, err := client.NewPVMClientWithEnv(c, opt.InstanceID, opt.InstanceName, pkg.Options.Environment)
+		pvmclients, err := client.NewPVMClientsWithEnv(c, opt.Environment)
 		if err != nil {
 			return err
 		}
-		klog.Infof("Purging the networks for the instance: %v", pvmclient.InstanceID)
 
-		networks, err := pvmclient.NetworkClient.GetAllPurgeable(opt.Before, opt.Since, opt.Expr)
+		rows := make([][]candidate, len(pvmclients))
+		items := make([][]purge.Item, len(pvmclients))
+		err = client.ForEach(pvmclients, func(i int, pvmclient *client.PVMClient) error {
+			klog.Infof("Purging the networks for the instance: %v", pvmclient.InstanceID)
+			networks, err := pvmclient.NetworkClient.GetAllPurgeable(opt.Before, opt.Since, opt.Expr)
+			if err != nil {
+				return fmt.Errorf("failed to get the list of networks for the instance %s: %v", pvmclient.InstanceName, err)
+			}
+			protection, err := purge.NewProtectionWithOptions(pvmclient)
+			if err != nil {
+				return err
+			}
+
+			for _, network := range networks {
+				id := *network.NetworkID
+				reason, err := protection.Reason("network", *network.Name, id)
+				if err != nil {
+					return err
+				}
+				rows[i] = append(rows[i], candidate{Workspace: pvmclient.Workspace(), NetworkReference: network, Action: purge.Action(reason), Reason: reason})
+				items[i] = append(items[i], purge.Item{
+					Name: *network.Name,
+					ID:   id,
+					Delete: func() error {
+						return pvmclient.NetworkClient.Delete(id)
+					},
+					Get: func() error {
+						_, err := pvmclient.NetworkClient.Get(id)
+						return err
+					},
+					SkipReason: reason,
+				})
+			}
+			return nil
+		})
 		if err != nil {
-			return fmt.Errorf("failed to get the list of networks: %v", err)
+			return err
 		}
-		table := utils.NewTable()
 
-		table.Render(networks, []string{"href"})
-		if !opt.DryRun && len(networks) != 0 {
+		var all []candidate
+		for _, r := range rows {
+			all = append(all, r...)
+		}
+		table := utils.NewTable()
+		if err := table.Render(all, client.ExcludeWorkspace(pvmclients, []string{"href"})); err != nil {
+			return err
+		}
+		if !opt.DryRun && purge.Deletable(items...) != 0 {
 			if opt.NoPrompt || utils.AskYesOrNo(deletePromptMessage) {
-				for _, network := range networks {
-					klog.Infof("Deleting the %s, and ID: %s", *network.Name, *network.NetworkID)
-					err = pvmclient.NetworkClient.Delete(*network.NetworkID)
-					if err != nil {
-						if opt.IgnoreErrors {
-							klog.Infof("error occurred while deleting the network: %v", err)
-						} else {
-							return err
-						}
-					}
-					audit.Log("networks", "delete", pvmclient.InstanceName+":"+*network.Name)
-				}
+				return purge.DeleteAllInstances(cmd.Context(), "networks", pvmclients, items)
 			}
 		}
 		return nil
@@ -0,0 +1,135 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package placementgroups
+
+import (
+	"fmt"
+
+	"github.com/IBM-Cloud/power-go-client/power/models"
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg"
+	"github.com/ppc64le-cloud/pvsadm/pkg/client"
+	"github.com/ppc64le-cloud/pvsadm/pkg/purge"
+	"github.com/ppc64le-cloud/pvsadm/pkg/utils"
+)
+
+const deletePromptMessage = "Deleting all the above placement groups, placement groups can't be claimed back once deleted. Do you really want to continue?"
+
+// candidate is the placement group along with the purge action
+type candidate struct {
+	client.Workspace
+	*models.PlacementGroup
+	Action string `json:"action"`
+	Reason string `json:"reason,omitempty"`
+}
+
+var Cmd = &cobra.Command{
+	Use:   "placementgroups",
+	Short: "Purge the powervs placement groups",
+	Long: `Purge the powervs placement groups
+
+Placement groups with members are skipped, placement groups don't carry any date hence --before and --since
+aren't supported.
+
+Examples:
+  # Delete all the placement groups starts with ci-
+  pvsadm purge placementgroups --instance-name upstream-core --regexp "^ci-.*"
+
+pvsadm purge --help for information
+`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if pkg.Options.Since != 0 || pkg.Options.Before != 0 {
+			return fmt.Errorf("--since and --before options are not supported for the placement groups")
+		}
+		if pkg.Options.Selector != "" {
+			return fmt.Errorf("--selector option is not supported for the placement groups")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opt := pkg.Options
+
+		c, err := client.NewClientWithEnv(cmd.Context(), opt.APIKey, opt.Environment, opt.Debug)
+		if err != nil {
+			return err
+		}
+
+		pvmclients, err := client.NewPVMClientsWithEnv(c, opt.Environment)
+		if err != nil {
+			return err
+		}
+
+		rows := make([][]candidate, len(pvmclients))
+		items := make([][]purge.Item, len(pvmclients))
+		err = client.ForEach(pvmclients, func(i int, pvmclient *client.PVMClient) error {
+			klog.Infof("Purging the placement groups for the instance: %v", pvmclient.InstanceID)
+
+			candidates, err := pvmclient.PlacementGroupClient.GetAllPurgeable(opt.ExprRegexp)
+			if err != nil {
+				return err
+			}
+			// placement groups can't be tagged, hence protected only by the exclude options
+			protection, err := purge.NewProtection(opt.ExcludeExpr, opt.ExcludeIDsFile, nil)
+			if err != nil {
+				return err
+			}
+
+			for _, item := range candidates {
+				id := *item.ID
+				reason, err := protection.Reason("placement group", *item.Name, id)
+				if err != nil {
+					return err
+				}
+				if reason == "" && len(item.Members) != 0 {
+					reason = fmt.Sprintf("has %d members", len(item.Members))
+				}
+				rows[i] = append(rows[i], candidate{Workspace: pvmclient.Workspace(), PlacementGroup: item, Action: purge.Action(reason), Reason: reason})
+				items[i] = append(items[i], purge.Item{
+					Name: *item.Name,
+					ID:   id,
+					Delete: func() error {
+						return pvmclient.PlacementGroupClient.Delete(id)
+					},
+					Get: func() error {
+						_, err := pvmclient.PlacementGroupClient.Get(id)
+						return err
+					},
+					SkipReason: reason,
+				})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		var all []candidate
+		for _, r := range rows {
+			all = append(all, r...)
+		}
+		table := utils.NewTable()
+		if err := table.Render(all, client.ExcludeWorkspace(pvmclients, nil)); err != nil {
+			return err
+		}
+		if !opt.DryRun && purge.Deletable(items...) != 0 {
+			if opt.NoPrompt || utils.AskYesOrNo(deletePromptMessage) {
+				return purge.DeleteAllInstances(cmd.Context(), "placementgroups", pvmclients, items)
+			}
+		}
+		return nil
+	},
+}
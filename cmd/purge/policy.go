@@ -0,0 +1,119 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package purge
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v2"
+	"k8s.io/klog/v2"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg"
+	"github.com/ppc64le-cloud/pvsadm/pkg/client"
+	"github.com/ppc64le-cloud/pvsadm/pkg/purge"
+	"github.com/ppc64le-cloud/pvsadm/pkg/utils"
+)
+
+const policyDeletePromptMessage = "Deleting all the above resources, resources can't be claimed back once deleted. Do you really want to continue?"
+
+// readPolicy reads and validates the purge policy file
+func readPolicy(file string) (*pkg.Policy, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the policy file: %v", err)
+	}
+	policy := &pkg.Policy{}
+	if err := yaml.UnmarshalStrict(content, policy); err != nil {
+		return nil, fmt.Errorf("failed to parse the policy file: %v", err)
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid policy: %v", err)
+	}
+	return policy, nil
+}
+
+// purgeWithPolicy evaluates the policy for all the instances, reports the combined plan and deletes the candidates
+// after the confirmation
+func purgeWithPolicy(ctx context.Context, file string) error {
+	opt := pkg.Options
+
+	policy, err := readPolicy(file)
+	if err != nil {
+		return err
+	}
+
+	c, err := client.NewClientWithEnv(ctx, opt.APIKey, opt.Environment, opt.Debug)
+	if err != nil {
+		return err
+	}
+
+	render := func(plan []*pkg.PurgeCandidate) error {
+		return utils.NewTable().Render(plan, []string{"instanceid"})
+	}
+	result, err := purge.Run(ctx, c, purge.PurgeRequest{
+		Policy:         policy,
+		ExcludeExpr:    opt.ExcludeExpr,
+		ExcludeIDsFile: opt.ExcludeIDsFile,
+		DryRun:         opt.DryRun,
+		IgnoreErrors:   opt.IgnoreErrors,
+		Parallel:       opt.Parallel,
+		Wait:           opt.Wait,
+		WaitTimeout:    opt.WaitTimeout,
+		Confirm: func(plan []*pkg.PurgeCandidate) bool {
+			if err := render(plan); err != nil {
+				klog.Error(err)
+				return false
+			}
+			return purge.DeletableCandidates(plan) != 0 && (opt.NoPrompt || utils.AskYesOrNo(policyDeletePromptMessage))
+		},
+	})
+	if result != nil && opt.DryRun {
+		if err := render(result.Plan); err != nil {
+			return err
+		}
+	}
+	if result != nil && len(result.Results) != 0 {
+		if err := renderSummary(result); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// summary is the outcome of the deletion of a candidate in the policy summary
+type summary struct {
+	Instance string `json:"instance"`
+	Kind     string `json:"kind"`
+	purge.Result
+}
+
+// renderSummary prints the outcome of every candidate in the plan, same as the purge commands without the policy
+func renderSummary(result *purge.PurgeResult) error {
+	var rows []summary
+	for i, r := range result.Results {
+		rows = append(rows, summary{Instance: result.Plan[i].Instance, Kind: result.Plan[i].Kind, Result: r})
+	}
+	t := utils.NewTable()
+	if t.Structured() {
+		for _, r := range rows {
+			klog.Infof("%s: %s %s: %s, ID: %s %s%s", r.Status, r.Instance, r.Kind, r.Name, r.ID, r.Error, r.Reason)
+		}
+		return nil
+	}
+	fmt.Println("Summary:")
+	return t.Render(rows, nil)
+}
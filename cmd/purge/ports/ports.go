@@ -0,0 +1,153 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ports
+
+import (
+	"fmt"
+
+	"github.com/IBM-Cloud/power-go-client/power/models"
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg"
+	"github.com/ppc64le-cloud/pvsadm/pkg/client"
+	"github.com/ppc64le-cloud/pvsadm/pkg/purge"
+	"github.com/ppc64le-cloud/pvsadm/pkg/utils"
+)
+
+const deletePromptMessage = "Deleting all the above ports, ports can't be claimed back once deleted. Do you really want to continue?"
+
+var (
+	network     string
+	description string
+)
+
+// candidate is the network port along with the purge action
+type candidate struct {
+	client.Workspace
+	*models.NetworkPort
+	Action string `json:"action"`
+	Reason string `json:"reason,omitempty"`
+}
+
+var Cmd = &cobra.Command{
+	Use:   "ports",
+	Short: "Purge the powervs network ports",
+	Long: `Purge the powervs network ports which aren't attached to any vm
+
+The --regexp is matched against the IP address of the ports and --description against the description. Ports don't
+carry any date hence --before and --since aren't supported, and they can't be tagged hence --selector isn't supported.
+The network is looked up by ID or name in every selected instance, i.e. use the name along with the multiple instances.
+
+Examples:
+  # Delete all the ports of the network ocp-net left behind by the failed installs
+  pvsadm purge ports --instance-name upstream-core --network ocp-net
+
+  # Delete the ports in the 192.168.25.0/24 subnet with the description bootstrap
+  pvsadm purge ports --instance-name upstream-core --network ocp-net --regexp "^192\.168\.25\." --description "^bootstrap$"
+
+pvsadm purge --help for information
+`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if pkg.Options.Since != 0 || pkg.Options.Before != 0 {
+			return fmt.Errorf("--since and --before options are not supported for the ports")
+		}
+		if pkg.Options.Selector != "" {
+			return fmt.Errorf("--selector option is not supported for the ports")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opt := pkg.Options
+
+		c, err := client.NewClientWithEnv(cmd.Context(), opt.APIKey, opt.Environment, opt.Debug)
+		if err != nil {
+			return err
+		}
+
+		pvmclients, err := client.NewPVMClientsWithEnv(c, opt.Environment)
+		if err != nil {
+			return err
+		}
+
+		rows := make([][]candidate, len(pvmclients))
+		items := make([][]purge.Item, len(pvmclients))
+		err = client.ForEach(pvmclients, func(i int, pvmclient *client.PVMClient) error {
+			n, err := pvmclient.NetworkClient.GetByNameOrID(network)
+			if err != nil {
+				return fmt.Errorf("failed to get the network of the instance %s: %v", pvmclient.InstanceName, err)
+			}
+			networkID := *n.NetworkID
+			klog.Infof("Purging the ports of the network: %s for the instance: %v", *n.Name, pvmclient.InstanceID)
+
+			ports, err := pvmclient.NetworkClient.GetAllPurgeablePorts(networkID, opt.Expr, description)
+			if err != nil {
+				return err
+			}
+			// ports can't be tagged, hence protected only by the exclude options
+			protection, err := purge.NewProtection(opt.ExcludeExpr, opt.ExcludeIDsFile, nil)
+			if err != nil {
+				return err
+			}
+
+			for _, port := range ports {
+				id := *port.PortID
+				reason, err := protection.Reason("port", *port.IPAddress, id)
+				if err != nil {
+					return err
+				}
+				rows[i] = append(rows[i], candidate{Workspace: pvmclient.Workspace(), NetworkPort: port, Action: purge.Action(reason), Reason: reason})
+				items[i] = append(items[i], purge.Item{
+					Name: *port.IPAddress,
+					ID:   id,
+					Delete: func() error {
+						_, err := pvmclient.NetworkClient.DeletePort(networkID, id)
+						return err
+					},
+					Get: func() error {
+						_, err := pvmclient.NetworkClient.GetPort(networkID, id)
+						return err
+					},
+					SkipReason: reason,
+				})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		var all []candidate
+		for _, r := range rows {
+			all = append(all, r...)
+		}
+		table := utils.NewTable()
+		if err := table.Render(all, client.ExcludeWorkspace(pvmclients, []string{"href", "pvminstance"})); err != nil {
+			return err
+		}
+		if !opt.DryRun && purge.Deletable(items...) != 0 {
+			if opt.NoPrompt || utils.AskYesOrNo(deletePromptMessage) {
+				return purge.DeleteAllInstances(cmd.Context(), "ports", pvmclients, items)
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	Cmd.Flags().StringVar(&network, "network", "", "Network ID or Name(preference will be given to the ID over Name)")
+	Cmd.Flags().StringVar(&description, "description", "", "Regular Expressions for filtering the selection by the description of the ports")
+	_ = Cmd.MarkFlagRequired("network")
+}
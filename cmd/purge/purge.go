@@ -16,15 +16,27 @@ package purge
 
 import (
 	"fmt"
+	"github.com/ppc64le-cloud/pvsadm/cmd/purge/all"
+	"github.com/ppc64le-cloud/pvsadm/cmd/purge/cloudconnections"
 	"github.com/ppc64le-cloud/pvsadm/cmd/purge/images"
 	"github.com/ppc64le-cloud/pvsadm/cmd/purge/networks"
+	"github.com/ppc64le-cloud/pvsadm/cmd/purge/placementgroups"
+	"github.com/ppc64le-cloud/pvsadm/cmd/purge/ports"
+	"github.com/ppc64le-cloud/pvsadm/cmd/purge/sharedprocessorpools"
+	"github.com/ppc64le-cloud/pvsadm/cmd/purge/snapshots"
+	"github.com/ppc64le-cloud/pvsadm/cmd/purge/sshkeys"
 	"github.com/ppc64le-cloud/pvsadm/cmd/purge/vms"
 	"github.com/ppc64le-cloud/pvsadm/cmd/purge/volumes"
 	"github.com/ppc64le-cloud/pvsadm/pkg"
+	"github.com/ppc64le-cloud/pvsadm/pkg/purge"
+	"github.com/ppc64le-cloud/pvsadm/pkg/utils"
 	"github.com/spf13/cobra"
+	"strings"
 	"time"
 )
 
+var policyFile string
+
 var Cmd = &cobra.Command{
 	Use:   "purge",
 	Short: "Purge the powervs resources",
@@ -46,6 +58,15 @@ Examples:
   # Delete all the networks and ignore if any errors during the delete operation
   pvsadm purge networks --instance-name upstream-core --ignore-errors
 
+  # Delete all the ports of the network which aren't attached to any virtual machines
+  pvsadm purge ports --instance-name upstream-core --network ocp-net
+
+  # Delete all the vm snapshots created before 72hrs
+  pvsadm purge snapshots --instance-name upstream-core --before 72h
+
+  # Delete all the SSH keys starts with ci-
+  pvsadm purge sshkeys --instance-name upstream-core --regexp "^ci-.*"
+
   # Delete all the images without asking any confirmation
   pvsadm purge images --instance-name upstream-core --no-prompt
 
@@ -55,8 +76,54 @@ Examples:
   # Delete all the virtual machines starts with k8s-cluster-
   pvsadm purge vms --instance-name upstream-core --regexp "^k8s-cluster-.*"
 
+  # Delete the virtual machines starts with k8s-cluster- across all the PowerVS instances starts with ci-
+  pvsadm purge vms --instance-regexp "^ci-.*" --regexp "^k8s-cluster-.*"
+
+  # Delete the images created before 72hrs in all the PowerVS instances of the account
+  pvsadm purge images --all-instances --before 72h
+
+  # Delete all the virtual machines tagged with ci-job:1234 and not tagged with owner:alice
+  pvsadm purge vms --instance-name upstream-core --selector "ci-job=1234,owner!=alice"
+
+  # Delete all the virtual machines except the ones starts with bastion- and the IDs listed in keep.txt,
+  # resources tagged with do-not-delete are always skipped
+  pvsadm purge vms --instance-name upstream-core --exclude-regexp "^bastion-.*" --exclude-ids-file keep.txt
+
   # List the purgeable candidate virtual machines and exit without deleting
   pvsadm purge vms --instance-name upstream-core --dry-run
+
+  # List the purgeable candidate images in json format and exit without deleting
+  pvsadm purge images --instance-name upstream-core --dry-run -o json
+
+  # Delete the volumes not updated in the last 24hrs, 10 at a time
+  pvsadm purge volumes --instance-name upstream-core --before 24h --parallel 10
+
+  # Delete the virtual machines and wait till all of them are gone
+  pvsadm purge vms --instance-name upstream-core --wait --wait-timeout 1h
+
+  # Delete all the resources of the instance in the dependency order(vms, volumes, ports, networks and images)
+  pvsadm purge all --instance-name upstream-core
+
+  # Delete the resources across the PowerVS instances as per the policy file
+  pvsadm purge --policy policy.yaml
+
+Sample policy.yaml file:
+---
+instances:
+- name: upstream-core
+  vms:
+    include: ["^k8s-cluster-.*"]
+    exclude: ["bastion"]
+    before: 4h
+  volumes:
+    before: 24h
+    protectedIDs: ["0a4e44b1-5bfa-4e0c-9d2b-1b2a9f0c1a2b"]
+- id: 7845d372-d4e1-46b8-91fc-41051c984601
+  images:
+    include: ["^rhcos-.*"]
+    keepNewest: 2
+  networks:
+    exclude: ["^mgmt-.*"]
 `,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 		// Code block to execute the strict check mentioned in the rootcmd for the environment.
@@ -69,27 +136,84 @@ Examples:
 			return err
 		}
 
+		if _, err := utils.ParseOutput(pkg.Options.Output); err != nil {
+			return err
+		}
+		if _, err := pkg.ParseSelector(pkg.Options.Selector); err != nil {
+			return err
+		}
+		var err error
+		if pkg.Options.ExprRegexp, err = pkg.ParseExpr(pkg.Options.Expr); err != nil {
+			return err
+		}
+		if _, err := purge.NewProtection(pkg.Options.ExcludeExpr, pkg.Options.ExcludeIDsFile, nil); err != nil {
+			return err
+		}
+		// purge command with the policy file is validated in the PreRunE
+		if cmd.HasSubCommands() {
+			return nil
+		}
+
 		if pkg.Options.Since != 0 && pkg.Options.Before != 0 {
 			return fmt.Errorf("--since and --before options can not be set at a time")
 		}
-		if pkg.Options.InstanceID == "" && pkg.Options.InstanceName == "" {
-			return fmt.Errorf("--instance-name or --instance-name required")
+		if pkg.Options.Parallel < 1 {
+			return fmt.Errorf("--parallel must be at least 1")
+		}
+		if !pkg.Options.InstanceSelected() {
+			return fmt.Errorf("--instance-id, --instance-name, --instance-regexp or --all-instances required")
+		}
+		// instances selected by the pattern are listed again, a stale cache may select the wrong instances to delete
+		if (pkg.Options.InstanceRegexp != "" || pkg.Options.AllInstances) && !pkg.Options.DryRun {
+			pkg.Options.Refresh = true
+		}
+		return nil
+	},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if policyFile == "" {
+			return nil
+		}
+		if pkg.Options.InstanceSelected() || pkg.Options.Since != 0 || pkg.Options.Before != 0 || pkg.Options.Expr != "" || pkg.Options.Selector != "" {
+			return fmt.Errorf("--policy can't be used along with --instance-id, --instance-name, --instance-regexp, --all-instances, --since, --before, --regexp and --selector options, set them in the policy file instead")
 		}
 		return nil
 	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if policyFile == "" {
+			return cmd.Help()
+		}
+		return purgeWithPolicy(cmd.Context(), policyFile)
+	},
 }
 
 func init() {
+	Cmd.AddCommand(all.Cmd)
 	Cmd.AddCommand(images.Cmd)
 	Cmd.AddCommand(vms.Cmd)
 	Cmd.AddCommand(networks.Cmd)
+	Cmd.AddCommand(ports.Cmd)
+	Cmd.AddCommand(snapshots.Cmd)
+	Cmd.AddCommand(sshkeys.Cmd)
+	Cmd.AddCommand(cloudconnections.Cmd)
+	Cmd.AddCommand(placementgroups.Cmd)
+	Cmd.AddCommand(sharedprocessorpools.Cmd)
 	Cmd.AddCommand(volumes.Cmd)
-	Cmd.PersistentFlags().StringVarP(&pkg.Options.InstanceID, "instance-id", "i", "", "Instance ID of the PowerVS instance")
-	Cmd.PersistentFlags().StringVarP(&pkg.Options.InstanceName, "instance-name", "n", "", "Instance name of the PowerVS")
+	Cmd.PersistentFlags().StringVarP(&pkg.Options.InstanceID, "instance-id", "i", "", "Instance ID of the PowerVS instance, comma separated for multiple instances")
+	Cmd.PersistentFlags().StringVarP(&pkg.Options.InstanceName, "instance-name", "n", "", "Instance name of the PowerVS, comma separated for multiple instances")
+	Cmd.PersistentFlags().StringVar(&pkg.Options.InstanceRegexp, "instance-regexp", "", "Regular Expressions for selecting the PowerVS instances by name, not supported by sshkeys as they belong to the account")
+	Cmd.PersistentFlags().BoolVar(&pkg.Options.AllInstances, "all-instances", false, "Select all the PowerVS instances in the account, not supported by sshkeys as they belong to the account")
 	Cmd.PersistentFlags().BoolVar(&pkg.Options.DryRun, "dry-run", false, "dry run the action and don't delete the actual resources")
 	Cmd.PersistentFlags().DurationVar(&pkg.Options.Since, "since", 0*time.Second, "Remove resources since mentioned duration(format: 99h99m00s), mutually exclusive with --before")
 	Cmd.PersistentFlags().DurationVar(&pkg.Options.Before, "before", 0*time.Second, "Remove resources before mentioned duration(format: 99h99m00s), mutually exclusive with --since")
 	Cmd.PersistentFlags().BoolVar(&pkg.Options.NoPrompt, "no-prompt", false, "Show prompt before doing any destructive operations")
 	Cmd.PersistentFlags().BoolVar(&pkg.Options.IgnoreErrors, "ignore-errors", false, "Ignore any errors during the operations")
 	Cmd.PersistentFlags().StringVar(&pkg.Options.Expr, "regexp", "", "Regular Expressions for filtering the selection")
+	Cmd.PersistentFlags().StringVar(&pkg.Options.Selector, "selector", "", "Tag selector for filtering the selection, supports key=value, key!=value, key and !key separated by comma. Not supported by sshkeys, placementgroups, sharedprocessorpools and ports")
+	Cmd.PersistentFlags().StringVar(&pkg.Options.ExcludeExpr, "exclude-regexp", "", "Regular Expressions for the resources to be skipped from the deletion, resources tagged with "+purge.DoNotDeleteTag+" are always skipped")
+	Cmd.PersistentFlags().StringVar(&pkg.Options.ExcludeIDsFile, "exclude-ids-file", "", "File with the IDs of the resources to be skipped from the deletion, one ID per line")
+	Cmd.PersistentFlags().IntVar(&pkg.Options.Parallel, "parallel", 1, "Number of resources to be deleted in parallel, failed deletions are retried as per the --max-retries")
+	Cmd.PersistentFlags().BoolVar(&pkg.Options.Wait, "wait", false, "Wait for the deleted resources to be gone, resources still present after the --wait-timeout are reported as failures")
+	Cmd.PersistentFlags().DurationVar(&pkg.Options.WaitTimeout, "wait-timeout", 30*time.Minute, "Time to wait for the deleted resources to be gone")
+	Cmd.Flags().StringVar(&policyFile, "policy", "", "Policy file with the purge rules per PowerVS instance and resource kind")
+	Cmd.PersistentFlags().StringVarP(&pkg.Options.Output, "output", "o", utils.OutputTable, "Output format of the purgeable candidates, supported are: ["+strings.Join(utils.OutputFormats, ", ")+"]")
 }
@@ -0,0 +1,142 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sharedprocessorpools
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg"
+	"github.com/ppc64le-cloud/pvsadm/pkg/client"
+	"github.com/ppc64le-cloud/pvsadm/pkg/client/sharedprocessorpool"
+	"github.com/ppc64le-cloud/pvsadm/pkg/purge"
+	"github.com/ppc64le-cloud/pvsadm/pkg/utils"
+)
+
+const deletePromptMessage = "Deleting all the above shared processor pools, shared processor pools can't be claimed back once deleted. Do you really want to continue?"
+
+// candidate is the shared processor pool along with the purge action
+type candidate struct {
+	client.Workspace
+	*sharedprocessorpool.SharedProcessorPool
+	Action string `json:"action"`
+	Reason string `json:"reason,omitempty"`
+}
+
+var Cmd = &cobra.Command{
+	Use:   "sharedprocessorpools",
+	Short: "Purge the powervs shared processor pools",
+	Long: `Purge the powervs shared processor pools
+
+Shared processor pools with vms placed in them are skipped, shared processor pools don't carry any date hence
+--before and --since aren't supported.
+
+Examples:
+  # Delete all the shared processor pools starts with ci-
+  pvsadm purge sharedprocessorpools --instance-name upstream-core --regexp "^ci-.*"
+
+pvsadm purge --help for information
+`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if pkg.Options.Since != 0 || pkg.Options.Before != 0 {
+			return fmt.Errorf("--since and --before options are not supported for the shared processor pools")
+		}
+		if pkg.Options.Selector != "" {
+			return fmt.Errorf("--selector option is not supported for the shared processor pools")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opt := pkg.Options
+
+		c, err := client.NewClientWithEnv(cmd.Context(), opt.APIKey, opt.Environment, opt.Debug)
+		if err != nil {
+			return err
+		}
+
+		pvmclients, err := client.NewPVMClientsWithEnv(c, opt.Environment)
+		if err != nil {
+			return err
+		}
+
+		rows := make([][]candidate, len(pvmclients))
+		items := make([][]purge.Item, len(pvmclients))
+		err = client.ForEach(pvmclients, func(i int, pvmclient *client.PVMClient) error {
+			klog.Infof("Purging the shared processor pools for the instance: %v", pvmclient.InstanceID)
+
+			candidates, err := pvmclient.SharedProcessorPoolClient.GetAllPurgeable(opt.ExprRegexp)
+			if err != nil {
+				return err
+			}
+			// shared processor pools are protected only by the exclude options
+			protection, err := purge.NewProtection(opt.ExcludeExpr, opt.ExcludeIDsFile, nil)
+			if err != nil {
+				return err
+			}
+
+			for _, item := range candidates {
+				id := *item.ID
+				reason, err := protection.Reason("shared processor pool", *item.Name, id)
+				if err != nil {
+					return err
+				}
+				if reason == "" {
+					// the list doesn't carry the vms placed in the pools
+					detail, err := pvmclient.SharedProcessorPoolClient.Get(id)
+					if err != nil {
+						return fmt.Errorf("failed to get the shared processor pool %s: %v", *item.Name, err)
+					}
+					if len(detail.Servers) != 0 {
+						reason = fmt.Sprintf("has %d vms", len(detail.Servers))
+					}
+				}
+				rows[i] = append(rows[i], candidate{Workspace: pvmclient.Workspace(), SharedProcessorPool: item, Action: purge.Action(reason), Reason: reason})
+				items[i] = append(items[i], purge.Item{
+					Name: *item.Name,
+					ID:   id,
+					Delete: func() error {
+						return pvmclient.SharedProcessorPoolClient.Delete(id)
+					},
+					Get: func() error {
+						_, err := pvmclient.SharedProcessorPoolClient.Get(id)
+						return err
+					},
+					SkipReason: reason,
+				})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		var all []candidate
+		for _, r := range rows {
+			all = append(all, r...)
+		}
+		table := utils.NewTable()
+		if err := table.Render(all, client.ExcludeWorkspace(pvmclients, nil)); err != nil {
+			return err
+		}
+		if !opt.DryRun && purge.Deletable(items...) != 0 {
+			if opt.NoPrompt || utils.AskYesOrNo(deletePromptMessage) {
+				return purge.DeleteAllInstances(cmd.Context(), "sharedprocessorpools", pvmclients, items)
+			}
+		}
+		return nil
+	},
+}
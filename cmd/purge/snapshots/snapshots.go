@@ -0,0 +1,120 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshots
+
+import (
+	"github.com/IBM-Cloud/power-go-client/power/models"
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg"
+	"github.com/ppc64le-cloud/pvsadm/pkg/client"
+	"github.com/ppc64le-cloud/pvsadm/pkg/purge"
+	"github.com/ppc64le-cloud/pvsadm/pkg/utils"
+)
+
+const deletePromptMessage = "Deleting all the above snapshots, snapshots can't be claimed back once deleted. Do you really want to continue?"
+
+// candidate is the snapshot along with the purge action
+type candidate struct {
+	client.Workspace
+	*models.Snapshot
+	Action string `json:"action"`
+	Reason string `json:"reason,omitempty"`
+}
+
+var Cmd = &cobra.Command{
+	Use:   "snapshots",
+	Short: "Purge the powervs VM snapshots",
+	Long: `Purge the powervs vm snapshots
+
+Examples:
+  # Delete all the snapshots created before 72hrs
+  pvsadm purge snapshots --instance-name upstream-core --before 72h
+
+  # Delete all the snapshots starts with ci-
+  pvsadm purge snapshots --instance-name upstream-core --regexp "^ci-.*"
+
+pvsadm purge --help for information
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opt := pkg.Options
+
+		c, err := client.NewClientWithEnv(cmd.Context(), opt.APIKey, opt.Environment, opt.Debug)
+		if err != nil {
+			return err
+		}
+
+		pvmclients, err := client.NewPVMClientsWithEnv(c, opt.Environment)
+		if err != nil {
+			return err
+		}
+
+		rows := make([][]candidate, len(pvmclients))
+		items := make([][]purge.Item, len(pvmclients))
+		err = client.ForEach(pvmclients, func(i int, pvmclient *client.PVMClient) error {
+			klog.Infof("Purging the snapshots for the instance: %v", pvmclient.InstanceID)
+
+			candidates, err := pvmclient.SnapshotClient.GetAllPurgeable(opt.Before, opt.Since, opt.ExprRegexp)
+			if err != nil {
+				return err
+			}
+			protection, err := purge.NewProtectionWithOptions(pvmclient)
+			if err != nil {
+				return err
+			}
+
+			for _, item := range candidates {
+				id := *item.SnapshotID
+				reason, err := protection.Reason("snapshot", *item.Name, id)
+				if err != nil {
+					return err
+				}
+				rows[i] = append(rows[i], candidate{Workspace: pvmclient.Workspace(), Snapshot: item, Action: purge.Action(reason), Reason: reason})
+				items[i] = append(items[i], purge.Item{
+					Name: *item.Name,
+					ID:   id,
+					Delete: func() error {
+						return pvmclient.SnapshotClient.Delete(id)
+					},
+					Get: func() error {
+						_, err := pvmclient.SnapshotClient.Get(id)
+						return err
+					},
+					SkipReason: reason,
+				})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		var all []candidate
+		for _, r := range rows {
+			all = append(all, r...)
+		}
+		table := utils.NewTable()
+		if err := table.Render(all, client.ExcludeWorkspace(pvmclients, []string{"volumesnapshots"})); err != nil {
+			return err
+		}
+		if !opt.DryRun && purge.Deletable(items...) != 0 {
+			if opt.NoPrompt || utils.AskYesOrNo(deletePromptMessage) {
+				return purge.DeleteAllInstances(cmd.Context(), "snapshots", pvmclients, items)
+			}
+		}
+		return nil
+	},
+}
@@ -0,0 +1,141 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sshkeys
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/IBM-Cloud/power-go-client/power/models"
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg"
+	"github.com/ppc64le-cloud/pvsadm/pkg/client"
+	"github.com/ppc64le-cloud/pvsadm/pkg/purge"
+	"github.com/ppc64le-cloud/pvsadm/pkg/utils"
+)
+
+const (
+	deletePromptMessage = "Deleting all the above ssh keys, ssh keys can't be claimed back once deleted. Do you really want to continue?"
+	// allKeysPromptMessage is used without the --regexp, lists the keys since they are shared by the whole account
+	allKeysPromptMessage = "No --regexp set, deleting the ssh keys: %s shared by all the PowerVS instances of the account, ssh keys can't be claimed back once deleted. Do you really want to continue?"
+)
+
+// candidate is the ssh key along with the purge action
+type candidate struct {
+	*models.SSHKey
+	Action string `json:"action"`
+	Reason string `json:"reason,omitempty"`
+}
+
+var Cmd = &cobra.Command{
+	Use:   "sshkeys",
+	Short: "Purge the powervs SSH keys",
+	Long: `Purge the powervs SSH keys
+
+SSH keys belong to the account and are shared by all the PowerVS instances, the instance is only used for
+authenticating the requests. SSH keys are identified by the name, hence --exclude-ids-file lists the names.
+Without --regexp, the keys to be deleted are listed and confirmed at the prompt even with --no-prompt, hence
+--no-prompt requires --regexp.
+
+Examples:
+  # Delete all the SSH keys starts with ci- and created before 24hrs
+  pvsadm purge sshkeys --instance-name upstream-core --regexp "^ci-.*" --before 24h
+
+pvsadm purge --help for information
+`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if pkg.Options.Selector != "" {
+			return fmt.Errorf("--selector option is not supported for the ssh keys")
+		}
+		if pkg.Options.MultipleInstances() {
+			return fmt.Errorf("ssh keys belong to the account, use a single --instance-id or --instance-name")
+		}
+		if pkg.Options.Expr == "" && pkg.Options.NoPrompt && !pkg.Options.DryRun {
+			return fmt.Errorf("--regexp is required along with --no-prompt, ssh keys are shared by the whole account")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opt := pkg.Options
+
+		c, err := client.NewClientWithEnv(cmd.Context(), opt.APIKey, opt.Environment, opt.Debug)
+		if err != nil {
+			return err
+		}
+
+		pvmclient, err := client.NewPVMClientWithEnv(c, opt.InstanceID, opt.InstanceName, opt.Environment)
+		if err != nil {
+			return err
+		}
+		klog.Infof("Purging the ssh keys for the instance: %v", pvmclient.InstanceID)
+
+		candidates, err := pvmclient.SSHKeyClient.GetAllPurgeable(opt.Before, opt.Since, opt.ExprRegexp)
+		if err != nil {
+			return err
+		}
+		// ssh keys can't be tagged, hence protected only by the exclude options
+		protection, err := purge.NewProtection(opt.ExcludeExpr, opt.ExcludeIDsFile, nil)
+		if err != nil {
+			return err
+		}
+
+		var rows []candidate
+		var items []purge.Item
+		for _, item := range candidates {
+			id := *item.Name
+			reason, err := protection.Reason("ssh key", *item.Name, id)
+			if err != nil {
+				return err
+			}
+			rows = append(rows, candidate{SSHKey: item, Action: purge.Action(reason), Reason: reason})
+			items = append(items, purge.Item{
+				Name: *item.Name,
+				ID:   id,
+				Delete: func() error {
+					return pvmclient.SSHKeyClient.Delete(id)
+				},
+				Get: func() error {
+					_, err := pvmclient.SSHKeyClient.Get(id)
+					return err
+				},
+				SkipReason: reason,
+			})
+		}
+
+		table := utils.NewTable()
+		if err := table.Render(rows, []string{"sshkey"}); err != nil {
+			return err
+		}
+		if opt.DryRun || purge.Deletable(items) == 0 {
+			return nil
+		}
+		if opt.Expr == "" {
+			var names []string
+			for _, item := range items {
+				if item.SkipReason == "" {
+					names = append(names, item.Name)
+				}
+			}
+			if !utils.AskYesOrNo(fmt.Sprintf(allKeysPromptMessage, strings.Join(names, ", "))) {
+				return nil
+			}
+		} else if !opt.NoPrompt && !utils.AskYesOrNo(deletePromptMessage) {
+			return nil
+		}
+		return purge.DeleteAll(cmd.Context(), "sshkeys", pvmclient.InstanceName, items)
+	},
+}
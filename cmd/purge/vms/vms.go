@@ -15,13 +15,15 @@
 package vms
 
 import (
+	"context"
 	"fmt"
 	"github.com/IBM-Cloud/power-go-client/errors"
 	"github.com/IBM-Cloud/power-go-client/ibmpisession"
 	"github.com/IBM-Cloud/power-go-client/power/client/p_cloud_instances"
+	"github.com/IBM-Cloud/power-go-client/power/models"
 	"github.com/ppc64le-cloud/pvsadm/pkg"
-	"github.com/ppc64le-cloud/pvsadm/pkg/audit"
 	"github.com/ppc64le-cloud/pvsadm/pkg/client"
+	"github.com/ppc64le-cloud/pvsadm/pkg/purge"
 	"github.com/ppc64le-cloud/pvsadm/pkg/utils"
 	"github.com/spf13/cobra"
 	"k8s.io/klog/v2"
@@ -40,80 +42,154 @@ pvsadm purge --help for information
 	RunE: func(cmd *cobra.Command, args []string) error {
 		opt := pkg.Options
 
-		c, err := client.NewClientWithEnv(opt.APIKey, opt.Environment, opt.Debug)
+		c, err := client.NewClientWithEnv(cmd.Context(), opt.APIKey, opt.Environment, opt.Debug)
 		if err != nil {
 			return err
 		}
 
-		pvmclient, err := client.NewPVMClientWithEnv(c, opt.InstanceID, opt.InstanceName, pkg.Options.Environment)
+		pvmclients, err := client.NewPVMClientsWithEnv(c, opt.Environment)
 		if err != nil {
 			return err
 		}
 
-		param := p_cloud_instances.NewPcloudCloudinstancesGetParamsWithTimeout(pkg.TIMEOUT).WithCloudInstanceID(pvmclient.InstanceID)
-		resp, err := pvmclient.PISession.Power.PCloudInstances.PcloudCloudinstancesGet(param, ibmpisession.NewAuth(pvmclient.PISession, pvmclient.InstanceID))
+		usages := make([]*models.CloudInstanceUsageLimits, len(pvmclients))
+		rows := make([][]candidate, len(pvmclients))
+		err = client.ForEach(pvmclients, func(i int, pvmclient *client.PVMClient) error {
+			param := p_cloud_instances.NewPcloudCloudinstancesGetParamsWithTimeout(pkg.Options.APITimeout).WithCloudInstanceID(pvmclient.InstanceID)
+			resp, err := pvmclient.PISession.Power.PCloudInstances.PcloudCloudinstancesGet(param, ibmpisession.NewAuth(pvmclient.PISession, pvmclient.InstanceID))
 
-		if err != nil || resp.Payload == nil {
-			klog.Infof("Failed to perform the operation... %v", err)
-			return errors.ToError(err)
-		}
-		usage := resp.Payload.Usage
+			if err != nil || resp.Payload == nil {
+				klog.Infof("Failed to perform the operation... %v", err)
+				return errors.ToError(err)
+			}
+			usages[i] = resp.Payload.Usage
 
-		fmt.Println("Usage:")
-		tu := utils.NewTable()
-		tu.SetHeader([]string{"Instances", "Memory", "Proc Units", "processors", "storage", "storageSSD", "storageStandard"})
-		tu.Append([]string{strconv.FormatFloat(*usage.Instances, 'f', -1, 64),
-			strconv.FormatFloat(*usage.Memory, 'f', -1, 64),
-			strconv.FormatFloat(*usage.ProcUnits, 'f', 1, 64),
-			strconv.FormatFloat(*usage.Processors, 'f', -1, 64),
-			strconv.FormatFloat(*usage.Storage, 'f', 2, 64),
-			strconv.FormatFloat(*usage.StorageSSD, 'f', 2, 64),
-			strconv.FormatFloat(*usage.StorageStandard, 'f', 2, 64),
-		})
-		tu.Table.Render()
+			instances, err := pvmclient.InstanceClient.GetAllPurgeable(pkg.Options.Before, pkg.Options.Since, pkg.Options.Expr)
+			if err != nil {
+				return err
+			}
 
-		instances, err := pvmclient.InstanceClient.GetAllPurgeable(pkg.Options.Before, pkg.Options.Since, pkg.Options.Expr)
+			protection, err := purge.NewProtectionWithOptions(pvmclient)
+			if err != nil {
+				return err
+			}
+			for _, instance := range instances {
+				reason, err := protection.Reason("pvm-instance", *instance.ServerName, *instance.PvmInstanceID)
+				if err != nil {
+					return err
+				}
+				rows[i] = append(rows[i], candidate{Workspace: pvmclient.Workspace(), PVMInstanceReference: instance, Action: purge.Action(reason), Reason: reason})
+			}
+			return nil
+		})
 		if err != nil {
 			return err
 		}
 
 		t := utils.NewTable()
-		t.SetHeader([]string{"Name", "IP Addresses", "Image", "CPUS", "RAM", "STATUS", "Creation Date"})
-		for _, instance := range instances {
-			ins, err := pvmclient.InstanceClient.Get(*instance.PvmInstanceID)
-			if err != nil {
-				klog.Infof("Error occurred while getting the vm %s", err)
-				continue
+		if t.Structured() {
+			var all []candidate
+			for _, r := range rows {
+				all = append(all, r...)
 			}
-			var ipAddrsPrivate, ipAddrsPublic []string
-			for _, ip := range ins.Networks {
-				if ip.ExternalIP != "" {
-					ipAddrsPublic = append(ipAddrsPublic, ip.ExternalIP)
-				}
-				ipAddrsPrivate = append(ipAddrsPrivate, ip.IPAddress)
+			if err := t.Render(all, nil); err != nil {
+				return err
 			}
-			ipString := fmt.Sprintf("External: %s\nPrivate: %s", strings.Join(ipAddrsPublic, ", "), strings.Join(ipAddrsPrivate, ", "))
-			status := fmt.Sprintf("Status: %s\nHealth: %s", *instance.Status, instance.Health.Status)
-			row := []string{*instance.ServerName, ipString, *instance.ImageID, utils.FormatProcessor(instance.Processors), utils.FormatMemory(instance.Memory), status, instance.CreationDate.String()}
-			t.Append(row)
+			return deleteInstances(cmd.Context(), pvmclients, rows)
 		}
-		t.Table.Render()
-		if !opt.DryRun && len(instances) != 0 {
-			if opt.NoPrompt || utils.AskYesOrNo(deletePromptMessage) {
-				for _, instance := range instances {
-					klog.Infof("Deleting the %s, and ID: %s", *instance.ServerName, *instance.PvmInstanceID)
-					err = pvmclient.InstanceClient.Delete(*instance.PvmInstanceID)
-					if err != nil {
-						if opt.IgnoreErrors {
-							klog.Infof("error occurred while deleting the vm: %v", err)
-						} else {
-							return err
-						}
+
+		multiple := len(pvmclients) > 1
+		// withWorkspace prepends the workspace columns for the multiple instances
+		withWorkspace := func(workspace, zone string, r []string) []string {
+			if !multiple {
+				return r
+			}
+			return append([]string{workspace, zone}, r...)
+		}
+
+		fmt.Println("Usage:")
+		tu := utils.NewTable()
+		tu.SetHeader(withWorkspace("Workspace", "Zone", []string{"Instances", "Memory", "Proc Units", "processors", "storage", "storageSSD", "storageStandard"}))
+		for i, usage := range usages {
+			tu.Append(withWorkspace(pvmclients[i].InstanceName, pvmclients[i].Zone, []string{strconv.FormatFloat(*usage.Instances, 'f', -1, 64),
+				strconv.FormatFloat(*usage.Memory, 'f', -1, 64),
+				strconv.FormatFloat(*usage.ProcUnits, 'f', 1, 64),
+				strconv.FormatFloat(*usage.Processors, 'f', -1, 64),
+				strconv.FormatFloat(*usage.Storage, 'f', 2, 64),
+				strconv.FormatFloat(*usage.StorageSSD, 'f', 2, 64),
+				strconv.FormatFloat(*usage.StorageStandard, 'f', 2, 64),
+			}))
+		}
+		tu.Table.Render()
+
+		header := []string{"Name", "IP Addresses", "Image", "CPUS", "RAM", "STATUS", "Creation Date", "Action", "Reason"}
+		if t.Wide() {
+			header = []string{"Name", "ID", "IP Addresses", "Image", "CPUS", "RAM", "Proc Type", "Sys Type", "OS Type", "Storage Type", "STATUS", "Creation Date", "Updated Date", "Action", "Reason"}
+		}
+		t.SetHeader(withWorkspace("Workspace", "Zone", header))
+		for i, pvmclient := range pvmclients {
+			for _, instance := range rows[i] {
+				ins, err := pvmclient.InstanceClient.Get(*instance.PvmInstanceID)
+				if err != nil {
+					klog.Infof("Error occurred while getting the vm %s", err)
+					continue
+				}
+				var ipAddrsPrivate, ipAddrsPublic []string
+				for _, ip := range ins.Networks {
+					if ip.ExternalIP != "" {
+						ipAddrsPublic = append(ipAddrsPublic, ip.ExternalIP)
 					}
-					audit.Log("vms", "delete", pvmclient.InstanceName+":"+*instance.ServerName)
+					ipAddrsPrivate = append(ipAddrsPrivate, ip.IPAddress)
 				}
+				ipString := fmt.Sprintf("External: %s\nPrivate: %s", strings.Join(ipAddrsPublic, ", "), strings.Join(ipAddrsPrivate, ", "))
+				status := fmt.Sprintf("Status: %s\nHealth: %s", *instance.Status, instance.Health.Status)
+				row := []string{*instance.ServerName, ipString, *instance.ImageID, utils.FormatProcessor(instance.Processors), utils.FormatMemory(instance.Memory), status, instance.CreationDate.String(), instance.Action, instance.Reason}
+				if t.Wide() {
+					row = []string{*instance.ServerName, *instance.PvmInstanceID, ipString, *instance.ImageID, utils.FormatProcessor(instance.Processors), utils.FormatMemory(instance.Memory),
+						utils.FormatValue(instance.ProcType), instance.SysType, utils.FormatValue(instance.OsType), instance.StorageType, status, instance.CreationDate.String(), instance.UpdatedDate.String(), instance.Action, instance.Reason}
+				}
+				t.Append(withWorkspace(pvmclient.InstanceName, pvmclient.Zone, row))
 			}
 		}
-		return nil
+		t.Table.Render()
+		return deleteInstances(cmd.Context(), pvmclients, rows)
 	},
 }
+
+// candidate is the vm along with the purge action
+type candidate struct {
+	client.Workspace
+	*models.PVMInstanceReference
+	Action string `json:"action"`
+	Reason string `json:"reason,omitempty"`
+}
+
+func deleteInstances(ctx context.Context, pvmclients []*client.PVMClient, rows [][]candidate) error {
+	items := make([][]purge.Item, len(pvmclients))
+	for i, pvmclient := range pvmclients {
+		pvmclient := pvmclient
+		for _, instance := range rows[i] {
+			id := *instance.PvmInstanceID
+			items[i] = append(items[i], purge.Item{
+				Name: *instance.ServerName,
+				ID:   id,
+				Delete: func() error {
+					return pvmclient.InstanceClient.Delete(id)
+				},
+				Get: func() error {
+					_, err := pvmclient.InstanceClient.Get(id)
+					return err
+				},
+				SkipReason: instance.Reason,
+			})
+		}
+	}
+
+	opt := pkg.Options
+	if !opt.DryRun && purge.Deletable(items...) != 0 {
+		if opt.NoPrompt || utils.AskYesOrNo(deletePromptMessage) {
+			return purge.DeleteAllInstances(ctx, "vms", pvmclients, items)
+		}
+	}
+	return nil
+}
@@ -16,69 +16,176 @@ package volumes
 
 import (
 	"fmt"
+	"github.com/IBM-Cloud/power-go-client/power/models"
 	"github.com/ppc64le-cloud/pvsadm/pkg"
-	"github.com/ppc64le-cloud/pvsadm/pkg/audit"
 	"github.com/ppc64le-cloud/pvsadm/pkg/client"
+	"github.com/ppc64le-cloud/pvsadm/pkg/client/volume"
+	"github.com/ppc64le-cloud/pvsadm/pkg/purge"
 	"github.com/ppc64le-cloud/pvsadm/pkg/utils"
 	"github.com/spf13/cobra"
 	"k8s.io/klog/v2"
-	"time"
+	"strings"
 )
 
-var before time.Duration
+var (
+	dateField string
+	states    []string
+	orphaned  bool
+)
+
+const deletePromptMessage = "Deleting all the above volumes, volumes can't be claimed back once deleted. Do you really want to continue?"
 
-const deletePromptMessage = "Deleting all the volumes in available state, volumes can't be claimed back once deleted. Do you really want to continue?"
+// candidate is the volume along with the purge action
+type candidate struct {
+	client.Workspace
+	*models.VolumeReference
+	Action string `json:"action"`
+	Reason string `json:"reason,omitempty"`
+}
 
 var Cmd = &cobra.Command{
 	Use:   "volumes",
 	Short: "Purge the powervs volumes",
 	Long: `Deletes all the volumes for the powervs instance which are in available state(not attached to any instances)
+
+The --before and --since thresholds are applied on the last update date of the volumes by default, use --date-field
+creation for the creation date instead. Volumes in other states can be selected with --state, and --orphaned selects
+the volumes whose owning vms no longer exist. Only the listed volumes marked DELETE are deleted.
+
+Examples:
+  # Delete the available volumes created before 48hrs
+  pvsadm purge volumes --instance-name upstream-core --date-field creation --before 48h
+
+  # Delete the volumes in error state
+  pvsadm purge volumes --instance-name upstream-core --state error
+
+  # Delete the volumes left behind by the deleted vms
+  pvsadm purge volumes --instance-name upstream-core --orphaned
+
 pvsadm purge --help for information
 `,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		for _, f := range volume.DateFields {
+			if dateField == f {
+				return nil
+			}
+		}
+		return fmt.Errorf("unsupported --date-field: %s, supported are: [%s]", dateField, strings.Join(volume.DateFields, ", "))
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		opt := pkg.Options
+		// orphaned volumes are still attached to the deleted vms, hence any state unless asked explicitly
+		if orphaned && !cmd.Flags().Changed("state") {
+			states = nil
+		}
 
-		c, err := client.NewClientWithEnv(opt.APIKey, opt.Environment, opt.Debug)
+		c, err := client.NewClientWithEnv(cmd.Context(), opt.APIKey, opt.Environment, opt.Debug)
 		if err != nil {
 			klog.Error(err)
 			return err
 		}
 
-		pvmclient, err := client.NewPVMClientWithEnv(c, opt.InstanceID, opt.InstanceName, pkg.Options.Environment)
+		pvmclients, err := client.NewPVMClientsWithEnv(c, opt.Environment)
 		if err != nil {
 			return err
 		}
-		volumes, err := pvmclient.VolumeClient.GetAllPurgeableByLastUpdateDate(opt.Before, opt.Since, opt.Expr)
+
+		rows := make([][]candidate, len(pvmclients))
+		items := make([][]purge.Item, len(pvmclients))
+		err = client.ForEach(pvmclients, func(i int, pvmclient *client.PVMClient) error {
+			volumes, err := pvmclient.VolumeClient.GetAllPurgeableByDateField(dateField, opt.Before, opt.Since, opt.Expr)
+			if err != nil {
+				return fmt.Errorf("failed to get the list of volumes for the instance %s: %v", pvmclient.InstanceName, err)
+			}
+			if len(states) != 0 {
+				volumes = volume.FilterByState(volumes, states...)
+			}
+			if orphaned {
+				instances, err := pvmclient.InstanceClient.GetAll()
+				if err != nil {
+					return fmt.Errorf("failed to get the list of vms for the instance %s: %v", pvmclient.InstanceName, err)
+				}
+				vms := map[string]bool{}
+				for _, ins := range instances.PvmInstances {
+					vms[*ins.PvmInstanceID] = true
+				}
+				volumes = volume.Orphaned(volumes, vms)
+			}
+
+			protection, err := purge.NewProtectionWithOptions(pvmclient)
+			if err != nil {
+				return err
+			}
+			for _, vol := range volumes {
+				id := *vol.VolumeID
+				reason, err := protection.Reason("volume", *vol.Name, id)
+				if err != nil {
+					return err
+				}
+				rows[i] = append(rows[i], candidate{Workspace: pvmclient.Workspace(), VolumeReference: vol, Action: purge.Action(reason), Reason: reason})
+				items[i] = append(items[i], purge.Item{
+					Name: *vol.Name,
+					ID:   id,
+					Delete: func() error {
+						return pvmclient.VolumeClient.DeleteVolume(id)
+					},
+					Get: func() error {
+						_, err := pvmclient.VolumeClient.Get(id)
+						return err
+					},
+					SkipReason: reason,
+				})
+			}
+			return nil
+		})
 		if err != nil {
-			return fmt.Errorf("failed to get the list of volumes: %v", err)
+			return err
 		}
 
+		var all []candidate
+		for _, r := range rows {
+			all = append(all, r...)
+		}
 		t := utils.NewTable()
-		t.SetHeader([]string{"Name", "Volume ID", "State", "Last Update Date"})
-		for _, volume := range volumes {
-			t.Append([]string{*volume.Name, *volume.VolumeID, *volume.State, volume.LastUpdateDate.String()})
+		if t.Structured() {
+			if err := t.Render(all, nil); err != nil {
+				return err
+			}
+		} else {
+			multiple := len(pvmclients) > 1
+			header := []string{"Name", "Volume ID", "State", "Creation Date", "Last Update Date", "Attached VMs", "Action", "Reason"}
+			if t.Wide() {
+				header = []string{"Name", "Volume ID", "State", "Size", "Disk Type", "Volume Pool", "Shareable", "Bootable", "Creation Date", "Last Update Date", "Attached VMs", "Action", "Reason"}
+			}
+			if multiple {
+				header = append([]string{"Workspace", "Zone"}, header...)
+			}
+			t.SetHeader(header)
+			for _, row := range all {
+				r := []string{*row.Name, *row.VolumeID, *row.State, row.CreationDate.String(), row.LastUpdateDate.String(), strings.Join(row.PvmInstanceIds, ", "), row.Action, row.Reason}
+				if t.Wide() {
+					r = []string{*row.Name, *row.VolumeID, *row.State, utils.FormatValue(row.Size), utils.FormatValue(row.DiskType), row.VolumePool,
+						utils.FormatValue(row.Shareable), utils.FormatValue(row.Bootable), row.CreationDate.String(), row.LastUpdateDate.String(), strings.Join(row.PvmInstanceIds, ", "), row.Action, row.Reason}
+				}
+				if multiple {
+					r = append([]string{row.Workspace.Workspace, row.Zone}, r...)
+				}
+				t.Append(r)
+			}
+			t.Table.Render()
 		}
-		t.Table.Render()
 
-		if !opt.DryRun && len(volumes) != 0 {
-			klog.Infof("Deleting all the volumes in available state")
+		if !opt.DryRun && purge.Deletable(items...) != 0 {
 			if opt.NoPrompt || utils.AskYesOrNo(deletePromptMessage) {
-				for _, volume := range volumes {
-					if *volume.State == "available" {
-						klog.Infof("Deleting the %s, and ID: %s", *volume.Name, *volume.VolumeID)
-						err = pvmclient.VolumeClient.DeleteVolume(*volume.VolumeID)
-						if err != nil {
-							if opt.IgnoreErrors {
-								klog.Infof("error occurred while deleting the volume: %v", err)
-							} else {
-								return err
-							}
-						}
-						audit.Log("volumes", "delete", pvmclient.InstanceName+":"+*volume.Name)
-					}
-				}
+				return purge.DeleteAllInstances(cmd.Context(), "volumes", pvmclients, items)
 			}
 		}
 		return nil
 	},
 }
+
+func init() {
+	Cmd.Flags().StringVar(&dateField, "date-field", volume.DateFieldLastUpdate, "Date of the volumes used for the --before and --since options, supported are: ["+strings.Join(volume.DateFields, ", ")+"]")
+	Cmd.Flags().StringSliceVar(&states, "state", []string{"available"}, "States of the volumes to be deleted, any state if empty, defaults to any state with --orphaned")
+	Cmd.Flags().BoolVar(&orphaned, "orphaned", false, "Delete only the volumes whose owning vms no longer exist")
+}
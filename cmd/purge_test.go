@@ -0,0 +1,424 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg/client/fake"
+	"github.com/ppc64le-cloud/pvsadm/pkg/purge"
+)
+
+// purgeFixture seeds the workspace with an old, a new and an old protected resource of each kind and returns the
+// names of the remaining resources by the kind
+func purgeFixture(s *fake.Server) map[string]func() []string {
+	ws := s.AddWorkspace("ws", "dal12")
+	old, recent := time.Now().Add(-48*time.Hour), time.Now()
+
+	ws.AddInstance("vm-old", old)
+	ws.AddInstance("vm-new", recent)
+	vm := ws.AddInstance("vm-keep", old)
+	ws.Tag("pvm-instance", *vm.PvmInstanceID, purge.DoNotDeleteTag)
+
+	ws.AddVolume("vol-old", "available", old)
+	ws.AddVolume("vol-new", "available", recent)
+	vol := ws.AddVolume("vol-keep", "available", old)
+	ws.Tag("volume", *vol.VolumeID, purge.DoNotDeleteTag)
+
+	ws.AddImage("img-old", old)
+	ws.AddImage("img-new", recent)
+	img := ws.AddImage("img-keep", old)
+	ws.Tag("image", *img.ImageID, purge.DoNotDeleteTag)
+
+	return map[string]func() []string{
+		"vms": func() (names []string) {
+			for _, i := range ws.Instances() {
+				names = append(names, *i.ServerName)
+			}
+			return
+		},
+		"volumes": func() (names []string) {
+			for _, v := range ws.Volumes() {
+				names = append(names, *v.Name)
+			}
+			return
+		},
+		"images": func() (names []string) {
+			for _, i := range ws.Images() {
+				names = append(names, *i.Name)
+			}
+			return
+		},
+	}
+}
+
+func TestPurge(t *testing.T) {
+	tests := []struct {
+		name    string
+		kind    string
+		args    []string
+		fail    string
+		want    []string
+		wantErr bool
+	}{
+		{name: "vms", kind: "vms", want: []string{"vm-keep", "vm-new"}},
+		{name: "volumes", kind: "volumes", args: []string{"--date-field", "creation"}, want: []string{"vol-keep", "vol-new"}},
+		{name: "images", kind: "images", want: []string{"img-keep", "img-new"}},
+		{name: "dry run", kind: "vms", args: []string{"--dry-run"}, want: []string{"vm-keep", "vm-new", "vm-old"}},
+		{name: "exclude regexp", kind: "images", args: []string{"--exclude-regexp", "-old$"}, want: []string{"img-keep", "img-new", "img-old"}},
+		{name: "delete failure", kind: "images", fail: "/images/", want: []string{"img-keep", "img-new", "img-old"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := fakeCloud(t)
+			remaining := purgeFixture(s)
+			if tt.fail != "" {
+				s.Fail(http.MethodDelete, tt.fail, http.StatusBadRequest, 1)
+			}
+
+			args := append([]string{"purge", tt.kind, "--instance-name", "ws", "--before", "24h", "--no-prompt"}, tt.args...)
+			_, err := pvsadm(t, args...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("purge %s error = %v, wantErr %v", tt.kind, err, tt.wantErr)
+			}
+			got := remaining[tt.kind]()
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("remaining %s = %v, want %v", tt.kind, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPurgeMultipleInstances(t *testing.T) {
+	s := fakeCloud(t)
+	old := time.Now().Add(-48 * time.Hour)
+	ws1, ws2 := s.AddWorkspace("ws-1", "dal12"), s.AddWorkspace("ws-2", "dal10")
+	ws1.AddInstance("vm-1", old)
+	ws2.AddInstance("vm-2", old)
+	// failure in the first instance doesn't stop the deletion in the second one
+	s.Fail(http.MethodDelete, "/cloud-instances/"+ws1.ID+"/pvm-instances/", http.StatusBadRequest, 1)
+
+	_, err := pvsadm(t, "purge", "vms", "--instance-regexp", "^ws-", "--before", "24h", "--no-prompt")
+	if err == nil || !strings.Contains(err.Error(), "ws-1") {
+		t.Errorf("purge vms error = %v, want the failure in the ws-1", err)
+	}
+	if got := len(ws1.Instances()); got != 1 {
+		t.Errorf("remaining vms in the ws-1 = %d, want 1", got)
+	}
+	if got := len(ws2.Instances()); got != 0 {
+		t.Errorf("remaining vms in the ws-2 = %d, want 0", got)
+	}
+}
+
+func TestPurgeWideOutput(t *testing.T) {
+	tests := []struct {
+		kind   string
+		column string
+	}{
+		{kind: "vms", column: "SYS TYPE"},
+		{kind: "volumes", column: "DISK TYPE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			s := fakeCloud(t)
+			purgeFixture(s)
+			for _, output := range []string{"table", "wide"} {
+				out, err := pvsadm(t, "purge", tt.kind, "--instance-name", "ws", "--before", "24h", "--dry-run", "-o", output)
+				if err != nil {
+					t.Fatalf("purge %s -o %s error = %v", tt.kind, output, err)
+				}
+				if got, want := strings.Contains(out, tt.column), output == "wide"; got != want {
+					t.Errorf("purge %s -o %s prints the %s column: %v, want %v\n%s", tt.kind, output, tt.column, got, want, out)
+				}
+			}
+		})
+	}
+}
+
+func TestPurgePolicySameInstanceNames(t *testing.T) {
+	s := fakeCloud(t)
+	old := time.Now().Add(-48 * time.Hour)
+	ws1, ws2 := s.AddWorkspace("ws", "dal12"), s.AddWorkspace("ws", "dal10")
+	ws1.AddImage("img-1", old)
+	ws2.AddImage("img-2", old)
+
+	policy := filepath.Join(os.Getenv("HOME"), "policy.yaml")
+	content := fmt.Sprintf("instances:\n- id: %s\n  images:\n    before: 24h\n- id: %s\n  images:\n    before: 24h\n", ws1.ID, ws2.ID)
+	if err := ioutil.WriteFile(policy, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pvsadm(t, "purge", "--policy", policy, "--no-prompt"); err != nil {
+		t.Fatalf("purge --policy error = %v", err)
+	}
+	for _, ws := range []*fake.Workspace{ws1, ws2} {
+		if images := ws.Images(); len(images) != 0 {
+			t.Errorf("images left in the instance %s: %d, want 0", ws.ID, len(images))
+		}
+	}
+}
+
+func TestPurgePolicyFailureInAnInstance(t *testing.T) {
+	s := fakeCloud(t)
+	old := time.Now().Add(-48 * time.Hour)
+	ws1, ws2 := s.AddWorkspace("ws-1", "dal12"), s.AddWorkspace("ws-2", "dal10")
+	ws1.AddImage("img-1", old)
+	ws2.AddImage("img-2", old)
+	s.Fail(http.MethodDelete, "/cloud-instances/"+ws1.ID+"/images/", http.StatusBadRequest, 1)
+
+	policy := filepath.Join(os.Getenv("HOME"), "policy.yaml")
+	content := fmt.Sprintf("instances:\n- id: %s\n  images:\n    before: 24h\n- id: %s\n  images:\n    before: 24h\n", ws1.ID, ws2.ID)
+	if err := ioutil.WriteFile(policy, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pvsadm(t, "purge", "--policy", policy, "--no-prompt"); err == nil || !strings.Contains(err.Error(), "ws-1") {
+		t.Errorf("purge --policy error = %v, want the failure in the ws-1", err)
+	}
+	if got := len(ws1.Images()); got != 1 {
+		t.Errorf("remaining images in the ws-1 = %d, want 1", got)
+	}
+	if got := len(ws2.Images()); got != 0 {
+		t.Errorf("remaining images in the ws-2 = %d, want 0", got)
+	}
+}
+
+func TestPurgePolicyWait(t *testing.T) {
+	s := fakeCloud(t)
+	ws := s.AddWorkspace("ws", "dal12")
+	ws.AddImage("img-1", time.Now().Add(-48*time.Hour))
+	policy := filepath.Join(os.Getenv("HOME"), "policy.yaml")
+	if err := ioutil.WriteFile(policy, []byte("instances:\n- name: ws\n  images:\n    before: 24h\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// image never turns 404 while waiting
+	s.Fail(http.MethodGet, "/cloud-instances/"+ws.ID+"/images/", http.StatusBadRequest, 100)
+
+	out, err := pvsadm(t, "purge", "--policy", policy, "--no-prompt", "--wait", "--wait-timeout", "1s")
+	if err == nil {
+		t.Errorf("purge --policy --wait error = nil, want the image still present reported as failure")
+	}
+	if !strings.Contains(out, "Summary:") || !strings.Contains(out, "still present") {
+		t.Errorf("purge --policy --wait output = %s, want the summary with the image still present", out)
+	}
+}
+
+func TestPurgeInvalidOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{name: "invalid regexp", args: []string{"snapshots", "--regexp", "ci-("}, want: "invalid --regexp"},
+		{name: "invalid regexp of the shared processor pools", args: []string{"sharedprocessorpools", "--regexp", "["}, want: "invalid --regexp"},
+		{name: "all the ssh keys without prompt", args: []string{"sshkeys", "--no-prompt"}, want: "--regexp is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := fakeCloud(t)
+			s.AddWorkspace("ws", "dal12")
+			args := append([]string{"purge", "--instance-name", "ws"}, tt.args...)
+			if _, err := pvsadm(t, args...); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("purge %v error = %v, want %s", tt.args, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPurgeAll(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		fail    string
+		want    []string
+		wantLog []string
+		wantErr bool
+	}{
+		{
+			name:    "all",
+			wantLog: []string{"vms delete ws:vm-old", "volumes delete ws:vol-old", "images delete ws:img-old"},
+		},
+		{
+			name:    "delete failure",
+			fail:    "/volumes/",
+			want:    []string{"vol-old"},
+			wantLog: []string{"vms delete ws:vm-old", "volumes delete-failed ws:vol-old"},
+			wantErr: true,
+		},
+		{
+			name:    "delete failure ignored",
+			args:    []string{"--ignore-errors"},
+			fail:    "/volumes/",
+			want:    []string{"vol-old"},
+			wantLog: []string{"vms delete ws:vm-old", "volumes delete-failed ws:vol-old", "images delete ws:img-old"},
+		},
+		{
+			name:    "dependents of the failed vm skipped",
+			args:    []string{"--ignore-errors", "--wait-timeout", "1s"},
+			fail:    "/pvm-instances/",
+			want:    []string{"vol-old"},
+			wantLog: []string{"vms delete-failed ws:vm-old", "volumes skip ws:vol-old", "images delete ws:img-old"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := fakeCloud(t)
+			ws := s.AddWorkspace("ws", "dal12")
+			old := time.Now().Add(-48 * time.Hour)
+			vm := ws.AddInstance("vm-old", old)
+			vol := ws.AddVolume("vol-old", "in-use", old)
+			ws.AttachVolume(*vol.VolumeID, *vm.PvmInstanceID)
+			ws.AddImage("img-old", old)
+			if tt.fail != "" {
+				s.Fail(http.MethodDelete, tt.fail, http.StatusBadRequest, 1)
+			}
+
+			args := append([]string{"purge", "all", "--instance-name", "ws", "--before", "24h", "--no-prompt"}, tt.args...)
+			if _, err := pvsadm(t, args...); (err != nil) != tt.wantErr {
+				t.Fatalf("purge all error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var got []string
+			for _, v := range ws.Volumes() {
+				got = append(got, *v.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("remaining volumes = %v, want %v", got, tt.want)
+			}
+			content, err := ioutil.ReadFile(filepath.Join(os.Getenv("HOME"), "audit.log"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var entries []string
+			for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+				var entry struct{ Name, Op, Value string }
+				if err := json.Unmarshal([]byte(line), &entry); err != nil {
+					t.Fatal(err)
+				}
+				entries = append(entries, entry.Name+" "+entry.Op+" "+entry.Value)
+			}
+			if !reflect.DeepEqual(entries, tt.wantLog) {
+				t.Errorf("audit log = %v, want %v", entries, tt.wantLog)
+			}
+		})
+	}
+}
+
+func TestPurgeAllMultipleInstances(t *testing.T) {
+	s := fakeCloud(t)
+	old := time.Now().Add(-48 * time.Hour)
+	ws1, ws2 := s.AddWorkspace("ws-1", "dal12"), s.AddWorkspace("ws-2", "dal10")
+	ws1.AddVolume("vol-1", "available", old)
+	ws2.AddVolume("vol-2", "available", old)
+	ws2.AddImage("img-2", old)
+	// failure in the first instance doesn't stop the purge of the second one
+	s.Fail(http.MethodDelete, "/cloud-instances/"+ws1.ID+"/volumes/", http.StatusBadRequest, 1)
+
+	_, err := pvsadm(t, "purge", "all", "--instance-regexp", "^ws-", "--before", "24h", "--no-prompt")
+	if err == nil || !strings.Contains(err.Error(), "ws-1") {
+		t.Errorf("purge all error = %v, want the failure in the ws-1", err)
+	}
+	if got := len(ws1.Volumes()); got != 1 {
+		t.Errorf("remaining volumes in the ws-1 = %d, want 1", got)
+	}
+	if got := len(ws2.Volumes()) + len(ws2.Images()); got != 0 {
+		t.Errorf("remaining volumes and images in the ws-2 = %d, want 0", got)
+	}
+	if _, err := pvsadm(t, "purge", "sshkeys", "--all-instances", "--regexp", "^ci-"); err == nil || !strings.Contains(err.Error(), "single") {
+		t.Errorf("purge sshkeys --all-instances error = %v, want the multiple instances rejected", err)
+	}
+}
+
+func TestPurgeStaleInstanceCache(t *testing.T) {
+	s := fakeCloud(t)
+	old := time.Now().Add(-48 * time.Hour)
+	s.AddWorkspace("ws-1", "dal12").AddInstance("vm-1", old)
+	// caches the ws-1 alone
+	if _, err := pvsadm(t, "get", "vms", "--instance-regexp", "^ws-"); err != nil {
+		t.Fatalf("get vms failed: %v", err)
+	}
+
+	ws2 := s.AddWorkspace("ws-2", "dal10")
+	ws2.AddInstance("vm-2", old)
+	if _, err := pvsadm(t, "get", "vms", "--instance-name", "ws-1,ws-2"); err != nil {
+		t.Errorf("get vms of the instance missing in the cache failed: %v", err)
+	}
+
+	ws3 := s.AddWorkspace("ws-3", "dal12")
+	ws3.AddInstance("vm-3", old)
+	if _, err := pvsadm(t, "purge", "vms", "--instance-regexp", "^ws-", "--before", "24h", "--no-prompt"); err != nil {
+		t.Fatalf("purge vms failed: %v", err)
+	}
+	if got := len(ws3.Instances()); got != 0 {
+		t.Errorf("remaining vms in the ws-3 missing in the cache = %d, want 0", got)
+	}
+
+	s.AddWorkspace("ws-1", "dal10")
+	if _, err := pvsadm(t, "purge", "vms", "--instance-name", "ws-1,ws-2", "--refresh", "--no-prompt"); err == nil || !strings.Contains(err.Error(), "more than one instance") {
+		t.Errorf("purge vms of the duplicate name error = %v, want the ambiguity error", err)
+	}
+}
+
+func TestPurgeMaxRetries(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "retried", want: []string{"img-keep", "img-new"}},
+		{name: "retries disabled", args: []string{"--max-retries", "0"}, want: []string{"img-keep", "img-new", "img-old"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := fakeCloud(t)
+			remaining := purgeFixture(s)
+			s.Fail(http.MethodDelete, "/images/", http.StatusServiceUnavailable, 1)
+
+			args := append([]string{"purge", "images", "--instance-name", "ws", "--before", "24h", "--no-prompt"}, tt.args...)
+			if _, err := pvsadm(t, args...); (err != nil) != tt.wantErr {
+				t.Fatalf("purge images error = %v, wantErr %v", err, tt.wantErr)
+			}
+			got := remaining["images"]()
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("remaining images = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPurgeSharedProcessorPools(t *testing.T) {
+	s := fakeCloud(t)
+	ws := s.AddWorkspace("ws", "dal12")
+	ws.AddSharedProcessorPool("ci-idle")
+	inUse := ws.AddSharedProcessorPool("ci-in-use", "vm-1")
+	other := ws.AddSharedProcessorPool("prod")
+
+	if _, err := pvsadm(t, "purge", "sharedprocessorpools", "--instance-name", "ws", "--regexp", "^ci-", "--no-prompt"); err != nil {
+		t.Fatalf("purge sharedprocessorpools error = %v", err)
+	}
+	if got, want := ws.SharedProcessorPools(), []string{inUse, other}; !reflect.DeepEqual(got, want) {
+		t.Errorf("remaining shared processor pools = %v, want %v", got, want)
+	}
+}
@@ -15,6 +15,7 @@
 package cmd
 
 import (
+	"context"
 	goflag "flag"
 	"fmt"
 	"os"
@@ -24,6 +25,7 @@ import (
 	flag "github.com/spf13/pflag"
 	"k8s.io/klog/v2"
 
+	configcmd "github.com/ppc64le-cloud/pvsadm/cmd/config"
 	"github.com/ppc64le-cloud/pvsadm/cmd/create"
 	deletecmd "github.com/ppc64le-cloud/pvsadm/cmd/delete"
 	"github.com/ppc64le-cloud/pvsadm/cmd/dhcp-sync"
@@ -34,6 +36,8 @@ import (
 	"github.com/ppc64le-cloud/pvsadm/pkg"
 	"github.com/ppc64le-cloud/pvsadm/pkg/audit"
 	"github.com/ppc64le-cloud/pvsadm/pkg/client"
+	"github.com/ppc64le-cloud/pvsadm/pkg/config"
+	"github.com/ppc64le-cloud/pvsadm/pkg/utils"
 )
 
 var rootCmd = &cobra.Command{
@@ -42,21 +46,65 @@ var rootCmd = &cobra.Command{
 	Long: `Power Systems Virtual Server projects deliver flexible compute capacity for Power Systems workloads.
 Integrated with the IBM Cloud platform for on-demand provisioning.
 
-This is a tool built for the Power Systems Virtual Server helps managing and maintaining the resources easily`,
+This is a tool built for the Power Systems Virtual Server helps managing and maintaining the resources easily
+
+Interrupting a command(Ctrl-C) stops it from starting the new operations, wraps up the in-flight ones and exits with
+the status 130, interrupt again to exit immediately.`,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := applyConfig(cmd); err != nil {
+			return err
+		}
 		if pkg.Options.APIKey == "" {
 			if key := os.Getenv("IBMCLOUD_API_KEY"); key != "" {
 				klog.Infof("Using an API key from IBMCLOUD_API_KEY environment variable")
 				pkg.Options.APIKey = key
 			}
 		}
+		if pkg.Options.TrustedProfile == "" {
+			pkg.Options.TrustedProfile = os.Getenv("IBMCLOUD_TRUSTED_PROFILE")
+		}
+		if pkg.Options.CRTokenFile == "" {
+			pkg.Options.CRTokenFile = os.Getenv("IBMCLOUD_CR_TOKEN_FILE")
+		}
 		if _, err := client.GetEnvironment(pkg.Options.Environment); err != nil {
 			return fmt.Errorf("invalid \"%s\" IBM Cloud Environment passed, valid values are: %s", pkg.Options.Environment, strings.Join(client.ListEnvironments(), ", "))
 		}
+		if pkg.Options.APITimeout <= 0 {
+			return fmt.Errorf("--api-timeout must be greater than 0")
+		}
+		if pkg.Options.MaxRetries < 0 {
+			return fmt.Errorf("--max-retries can't be negative")
+		}
+		audit.Logger = audit.New(pkg.Options.AuditFile)
 		return nil
 	},
 }
 
+// applyConfig registers the environments of the config file and sets the options not set by the flags with the
+// profile in use
+func applyConfig(cmd *cobra.Command) error {
+	file, err := config.File()
+	if err != nil {
+		return err
+	}
+	c, err := config.Load(file)
+	if err != nil {
+		return err
+	}
+	for name, endpoints := range c.Environments {
+		if err := client.RegisterEnvironment(name, endpoints); err != nil {
+			return fmt.Errorf("invalid environment in the config file: %v", err)
+		}
+	}
+	p, err := c.Profile(pkg.Options.Profile)
+	if err != nil || p == nil {
+		return err
+	}
+	klog.V(2).Infof("Using the profile: %s", c.ProfileName(pkg.Options.Profile))
+	pkg.Options.Account = p.Account
+	return p.Apply(cmd.Flags())
+}
+
 func init() {
 	// Initilize the klog flags
 	klog.InitFlags(nil)
@@ -69,9 +117,16 @@ func init() {
 	rootCmd.AddCommand(create.Cmd)
 	rootCmd.AddCommand(deletecmd.Cmd)
 	rootCmd.AddCommand(dhcp.Cmd)
-	rootCmd.PersistentFlags().StringVarP(&pkg.Options.APIKey, "api-key", "k", "", "IBMCLOUD API Key(env name: IBMCLOUD_API_KEY)")
-	rootCmd.PersistentFlags().StringVar(&pkg.Options.Environment, "env", client.DefaultEnv, "IBM Cloud Environments, supported are: ["+strings.Join(client.ListEnvironments(), ", ")+"]")
+	rootCmd.AddCommand(configcmd.Cmd)
+	rootCmd.PersistentFlags().StringVar(&pkg.Options.Profile, "profile", "", "Profile in the config file(~/.pvsadm/config.yaml) providing the defaults for the options(env name: "+config.EnvProfile+")")
+	rootCmd.PersistentFlags().StringVarP(&pkg.Options.APIKey, "api-key", "k", "", "IBMCLOUD API Key(env name: IBMCLOUD_API_KEY), a pre-issued IAM access token can be passed via IBMCLOUD_IAM_TOKEN environment variable instead")
+	rootCmd.PersistentFlags().StringVar(&pkg.Options.TrustedProfile, "trusted-profile", "", "ID, CRN or name of the trusted profile to authenticate with the compute resource token, preferred over the API key(env name: IBMCLOUD_TRUSTED_PROFILE)")
+	rootCmd.PersistentFlags().StringVar(&pkg.Options.CRTokenFile, "cr-token-file", "", "Compute resource token file for the --trusted-profile(env name: IBMCLOUD_CR_TOKEN_FILE, default: ["+strings.Join(client.DefaultCRTokenFiles, ", ")+"])")
+	rootCmd.PersistentFlags().StringVar(&pkg.Options.Environment, "env", client.DefaultEnv, "IBM Cloud Environments, supported are: ["+strings.Join(client.ListEnvironments(), ", ")+"] and the environments in the config file")
 	rootCmd.PersistentFlags().BoolVar(&pkg.Options.Debug, "debug", false, "Enable PowerVS debug option(ATTENTION: dev only option, may print sensitive data from APIs)")
+	rootCmd.PersistentFlags().DurationVar(&pkg.Options.APITimeout, "api-timeout", pkg.TIMEOUT, "Timeout of the PowerVS API calls including the retries")
+	rootCmd.PersistentFlags().IntVar(&pkg.Options.MaxRetries, "max-retries", pkg.DefaultMaxRetries, "Maximum retries of the API calls failed with 429, 5xx or the network errors, 0 disables the retries. Retries are logged with -v=2")
+	rootCmd.PersistentFlags().BoolVar(&pkg.Options.Refresh, "refresh", false, "Refresh the cached PowerVS instances(cached under ~/.pvsadm/cache for "+client.InstanceCacheTTL.String()+")")
 	rootCmd.PersistentFlags().StringVar(&pkg.Options.AuditFile, "audit-file", "pvsadm.log", "Audit logs for the tool")
 	rootCmd.Flags().SortFlags = false
 	rootCmd.PersistentFlags().SortFlags = false
@@ -86,12 +141,21 @@ func init() {
 		}
 		origHelpFunc(cmd, args)
 	})
-
-	audit.Logger = audit.New(pkg.Options.AuditFile)
 }
 
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	ctx, cancel := utils.InterruptContext(context.Background())
+	err := rootCmd.ExecuteContext(ctx)
+	interrupted := utils.Interrupted(ctx)
+	cancel()
+	if interrupted {
+		if err != nil {
+			klog.Errorln(err)
+		}
+		klog.Errorln("interrupted")
+		os.Exit(utils.ExitInterrupted)
+	}
+	if err != nil {
 		klog.Errorln(err)
 		os.Exit(1)
 	}
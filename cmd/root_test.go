@@ -0,0 +1,108 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg/client"
+	"github.com/ppc64le-cloud/pvsadm/pkg/client/fake"
+	"github.com/ppc64le-cloud/pvsadm/pkg/config"
+)
+
+// fakeCloud serves the IBM Cloud endpoints by the fake server for the test. HOME is pointed at a temporary directory
+// to keep the instance cache and the config file of the user out of the test.
+func fakeCloud(t *testing.T) *fake.Server {
+	t.Helper()
+	env := map[string]string{"HOME": t.TempDir()}
+	for _, name := range []string{"IBMCLOUD_API_KEY", client.EnvIAMToken, "IBMCLOUD_TRUSTED_PROFILE", config.EnvProfile} {
+		env[name] = ""
+	}
+	for _, name := range client.EndpointEnvVars {
+		env[name] = ""
+	}
+	for name, value := range env {
+		old, ok := os.LookupEnv(name)
+		os.Setenv(name, value)
+		name := name
+		t.Cleanup(func() {
+			if ok {
+				os.Setenv(name, old)
+			} else {
+				os.Unsetenv(name)
+			}
+		})
+	}
+
+	s := fake.NewServer()
+	s.Install()
+	t.Cleanup(s.Close)
+	return s
+}
+
+// pvsadm runs the pvsadm with the args authenticated with the API key of the fake server, returns the standard output
+func pvsadm(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	resetFlags(rootCmd)
+	rootCmd.SetArgs(append(args, "--api-key", fake.DefaultAPIKey, "--audit-file", filepath.Join(os.Getenv("HOME"), "audit.log")))
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	out := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		_, _ = io.Copy(&buf, r)
+		out <- buf.String()
+	}()
+	err = rootCmd.ExecuteContext(context.Background())
+	os.Stdout = stdout
+	w.Close()
+	return <-out, err
+}
+
+// resetFlags sets the flags of the command and its subcommands back to the defaults, flags keep the values across
+// the executions otherwise. The slice flags set in an execution append to the defaults in the later executions.
+func resetFlags(c *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if v, ok := f.Value.(pflag.SliceValue); ok {
+			var def []string
+			if s := strings.Trim(f.DefValue, "[]"); s != "" {
+				def = strings.Split(s, ",")
+			}
+			_ = v.Replace(def)
+		} else if f.Value.String() != f.DefValue {
+			_ = f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	c.Flags().VisitAll(reset)
+	c.PersistentFlags().VisitAll(reset)
+	for _, sub := range c.Commands() {
+		resetFlags(sub)
+	}
+}
@@ -11,6 +11,7 @@ require (
 	github.com/IBM/platform-services-go-sdk v0.14.4
 	github.com/dgrijalva/jwt-go v3.2.0+incompatible
 	github.com/fsnotify/fsnotify v1.4.9
+	github.com/go-openapi/runtime v0.19.11
 	github.com/go-openapi/strfmt v0.19.10
 	github.com/klauspost/compress v1.11.1 // indirect
 	github.com/klauspost/pgzip v1.2.5
@@ -22,7 +23,8 @@ require (
 	github.com/sayotte/iscdhcp v0.0.0-20190926162140-d6be84ba9969
 	github.com/spf13/cobra v1.0.0
 	github.com/spf13/pflag v1.0.5
-	gopkg.in/yaml.v2 v2.3.0 // indirect
+	gopkg.in/yaml.v2 v2.3.0
 	k8s.io/apimachinery v0.20.0
+	k8s.io/client-go v0.20.0
 	k8s.io/klog/v2 v2.4.0
 )
@@ -25,7 +25,11 @@ import (
 
 var Logger *Audit
 
+// Log records the entry in the Logger, entries are dropped if the Logger isn't set e.g. when used as a library
 func Log(name, op, value string) {
+	if Logger == nil {
+		return
+	}
 	Logger.Log(name, op, value)
 }
 
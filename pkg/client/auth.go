@@ -0,0 +1,306 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IBM/go-sdk-core/v4/core"
+	"github.com/IBM/ibm-cos-sdk-go/aws/credentials"
+	"github.com/IBM/ibm-cos-sdk-go/aws/credentials/ibmiam/token"
+	"github.com/dgrijalva/jwt-go"
+	"k8s.io/klog/v2"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg"
+)
+
+const (
+	// EnvIAMToken is the environment variable holding a pre-issued IAM access token
+	EnvIAMToken = "IBMCLOUD_IAM_TOKEN"
+
+	// tokenRefreshMargin is the time before the expiry at which the tokens are refreshed
+	tokenRefreshMargin = 5 * time.Minute
+)
+
+// DefaultCRTokenFiles are the compute resource token files looked up for the trusted profile, in the order
+var DefaultCRTokenFiles = []string{
+	"/var/run/secrets/tokens/vault-token",
+	"/var/run/secrets/tokens/sa-token",
+	"/var/run/secrets/codeengine.cloud.ibm.com/compute-resource-token/token",
+}
+
+// Token is the IAM access token
+type Token struct {
+	// AccessToken is the token without the Bearer prefix
+	AccessToken string
+	Expiry      time.Time
+}
+
+// Authenticator issues the IAM access tokens
+type Authenticator interface {
+	// RequestToken returns a new IAM access token issued by the IAM endpoint
+	RequestToken(iamEndpoint string) (*Token, error)
+}
+
+// APIKeyAuthenticator authenticates with the IBM Cloud API key
+type APIKeyAuthenticator struct {
+	APIKey string
+}
+
+func (a *APIKeyAuthenticator) RequestToken(iamEndpoint string) (*Token, error) {
+	return requestToken(iamEndpoint, url.Values{
+		"grant_type": {"urn:ibm:params:oauth:grant-type:apikey"},
+		"apikey":     {a.APIKey},
+	})
+}
+
+// IAMTokenAuthenticator uses a pre-issued IAM access token, the token can't be refreshed
+type IAMTokenAuthenticator struct {
+	Token string
+}
+
+func (a *IAMTokenAuthenticator) RequestToken(_ string) (*Token, error) {
+	token := strings.TrimPrefix(a.Token, "Bearer ")
+	expiry, err := tokenExpiry(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IAM access token: %v", err)
+	}
+	if time.Now().After(expiry) {
+		return nil, fmt.Errorf("IAM access token expired at %s", expiry.Format(time.RFC3339))
+	}
+	return &Token{AccessToken: token, Expiry: expiry}, nil
+}
+
+// TrustedProfileAuthenticator authenticates as the trusted profile with the compute resource token of the IBM Cloud
+// compute the tool is running on
+type TrustedProfileAuthenticator struct {
+	// Profile is the ID, CRN or name of the trusted profile
+	Profile string
+	// CRTokenFile is the compute resource token file, DefaultCRTokenFiles are looked up if empty
+	CRTokenFile string
+}
+
+func (a *TrustedProfileAuthenticator) RequestToken(iamEndpoint string) (*Token, error) {
+	// compute resource tokens are rotated, hence read on every request
+	crToken, err := a.readCRToken()
+	if err != nil {
+		return nil, err
+	}
+	data := url.Values{
+		"grant_type": {"urn:ibm:params:oauth:grant-type:cr-token"},
+		"cr_token":   {crToken},
+	}
+	switch {
+	case strings.HasPrefix(a.Profile, "Profile-"):
+		data.Set("profile_id", a.Profile)
+	case strings.HasPrefix(a.Profile, "crn:"):
+		data.Set("profile_crn", a.Profile)
+	default:
+		data.Set("profile_name", a.Profile)
+	}
+	return requestToken(iamEndpoint, data)
+}
+
+func (a *TrustedProfileAuthenticator) readCRToken() (string, error) {
+	files := DefaultCRTokenFiles
+	if a.CRTokenFile != "" {
+		files = []string{a.CRTokenFile}
+	}
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if os.IsNotExist(err) && a.CRTokenFile == "" {
+			continue
+		} else if err != nil {
+			return "", fmt.Errorf("failed to read the compute resource token: %v", err)
+		}
+		return strings.TrimSpace(string(content)), nil
+	}
+	return "", fmt.Errorf("compute resource token not found in any of %v, use --cr-token-file", files)
+}
+
+// NewAuthenticator returns the authenticator for the --trusted-profile, API key or IBMCLOUD_IAM_TOKEN in that order
+func NewAuthenticator(apikey string) (Authenticator, error) {
+	if pkg.Options.TrustedProfile != "" {
+		klog.V(2).Infof("Authenticating as the trusted profile: %s", pkg.Options.TrustedProfile)
+		return &TrustedProfileAuthenticator{Profile: pkg.Options.TrustedProfile, CRTokenFile: pkg.Options.CRTokenFile}, nil
+	}
+	if apikey != "" {
+		return &APIKeyAuthenticator{APIKey: apikey}, nil
+	}
+	if token := os.Getenv(EnvIAMToken); token != "" {
+		klog.V(2).Infof("Using the IAM access token from %s environment variable", EnvIAMToken)
+		return &IAMTokenAuthenticator{Token: token}, nil
+	}
+	return nil, fmt.Errorf("no credentials found, pass the API key via --api-key or IBMCLOUD_API_KEY, an IAM access token via %s or the --trusted-profile", EnvIAMToken)
+}
+
+// TokenSource shares the IAM access token among the clients, the token is requested again before the expiry
+type TokenSource struct {
+	auth     Authenticator
+	endpoint string
+
+	mutex sync.Mutex
+	token *Token
+}
+
+func NewTokenSource(auth Authenticator, iamEndpoint string) *TokenSource {
+	return &TokenSource{auth: auth, endpoint: iamEndpoint}
+}
+
+// Token returns the IAM access token without the Bearer prefix
+func (s *TokenSource) Token() (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.token != nil && time.Until(s.token.Expiry) > tokenRefreshMargin {
+		return s.token.AccessToken, nil
+	}
+	klog.V(2).Infof("Requesting a new IAM access token")
+	token, err := s.auth.RequestToken(s.endpoint)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the IAM access token: %v", err)
+	}
+	s.token = token
+	return token.AccessToken, nil
+}
+
+// Refresh requests a new token if the stale token is still in use, the token refreshed by the other requests
+// meanwhile is returned otherwise
+func (s *TokenSource) Refresh(stale string) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.token != nil && s.token.AccessToken != stale {
+		return s.token.AccessToken, nil
+	}
+	klog.V(2).Infof("Refreshing the IAM access token")
+	token, err := s.auth.RequestToken(s.endpoint)
+	if err != nil {
+		return "", fmt.Errorf("failed to refresh the IAM access token: %v", err)
+	}
+	s.token = token
+	return token.AccessToken, nil
+}
+
+// CoreAuthenticator returns the authenticator for the IBM Cloud platform services clients sharing the token
+func (s *TokenSource) CoreAuthenticator() core.Authenticator {
+	return &coreAuthenticator{source: s}
+}
+
+type coreAuthenticator struct {
+	source *TokenSource
+}
+
+func (a *coreAuthenticator) AuthenticationType() string {
+	return core.AUTHTYPE_BEARER_TOKEN
+}
+
+func (a *coreAuthenticator) Authenticate(req *http.Request) error {
+	token, err := a.source.Token()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+func (a *coreAuthenticator) Validate() error {
+	return nil
+}
+
+// COSCredentials returns the credentials for the COS instance sharing the token
+func (s *TokenSource) COSCredentials(serviceInstanceID string) *credentials.Credentials {
+	return credentials.NewCredentials(&cosProvider{source: s, serviceInstanceID: serviceInstanceID})
+}
+
+type cosProvider struct {
+	source            *TokenSource
+	serviceInstanceID string
+}
+
+func (p *cosProvider) Retrieve() (credentials.Value, error) {
+	t, err := p.source.Token()
+	if err != nil {
+		return credentials.Value{}, err
+	}
+	return credentials.Value{
+		Token:             token.Token{AccessToken: t, TokenType: "Bearer"},
+		ProviderName:      "TokenSourceProviderIBM",
+		ProviderType:      "oauth",
+		ServiceInstanceID: p.serviceInstanceID,
+	}, nil
+}
+
+// IsExpired always retrieves the token from the TokenSource, which caches the token until the expiry
+func (p *cosProvider) IsExpired() bool {
+	return true
+}
+
+// requestToken requests the IAM access token from the IAM endpoint with the grant
+func requestToken(iamEndpoint string, data url.Values) (*Token, error) {
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(iamEndpoint, "/")+"/identity/token", strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var iamErr struct {
+			ErrorCode    string `json:"errorCode"`
+			ErrorMessage string `json:"errorMessage"`
+		}
+		if json.Unmarshal(body, &iamErr) == nil && iamErr.ErrorMessage != "" {
+			return nil, fmt.Errorf("%s: %s", iamErr.ErrorCode, iamErr.ErrorMessage)
+		}
+		return nil, fmt.Errorf("IAM token request failed with the status: %s", resp.Status)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		Expiration  int64  `json:"expiration"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("failed to parse the IAM token response: %v", err)
+	}
+	return &Token{AccessToken: token.AccessToken, Expiry: time.Unix(token.Expiration, 0)}, nil
+}
+
+// tokenExpiry returns the expiry of the JWT token, the signature isn't verified
+func tokenExpiry(token string) (time.Time, error) {
+	claims := jwt.MapClaims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(token, claims); err != nil {
+		return time.Time{}, err
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return time.Time{}, fmt.Errorf("token has no expiry")
+	}
+	return time.Unix(int64(exp), 0), nil
+}
@@ -0,0 +1,156 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// iamServer issues the tokens for the grants, counting the requests
+func iamServer(t *testing.T, requests *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		if r.URL.Path != "/identity/token" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		var token string
+		switch r.Form.Get("grant_type") {
+		case "urn:ibm:params:oauth:grant-type:apikey":
+			if r.Form.Get("apikey") != "valid" {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"errorCode":"BXNIM0415E","errorMessage":"Provided API key could not be found"}`)
+				return
+			}
+			token = "apikey-token"
+		case "urn:ibm:params:oauth:grant-type:cr-token":
+			token = "profile-token-" + r.Form.Get("cr_token") + "-" + r.Form.Get("profile_id") + r.Form.Get("profile_name")
+		}
+		fmt.Fprintf(w, `{"access_token":"%s","expiration":%d}`, token, time.Now().Add(time.Hour).Unix())
+	}))
+}
+
+func TestAuthenticators(t *testing.T) {
+	var requests int
+	server := iamServer(t, &requests)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "pvsadm-auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	crTokenFile := filepath.Join(dir, "token")
+	if err := ioutil.WriteFile(crTokenFile, []byte("cr\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		auth    Authenticator
+		want    string
+		wantErr bool
+	}{
+		{"api key", &APIKeyAuthenticator{APIKey: "valid"}, "apikey-token", false},
+		{"invalid api key", &APIKeyAuthenticator{APIKey: "invalid"}, "", true},
+		{"trusted profile id", &TrustedProfileAuthenticator{Profile: "Profile-1234", CRTokenFile: crTokenFile}, "profile-token-cr-Profile-1234", false},
+		{"trusted profile name", &TrustedProfileAuthenticator{Profile: "ci", CRTokenFile: crTokenFile}, "profile-token-cr-ci", false},
+		{"missing cr token", &TrustedProfileAuthenticator{Profile: "ci", CRTokenFile: filepath.Join(dir, "missing")}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.auth.RequestToken(server.URL)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RequestToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.AccessToken != tt.want {
+				t.Errorf("RequestToken() = %v, want %v", got.AccessToken, tt.want)
+			}
+		})
+	}
+}
+
+func TestIAMTokenAuthenticator(t *testing.T) {
+	sign := func(exp time.Time) string {
+		s, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"exp": exp.Unix()}).SignedString([]byte("key"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	valid := sign(time.Now().Add(time.Hour))
+	got, err := (&IAMTokenAuthenticator{Token: "Bearer " + valid}).RequestToken("")
+	if err != nil {
+		t.Fatalf("RequestToken() error = %v", err)
+	}
+	if got.AccessToken != valid || time.Until(got.Expiry) < 50*time.Minute {
+		t.Errorf("RequestToken() = %+v, want the token without the Bearer prefix", got)
+	}
+	if _, err := (&IAMTokenAuthenticator{Token: sign(time.Now().Add(-time.Minute))}).RequestToken(""); err == nil {
+		t.Errorf("RequestToken() expected error for the expired token")
+	}
+	if _, err := (&IAMTokenAuthenticator{Token: "invalid"}).RequestToken(""); err == nil {
+		t.Errorf("RequestToken() expected error for the invalid token")
+	}
+}
+
+// fakeAuthenticator issues the numbered tokens valid for the ttl
+type fakeAuthenticator struct {
+	issued int
+	ttl    time.Duration
+}
+
+func (a *fakeAuthenticator) RequestToken(_ string) (*Token, error) {
+	a.issued++
+	return &Token{AccessToken: fmt.Sprintf("token-%d", a.issued), Expiry: time.Now().Add(a.ttl)}, nil
+}
+
+func TestTokenSource(t *testing.T) {
+	auth := &fakeAuthenticator{ttl: time.Hour}
+	s := NewTokenSource(auth, "")
+	for i := 0; i < 3; i++ {
+		if got, _ := s.Token(); got != "token-1" {
+			t.Errorf("Token() = %v, want the cached token-1", got)
+		}
+	}
+
+	// tokens about to expire are requested again
+	auth.ttl = tokenRefreshMargin - time.Second
+	s = NewTokenSource(auth, "")
+	first, _ := s.Token()
+	second, _ := s.Token()
+	if first == second {
+		t.Errorf("Token() = %v, want a new token before the expiry", second)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if err := s.CoreAuthenticator().Authenticate(req); err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if got := req.Header.Get("Authorization"); got != fmt.Sprintf("Bearer token-%d", auth.issued) {
+		t.Errorf("Authorization = %v", got)
+	}
+}
@@ -15,8 +15,9 @@
 package client
 
 import (
+	"context"
 	"fmt"
-	gohttp "net/http"
+	"net/http"
 	"strings"
 
 	"github.com/IBM-Cloud/bluemix-go"
@@ -24,10 +25,8 @@ import (
 	"github.com/IBM-Cloud/bluemix-go/api/resource/resourcev1/controller"
 	"github.com/IBM-Cloud/bluemix-go/api/resource/resourcev1/management"
 	"github.com/IBM-Cloud/bluemix-go/api/resource/resourcev2/controllerv2"
-	"github.com/IBM-Cloud/bluemix-go/authentication"
-	"github.com/IBM-Cloud/bluemix-go/http"
+	bxhttp "github.com/IBM-Cloud/bluemix-go/http"
 	"github.com/IBM-Cloud/bluemix-go/models"
-	"github.com/IBM-Cloud/bluemix-go/rest"
 	bxsession "github.com/IBM-Cloud/bluemix-go/session"
 	"k8s.io/klog/v2"
 	//"golang.org/x/oauth2/jwt"
@@ -42,19 +41,12 @@ type Client struct {
 	ResourceServiceKey controller.ResourceServiceKeyRepository
 	ResCatalogAPI      catalog.ResourceCatalogRepository
 	ResGroupAPI        management.ResourceGroupRepository
-}
-
-func authenticateAPIKey(sess *bxsession.Session) error {
-	config := sess.Config
-	tokenRefresher, err := authentication.NewIAMAuthRepository(config, &rest.Client{
-		DefaultHeader: gohttp.Header{
-			"User-Agent": []string{http.UserAgent()},
-		},
-	})
-	if err != nil {
-		return err
-	}
-	return tokenRefresher.AuthenticateAPIKey(config.BluemixAPIKey)
+	// Environment holds the endpoints of the environment in use
+	Environment map[string]string
+	// TokenSource issues the IAM access tokens shared by all the clients
+	TokenSource *TokenSource
+	// Context bounds the API calls of the client and its PowerVS, COS and tagging clients
+	Context context.Context
 }
 
 type User struct {
@@ -102,24 +94,45 @@ func fetchUserDetails(sess *bxsession.Session, generation int) (*User, error) {
 	return &user, nil
 }
 
-func NewClient(apikey, ep string, debug bool) (*Client, error) {
-	c := &Client{}
-
-	bxSess, err := bxsession.New(&bluemix.Config{
-		BluemixAPIKey:         apikey,
-		TokenProviderEndpoint: &ep,
-		Debug:                 debug,
-	})
+// NewClient returns the client authenticated by the NewAuthenticator for the API key
+func NewClient(ctx context.Context, apikey, ep string, debug bool) (*Client, error) {
+	auth, err := NewAuthenticator(apikey)
 	if err != nil {
 		return nil, err
 	}
+	return NewClientWithAuthenticator(ctx, auth, ep, debug)
+}
 
-	c.Session = bxSess
+// NewClientWithAuthenticator returns the client authenticated by the authenticator against the IAM endpoint, API
+// calls are cancelled along with the ctx
+func NewClientWithAuthenticator(ctx context.Context, auth Authenticator, ep string, debug bool) (*Client, error) {
+	c := &Client{TokenSource: NewTokenSource(auth, ep), Context: ctx}
+	token, err := c.TokenSource.Token()
+	if err != nil {
+		return nil, err
+	}
 
-	err = authenticateAPIKey(bxSess)
+	config := &bluemix.Config{
+		IAMAccessToken: "Bearer " + token,
+		// bluemix-go requires a refresh token along with the access token, the tokens are refreshed by the TokenSource
+		IAMRefreshToken:       "not-used",
+		TokenProviderEndpoint: &ep,
+		Debug:                 debug,
+	}
+	if a, ok := auth.(*APIKeyAuthenticator); ok {
+		config.BluemixAPIKey = a.APIKey
+	}
+	bxSess, err := bxsession.New(config)
 	if err != nil {
 		return nil, err
 	}
+	config.HTTPClient = bxhttp.NewHTTPClient(config)
+	// over the http.DefaultTransport like the rest of the clients instead of the transport of the bluemix-go
+	config.HTTPClient.Transport = c.transport(bxhttp.NewTraceLoggingTransport(http.DefaultTransport))
+	// retried by the RetryTransport instead
+	config.MaxRetries = new(int)
+
+	c.Session = bxSess
 
 	c.User, err = fetchUserDetails(bxSess, 2)
 	if err != nil {
@@ -174,6 +187,24 @@ func (c *Client) ListServiceInstances(serviceType string) (map[string]string, er
 	return instances, nil
 }
 
+// ListServiceInstancesByType returns all the service instances of the particular service type
+func (c *Client) ListServiceInstancesByType(serviceType string) ([]models.ServiceInstanceV2, error) {
+	svcs, err := c.ResourceClientV2.ListInstances(controllerv2.ServiceInstanceQuery{
+		Type: "service_instance",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var instances []models.ServiceInstanceV2
+	for _, svc := range svcs {
+		if svc.Crn.ServiceName == serviceType {
+			instances = append(instances, svc)
+		}
+	}
+	return instances, nil
+}
+
 func (c *Client) CreateServiceInstance(instanceName, serviceName, servicePlan, resourceGrp, region string) (string, error) {
 	//Check Service using ServiceName and returns []models.Service
 	service, err := c.ResCatalogAPI.FindByName(serviceName, true)
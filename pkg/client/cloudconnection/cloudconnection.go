@@ -0,0 +1,88 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudconnection
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/IBM-Cloud/power-go-client/clients/instance"
+	"github.com/IBM-Cloud/power-go-client/ibmpisession"
+	"github.com/IBM-Cloud/power-go-client/power/models"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg"
+	"github.com/ppc64le-cloud/pvsadm/pkg/client/tagging"
+)
+
+type Client struct {
+	client     *instance.IBMPICloudConnectionClient
+	instanceID string
+	filter     *tagging.Filter
+}
+
+func NewClient(sess *ibmpisession.IBMPISession, powerinstanceid string) *Client {
+	return &Client{
+		client:     instance.NewIBMPICloudConnectionClient(sess, powerinstanceid),
+		instanceID: powerinstanceid,
+	}
+}
+
+// SetFilter sets the tag filter applied on top of the regular expression and age in GetAllPurgeable
+func (c *Client) SetFilter(f *tagging.Filter) {
+	c.filter = f
+}
+
+func (c *Client) Get(id string) (*models.CloudConnection, error) {
+	return c.client.Get(c.instanceID, id)
+}
+
+func (c *Client) GetAll() (*models.CloudConnections, error) {
+	return c.client.GetAll(c.instanceID, pkg.Options.APITimeout)
+}
+
+func (c *Client) Delete(id string) error {
+	_, err := c.client.Delete(c.instanceID, id)
+	return err
+}
+
+// GetAllPurgeable returns the cloud connections matching the regular expression(all if nil) and the creation date
+func (c *Client) GetAllPurgeable(before, since time.Duration, expr *regexp.Regexp) ([]*models.CloudConnection, error) {
+	connections, err := c.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the list of cloud connections: %v", err)
+	}
+
+	var candidates []*models.CloudConnection
+	for _, connection := range connections.CloudConnections {
+		if expr != nil && !expr.MatchString(*connection.Name) {
+			continue
+		}
+		var created time.Time
+		if connection.CreationDate != nil {
+			created = time.Time(*connection.CreationDate)
+		}
+		if !pkg.IsPurgeable(created, before, since) {
+			continue
+		}
+		if ok, err := c.filter.Matches("cloud-connection", *connection.CloudConnectionID); err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+		candidates = append(candidates, connection)
+	}
+	return candidates, nil
+}
@@ -1,54 +1,181 @@
 package client
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"os"
+	"sort"
+	"strings"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg"
 )
 
 const DefaultEnv = "prod"
 
 var EnvironmentNotFound = errors.New("environment not found")
 
+// Endpoints of the environment, PIEndpoint is the host name of the PowerVS API. PIEndpoint and COSEndpoint may carry
+// the {region} placeholder
+const (
+	TPEndpoint  = "TPEndpoint"
+	RCEndpoint  = "RCEndpoint"
+	PIEndpoint  = "PIEndpoint"
+	GTEndpoint  = "GTEndpoint"
+	COSEndpoint = "COSEndpoint"
+)
+
+// EndpointEnvVars are the environment variables overriding the endpoints of the environment in use
+var EndpointEnvVars = map[string]string{
+	TPEndpoint:  "PVSADM_IAM_ENDPOINT",
+	RCEndpoint:  "PVSADM_RC_ENDPOINT",
+	PIEndpoint:  "PVSADM_PI_ENDPOINT",
+	GTEndpoint:  "PVSADM_GT_ENDPOINT",
+	COSEndpoint: "PVSADM_COS_ENDPOINT",
+}
+
 var Environments = map[string]map[string]string{
 	"test": {
-		"TPEndpoint": "https://iam.test.cloud.ibm.com",
-		"RCEndpoint": "https://resource-controller.test.cloud.ibm.com",
-		"PIEndpoint": "power-iaas.test.cloud.ibm.com",
+		TPEndpoint:  "https://iam.test.cloud.ibm.com",
+		RCEndpoint:  "https://resource-controller.test.cloud.ibm.com",
+		PIEndpoint:  "power-iaas.test.cloud.ibm.com",
+		GTEndpoint:  "https://tags.global-search-tagging.test.cloud.ibm.com",
+		COSEndpoint: "https://s3.{region}.cloud-object-storage.appdomain.cloud",
 	},
 	"prod": {
-		"TPEndpoint": "https://iam.cloud.ibm.com",
-		"RCEndpoint": "https://resource-controller.cloud.ibm.com",
-		"PIEndpoint": "power-iaas.cloud.ibm.com",
+		TPEndpoint:  "https://iam.cloud.ibm.com",
+		RCEndpoint:  "https://resource-controller.cloud.ibm.com",
+		PIEndpoint:  "power-iaas.cloud.ibm.com",
+		GTEndpoint:  "https://tags.global-search-tagging.cloud.ibm.com",
+		COSEndpoint: "https://s3.{region}.cloud-object-storage.appdomain.cloud",
 	},
 }
 
+// ListEnvironments returns the names of the environments, sorted
 func ListEnvironments() (keys []string) {
 	for k := range Environments {
 		keys = append(keys, k)
 	}
+	sort.Strings(keys)
 	return
 }
 
+// GetEnvironment returns the endpoints of the environment along with the overrides from the EndpointEnvVars
 func GetEnvironment(env string) (map[string]string, error) {
 	if _, ok := Environments[env]; !ok {
 		return nil, EnvironmentNotFound
 	}
-	return Environments[env], nil
+	e := map[string]string{}
+	for k, v := range Environments[env] {
+		e[k] = v
+	}
+	for k, name := range EndpointEnvVars {
+		if v := os.Getenv(name); v != "" {
+			e[k] = v
+		}
+	}
+	return e, nil
+}
+
+// RegisterEnvironment adds or replaces the environment, all the endpoints are required
+func RegisterEnvironment(name string, endpoints map[string]string) error {
+	var missing []string
+	for k := range EndpointEnvVars {
+		if endpoints[k] == "" {
+			missing = append(missing, k)
+		}
+	}
+	if len(missing) != 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("environment: %s is missing the endpoints: [%s]", name, strings.Join(missing, ", "))
+	}
+	for k := range endpoints {
+		if _, ok := EndpointEnvVars[k]; !ok {
+			return fmt.Errorf("environment: %s has an unsupported endpoint: %s", name, k)
+		}
+	}
+	Environments[name] = endpoints
+	return nil
+}
+
+// RegionalEndpoint returns the endpoint for the region, the {region} placeholder is replaced with the region if
+// present or else the region is prefixed to the endpoint host
+func RegionalEndpoint(endpoint, region string) string {
+	if strings.Contains(endpoint, "{region}") {
+		return strings.ReplaceAll(endpoint, "{region}", region)
+	}
+	return fmt.Sprintf("%s.%s", region, endpoint)
 }
 
 func NewPVMClientWithEnv(c *Client, instanceID, instanceName, env string) (*PVMClient, error) {
+	if pkg.Options.MultipleInstances() {
+		return nil, fmt.Errorf("multiple instances are not supported for the command, use a single --instance-id or --instance-name")
+	}
+	return newPVMClientWithEnv(c, instanceID, instanceName, env)
+}
+
+func newPVMClientWithEnv(c *Client, instanceID, instanceName, env string) (*PVMClient, error) {
+	ins, err := c.LookupInstance(instanceID, instanceName)
+	if err != nil {
+		return nil, err
+	}
+	return newPVMClientForInstance(c, ins, env)
+}
+
+// newPVMClientForInstance returns the client for the PowerVS instance already looked up, the --selector is applied
+func newPVMClientForInstance(c *Client, ins *Instance, env string) (*PVMClient, error) {
 	e, err := GetEnvironment(env)
 	if err != nil {
 		return nil, err
 	}
-	return NewPVMClient(c, instanceID, instanceName, e["PIEndpoint"])
+	pvmclient, err := NewPVMClientForInstanceWithEndpoints(c, ins, e)
+	if err != nil {
+		return nil, err
+	}
+	if pkg.Options.Selector != "" {
+		if err := pvmclient.setSelector(pkg.Options.Selector); err != nil {
+			return nil, err
+		}
+	}
+	return pvmclient, nil
 }
 
-func NewClientWithEnv(apikey, env string, debug bool) (*Client, error) {
+// NewPVMClientWithEndpoints returns the client for the PowerVS instance along with the tagging client for the
+// endpoints, e.g. the Environment of the client. Unlike the NewPVMClientWithEnv, the --selector isn't applied.
+func NewPVMClientWithEndpoints(c *Client, instanceID, instanceName string, endpoints map[string]string) (*PVMClient, error) {
+	ins, err := c.LookupInstance(instanceID, instanceName)
+	if err != nil {
+		return nil, err
+	}
+	return NewPVMClientForInstanceWithEndpoints(c, ins, endpoints)
+}
+
+// NewPVMClientForInstanceWithEndpoints is the NewPVMClientWithEndpoints for the PowerVS instance already looked up
+func NewPVMClientForInstanceWithEndpoints(c *Client, ins *Instance, endpoints map[string]string) (*PVMClient, error) {
+	pvmclient, err := NewPVMClientForInstance(c, ins, endpoints[PIEndpoint])
+	if err != nil {
+		return nil, err
+	}
+	if err := pvmclient.setTagger(c, endpoints[GTEndpoint]); err != nil {
+		return nil, err
+	}
+	return pvmclient, nil
+}
+
+func NewClientWithEnv(ctx context.Context, apikey, env string, debug bool) (*Client, error) {
 	e, err := GetEnvironment(env)
 	if err != nil {
 		return nil, err
 	}
-	os.Setenv("IBMCLOUD_RESOURCE_CONTROLLER_API_ENDPOINT", e["RCEndpoint"])
-	return NewClient(apikey, e["TPEndpoint"], debug)
+	os.Setenv("IBMCLOUD_RESOURCE_CONTROLLER_API_ENDPOINT", e[RCEndpoint])
+	c, err := NewClient(ctx, apikey, e[TPEndpoint], debug)
+	if err != nil {
+		return nil, err
+	}
+	c.Environment = e
+	// guards against using the API key of another account with the profile
+	if pkg.Options.Account != "" && c.User.Account != pkg.Options.Account {
+		return nil, fmt.Errorf("API key belongs to the account: %s, not the account: %s of the profile", c.User.Account, pkg.Options.Account)
+	}
+	return c, nil
 }
@@ -1,6 +1,7 @@
 package client
 
 import (
+	"os"
 	"reflect"
 	"testing"
 )
@@ -12,7 +13,7 @@ func TestListEnvironments(t *testing.T) {
 	}{
 		{
 			"valid environments",
-			[]string{"test", "prod"},
+			[]string{"prod", "test"},
 		},
 	}
 	for _, tt := range tests {
@@ -65,3 +66,63 @@ func TestGetEnvironment(t *testing.T) {
 		})
 	}
 }
+
+func TestGetEnvironment_overrides(t *testing.T) {
+	os.Setenv(EndpointEnvVars[TPEndpoint], "http://127.0.0.1:8080")
+	defer os.Unsetenv(EndpointEnvVars[TPEndpoint])
+
+	got, err := GetEnvironment("prod")
+	if err != nil {
+		t.Fatalf("GetEnvironment() error = %v", err)
+	}
+	if got[TPEndpoint] != "http://127.0.0.1:8080" || got[RCEndpoint] != Environments["prod"][RCEndpoint] {
+		t.Errorf("GetEnvironment() = %v, want the overridden %s", got, TPEndpoint)
+	}
+	if Environments["prod"][TPEndpoint] == got[TPEndpoint] {
+		t.Errorf("GetEnvironment() modified the environment")
+	}
+}
+
+func TestRegisterEnvironment(t *testing.T) {
+	private := map[string]string{
+		TPEndpoint:  "https://private.iam.cloud.ibm.com",
+		RCEndpoint:  "https://private.resource-controller.cloud.ibm.com",
+		PIEndpoint:  "private.{region}.power-iaas.cloud.ibm.com",
+		GTEndpoint:  "https://tags.global-search-tagging.cloud.ibm.com",
+		COSEndpoint: "https://s3.private.{region}.cloud-object-storage.appdomain.cloud",
+	}
+	defer delete(Environments, "prod-private")
+	if err := RegisterEnvironment("prod-private", private); err != nil {
+		t.Fatalf("RegisterEnvironment() error = %v", err)
+	}
+	if got, _ := GetEnvironment("prod-private"); !reflect.DeepEqual(got, private) {
+		t.Errorf("GetEnvironment() = %v, want %v", got, private)
+	}
+
+	if err := RegisterEnvironment("partial", map[string]string{TPEndpoint: "https://iam.cloud.ibm.com"}); err == nil {
+		t.Errorf("RegisterEnvironment() expected error for the missing endpoints")
+	}
+	unknown := map[string]string{"XXEndpoint": "https://example.com"}
+	for k, v := range private {
+		unknown[k] = v
+	}
+	if err := RegisterEnvironment("unknown", unknown); err == nil {
+		t.Errorf("RegisterEnvironment() expected error for the unsupported endpoint")
+	}
+}
+
+func TestRegionalEndpoint(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		want     string
+	}{
+		{"power-iaas.cloud.ibm.com", "us-south.power-iaas.cloud.ibm.com"},
+		{"private.{region}.power-iaas.cloud.ibm.com", "private.us-south.power-iaas.cloud.ibm.com"},
+		{"https://s3.{region}.cloud-object-storage.appdomain.cloud", "https://s3.us-south.cloud-object-storage.appdomain.cloud"},
+	}
+	for _, tt := range tests {
+		if got := RegionalEndpoint(tt.endpoint, "us-south"); got != tt.want {
+			t.Errorf("RegionalEndpoint() = %v, want %v", got, tt.want)
+		}
+	}
+}
@@ -38,6 +38,6 @@ func NewClient(sess *ibmpisession.IBMPISession, powerinstanceid string) *Client
 }
 
 func (c *Client) GetPcloudEventsGetsince(since time.Duration) (*p_cloud_events.PcloudEventsGetqueryOK, error) {
-	params := p_cloud_events.NewPcloudEventsGetqueryParamsWithTimeout(pkg.TIMEOUT).WithCloudInstanceID(c.instanceID).WithFromTime(core.StringPtr(time.Now().UTC().Add(-since).Format(time.RFC3339)))
+	params := p_cloud_events.NewPcloudEventsGetqueryParamsWithTimeout(pkg.Options.APITimeout).WithCloudInstanceID(c.instanceID).WithFromTime(core.StringPtr(time.Now().UTC().Add(-since).Format(time.RFC3339)))
 	return c.client.PcloudEventsGetquery(params, ibmpisession.NewAuth(c.session, c.instanceID))
 }
@@ -0,0 +1,577 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fake
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// COSInstance is a Cloud Object Storage service instance, owning the buckets created with it
+type COSInstance struct {
+	ID   string
+	Name string
+	CRN  string
+
+	server  *Server
+	created time.Time
+}
+
+// Object is an object stored in a bucket
+type Object struct {
+	Data         []byte
+	ETag         string
+	ContentType  string
+	Metadata     map[string]string
+	LastModified time.Time
+}
+
+type bucket struct {
+	name     string
+	location string
+	// owner is the ID of the COS instance
+	owner   string
+	created time.Time
+	objects map[string]*Object
+	uploads map[string]*upload
+}
+
+// upload is an in-progress multipart upload
+type upload struct {
+	id, key     string
+	initiated   time.Time
+	contentType string
+	metadata    map[string]string
+	parts       map[int]*Object
+}
+
+// AddCOSInstance adds a Cloud Object Storage instance
+func (s *Server) AddCOSInstance(name string) *COSInstance {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	id := s.newID()
+	cos := &COSInstance{
+		ID:      id,
+		Name:    name,
+		CRN:     fmt.Sprintf("crn:v1:bluemix:public:cloud-object-storage:global:a/%s:%s::", s.Account, id),
+		server:  s,
+		created: time.Now(),
+	}
+	s.cos = append(s.cos, cos)
+	return cos
+}
+
+// AddBucket adds a bucket with the location constraint, e.g. us-south-standard
+func (cos *COSInstance) AddBucket(name, location string) {
+	cos.server.mutex.Lock()
+	defer cos.server.mutex.Unlock()
+	cos.server.buckets[name] = newBucket(name, location, cos.ID)
+}
+
+// PutObject stores the object in the bucket
+func (cos *COSInstance) PutObject(bucketName, key string, data []byte) {
+	cos.PutObjectWithMetadata(bucketName, key, data, nil)
+}
+
+// PutObjectWithMetadata stores the object in the bucket along with the metadata, keyed without the X-Amz-Meta- prefix
+// in the canonical header form, e.g. Sha256
+func (cos *COSInstance) PutObjectWithMetadata(bucketName, key string, data []byte, metadata map[string]string) {
+	cos.server.mutex.Lock()
+	defer cos.server.mutex.Unlock()
+	b, ok := cos.server.buckets[bucketName]
+	if !ok {
+		panic("fake: bucket not found: " + bucketName)
+	}
+	b.objects[key] = newObject(data, "", metadata)
+}
+
+// Object returns the object in the bucket, nil if not found
+func (s *Server) Object(bucketName, key string) *Object {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if b, ok := s.buckets[bucketName]; ok {
+		if o, ok := b.objects[key]; ok {
+			c := *o
+			return &c
+		}
+	}
+	return nil
+}
+
+// Uploads returns the keys of the in-progress multipart uploads of the bucket
+func (s *Server) Uploads(bucketName string) []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var keys []string
+	if b, ok := s.buckets[bucketName]; ok {
+		for _, u := range b.uploads {
+			keys = append(keys, u.key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *Server) findBucket(name string) *bucket {
+	return s.buckets[name]
+}
+
+func newBucket(name, location, owner string) *bucket {
+	return &bucket{
+		name:     name,
+		location: location,
+		owner:    owner,
+		created:  time.Now(),
+		objects:  map[string]*Object{},
+		uploads:  map[string]*upload{},
+	}
+}
+
+func newObject(data []byte, contentType string, metadata map[string]string) *Object {
+	sum := md5.Sum(data)
+	if contentType == "" {
+		contentType = "binary/octet-stream"
+	}
+	return &Object{
+		Data:         data,
+		ETag:         `"` + hex.EncodeToString(sum[:]) + `"`,
+		ContentType:  contentType,
+		Metadata:     metadata,
+		LastModified: time.Now().UTC(),
+	}
+}
+
+// serveCOS serves the path style S3 API, the region is taken from the host name s3.{region}.<domain>
+func (s *Server) serveCOS(w http.ResponseWriter, r *http.Request, host string) {
+	region := strings.SplitN(strings.TrimPrefix(host, "s3."), ".", 2)[0]
+	bucketName, key := splitPath(r.URL.Path)
+	q := r.URL.Query()
+
+	var body []byte
+	if r.Method == http.MethodPut || r.Method == http.MethodPost {
+		var err error
+		if body, err = ioutil.ReadAll(r.Body); err != nil {
+			writeS3Error(w, r, http.StatusBadRequest, "IncompleteBody", err.Error())
+			return
+		}
+	}
+	// the content is verified against the Content-MD5 like the COS does
+	if md5sum := r.Header.Get("Content-MD5"); md5sum != "" {
+		sum := md5.Sum(body)
+		if md5sum != base64.StdEncoding.EncodeToString(sum[:]) {
+			writeS3Error(w, r, http.StatusBadRequest, "BadDigest", "The Content-MD5 you specified did not match what we received.")
+			return
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if bucketName == "" {
+		if r.Method != http.MethodGet {
+			writeS3Error(w, r, http.StatusMethodNotAllowed, "MethodNotAllowed", "method not allowed")
+			return
+		}
+		s.listBuckets(w, r.Header.Get("ibm-service-instance-id"))
+		return
+	}
+
+	b := s.buckets[bucketName]
+	if key == "" && r.Method == http.MethodPut {
+		s.createBucket(w, r, bucketName, region, body)
+		return
+	}
+	if b == nil {
+		writeS3Error(w, r, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist.")
+		return
+	}
+
+	if key == "" {
+		switch {
+		case r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodDelete:
+			if len(b.objects) != 0 {
+				writeS3Error(w, r, http.StatusConflict, "BucketNotEmpty", "The bucket you tried to delete is not empty.")
+				return
+			}
+			delete(s.buckets, bucketName)
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodGet && has(q, "location"):
+			writeXML(w, http.StatusOK, struct {
+				XMLName  xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ LocationConstraint"`
+				Location string   `xml:",chardata"`
+			}{Location: b.location})
+		case r.Method == http.MethodGet && has(q, "uploads"):
+			b.listUploads(w)
+		case r.Method == http.MethodGet:
+			b.listObjects(w, q)
+		default:
+			writeS3Error(w, r, http.StatusMethodNotAllowed, "MethodNotAllowed", "method not allowed")
+		}
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPost && has(q, "uploads"):
+		b.createUpload(w, r, s.newID(), key)
+	case has(q, "uploadId"):
+		u, ok := b.uploads[q.Get("uploadId")]
+		if !ok || u.key != key {
+			writeS3Error(w, r, http.StatusNotFound, "NoSuchUpload", "The specified multipart upload does not exist.")
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			n, err := strconv.Atoi(q.Get("partNumber"))
+			if err != nil || n < 1 || n > 10000 {
+				writeS3Error(w, r, http.StatusBadRequest, "InvalidArgument", "Part number must be an integer between 1 and 10000.")
+				return
+			}
+			part := newObject(body, "", nil)
+			u.parts[n] = part
+			w.Header().Set("ETag", part.ETag)
+			w.WriteHeader(http.StatusOK)
+		case http.MethodPost:
+			b.completeUpload(w, r, u, body)
+		case http.MethodDelete:
+			delete(b.uploads, u.id)
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodGet:
+			u.listParts(w, b.name)
+		default:
+			writeS3Error(w, r, http.StatusMethodNotAllowed, "MethodNotAllowed", "method not allowed")
+		}
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		s.copyObject(w, r, b, key)
+	case r.Method == http.MethodPut:
+		b.objects[key] = newObject(body, r.Header.Get("Content-Type"), metadata(r.Header))
+		w.Header().Set("ETag", b.objects[key].ETag)
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		o, ok := b.objects[key]
+		if !ok {
+			writeS3Error(w, r, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+			return
+		}
+		for k, v := range o.Metadata {
+			w.Header().Set("X-Amz-Meta-"+k, v)
+		}
+		w.Header().Set("Content-Type", o.ContentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(o.Data)))
+		w.Header().Set("ETag", o.ETag)
+		w.Header().Set("Last-Modified", o.LastModified.Format(http.TimeFormat))
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(o.Data)
+		}
+	case r.Method == http.MethodDelete:
+		delete(b.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeS3Error(w, r, http.StatusMethodNotAllowed, "MethodNotAllowed", "method not allowed")
+	}
+}
+
+// listBuckets lists the buckets owned by the COS instance
+func (s *Server) listBuckets(w http.ResponseWriter, owner string) {
+	type xmlBucket struct {
+		Name         string `xml:"Name"`
+		CreationDate string `xml:"CreationDate"`
+	}
+	result := struct {
+		XMLName xml.Name    `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListAllMyBucketsResult"`
+		OwnerID string      `xml:"Owner>ID"`
+		Buckets []xmlBucket `xml:"Buckets>Bucket"`
+	}{OwnerID: owner}
+	var names []string
+	for name, b := range s.buckets {
+		if b.owner == owner {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		result.Buckets = append(result.Buckets, xmlBucket{Name: name, CreationDate: timestamp(s.buckets[name].created)})
+	}
+	writeXML(w, http.StatusOK, result)
+}
+
+// createBucket creates the bucket in the COS instance of the request, location constraint defaults to the standard
+// storage class of the region
+func (s *Server) createBucket(w http.ResponseWriter, r *http.Request, name, region string, body []byte) {
+	owner := r.Header.Get("ibm-service-instance-id")
+	if owner == "" {
+		writeS3Error(w, r, http.StatusBadRequest, "InvalidArgument", "ibm-service-instance-id header is required.")
+		return
+	}
+	if _, ok := s.buckets[name]; ok {
+		writeS3Error(w, r, http.StatusConflict, "BucketAlreadyExists", "The requested bucket name is not available.")
+		return
+	}
+	location := region + "-standard"
+	if len(bytes.TrimSpace(body)) != 0 {
+		var config struct {
+			LocationConstraint string `xml:"LocationConstraint"`
+		}
+		if err := xml.Unmarshal(body, &config); err != nil {
+			writeS3Error(w, r, http.StatusBadRequest, "MalformedXML", err.Error())
+			return
+		}
+		if config.LocationConstraint != "" {
+			location = config.LocationConstraint
+		}
+	}
+	s.buckets[name] = newBucket(name, location, owner)
+	w.Header().Set("Location", "/"+name)
+	w.WriteHeader(http.StatusOK)
+}
+
+// copyObject copies the object named by the X-Amz-Copy-Source header, bucket/key, into the bucket
+func (s *Server) copyObject(w http.ResponseWriter, r *http.Request, b *bucket, key string) {
+	source, err := url.PathUnescape(r.Header.Get("X-Amz-Copy-Source"))
+	if err != nil {
+		writeS3Error(w, r, http.StatusBadRequest, "InvalidArgument", "invalid copy source: "+err.Error())
+		return
+	}
+	srcBucket, srcKey := splitPath(source)
+	src, ok := s.buckets[srcBucket]
+	if !ok {
+		writeS3Error(w, r, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist.")
+		return
+	}
+	o, ok := src.objects[srcKey]
+	if !ok {
+		writeS3Error(w, r, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+		return
+	}
+	copied := *o
+	copied.LastModified = time.Now().UTC()
+	if r.Header.Get("X-Amz-Metadata-Directive") == "REPLACE" {
+		copied.ContentType, copied.Metadata = r.Header.Get("Content-Type"), metadata(r.Header)
+	}
+	b.objects[key] = &copied
+	writeXML(w, http.StatusOK, struct {
+		XMLName      xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ CopyObjectResult"`
+		LastModified string   `xml:"LastModified"`
+		ETag         string   `xml:"ETag"`
+	}{LastModified: timestamp(copied.LastModified), ETag: copied.ETag})
+}
+
+// listObjects lists the objects of the bucket sorted by the key, supports the prefix, marker and max-keys
+func (b *bucket) listObjects(w http.ResponseWriter, q url.Values) {
+	maxKeys := 1000
+	if v, err := strconv.Atoi(q.Get("max-keys")); err == nil && v >= 0 && v < maxKeys {
+		maxKeys = v
+	}
+	type content struct {
+		Key          string `xml:"Key"`
+		LastModified string `xml:"LastModified"`
+		ETag         string `xml:"ETag"`
+		Size         int    `xml:"Size"`
+		StorageClass string `xml:"StorageClass"`
+	}
+	result := struct {
+		XMLName     xml.Name  `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListBucketResult"`
+		Name        string    `xml:"Name"`
+		Prefix      string    `xml:"Prefix"`
+		Marker      string    `xml:"Marker"`
+		NextMarker  string    `xml:"NextMarker,omitempty"`
+		MaxKeys     int       `xml:"MaxKeys"`
+		IsTruncated bool      `xml:"IsTruncated"`
+		Contents    []content `xml:"Contents"`
+	}{Name: b.name, Prefix: q.Get("prefix"), Marker: q.Get("marker"), MaxKeys: maxKeys}
+
+	var keys []string
+	for key := range b.objects {
+		if strings.HasPrefix(key, result.Prefix) && key > result.Marker {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	if len(keys) > maxKeys {
+		keys, result.IsTruncated = keys[:maxKeys], true
+		result.NextMarker = keys[len(keys)-1]
+	}
+	for _, key := range keys {
+		o := b.objects[key]
+		result.Contents = append(result.Contents, content{Key: key, LastModified: timestamp(o.LastModified), ETag: o.ETag, Size: len(o.Data), StorageClass: "STANDARD"})
+	}
+	writeXML(w, http.StatusOK, result)
+}
+
+func (b *bucket) createUpload(w http.ResponseWriter, r *http.Request, id, key string) {
+	b.uploads[id] = &upload{
+		id:          id,
+		key:         key,
+		initiated:   time.Now().UTC(),
+		contentType: r.Header.Get("Content-Type"),
+		metadata:    metadata(r.Header),
+		parts:       map[int]*Object{},
+	}
+	writeXML(w, http.StatusOK, struct {
+		XMLName  xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ InitiateMultipartUploadResult"`
+		Bucket   string   `xml:"Bucket"`
+		Key      string   `xml:"Key"`
+		UploadID string   `xml:"UploadId"`
+	}{Bucket: b.name, Key: key, UploadID: id})
+}
+
+// completeUpload assembles the object from the listed parts, the ETag is the S3 style multipart ETag
+func (b *bucket) completeUpload(w http.ResponseWriter, r *http.Request, u *upload, body []byte) {
+	var req struct {
+		Parts []struct {
+			PartNumber int    `xml:"PartNumber"`
+			ETag       string `xml:"ETag"`
+		} `xml:"Part"`
+	}
+	if err := xml.Unmarshal(body, &req); err != nil || len(req.Parts) == 0 {
+		writeS3Error(w, r, http.StatusBadRequest, "MalformedXML", "The XML you provided was not well-formed.")
+		return
+	}
+	var data, sums []byte
+	for i, p := range req.Parts {
+		part, ok := u.parts[p.PartNumber]
+		if !ok || part.ETag != p.ETag {
+			writeS3Error(w, r, http.StatusBadRequest, "InvalidPart", fmt.Sprintf("part %d not found or the ETag doesn't match", p.PartNumber))
+			return
+		}
+		if i > 0 && p.PartNumber <= req.Parts[i-1].PartNumber {
+			writeS3Error(w, r, http.StatusBadRequest, "InvalidPartOrder", "The list of parts was not in ascending order.")
+			return
+		}
+		data = append(data, part.Data...)
+		sum, _ := hex.DecodeString(strings.Trim(part.ETag, `"`))
+		sums = append(sums, sum...)
+	}
+	o := newObject(data, u.contentType, u.metadata)
+	sum := md5.Sum(sums)
+	o.ETag = fmt.Sprintf(`"%s-%d"`, hex.EncodeToString(sum[:]), len(req.Parts))
+	b.objects[u.key] = o
+	delete(b.uploads, u.id)
+	writeXML(w, http.StatusOK, struct {
+		XMLName  xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ CompleteMultipartUploadResult"`
+		Location string   `xml:"Location"`
+		Bucket   string   `xml:"Bucket"`
+		Key      string   `xml:"Key"`
+		ETag     string   `xml:"ETag"`
+	}{Location: "/" + b.name + "/" + u.key, Bucket: b.name, Key: u.key, ETag: o.ETag})
+}
+
+func (u *upload) listParts(w http.ResponseWriter, bucketName string) {
+	type xmlPart struct {
+		PartNumber   int    `xml:"PartNumber"`
+		LastModified string `xml:"LastModified"`
+		ETag         string `xml:"ETag"`
+		Size         int    `xml:"Size"`
+	}
+	result := struct {
+		XMLName     xml.Name  `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListPartsResult"`
+		Bucket      string    `xml:"Bucket"`
+		Key         string    `xml:"Key"`
+		UploadID    string    `xml:"UploadId"`
+		IsTruncated bool      `xml:"IsTruncated"`
+		Parts       []xmlPart `xml:"Part"`
+	}{Bucket: bucketName, Key: u.key, UploadID: u.id}
+	var numbers []int
+	for n := range u.parts {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+	for _, n := range numbers {
+		p := u.parts[n]
+		result.Parts = append(result.Parts, xmlPart{PartNumber: n, LastModified: timestamp(p.LastModified), ETag: p.ETag, Size: len(p.Data)})
+	}
+	writeXML(w, http.StatusOK, result)
+}
+
+func (b *bucket) listUploads(w http.ResponseWriter) {
+	type xmlUpload struct {
+		Key       string `xml:"Key"`
+		UploadID  string `xml:"UploadId"`
+		Initiated string `xml:"Initiated"`
+	}
+	result := struct {
+		XMLName     xml.Name    `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListMultipartUploadsResult"`
+		Bucket      string      `xml:"Bucket"`
+		IsTruncated bool        `xml:"IsTruncated"`
+		Uploads     []xmlUpload `xml:"Upload"`
+	}{Bucket: b.name}
+	for _, u := range b.uploads {
+		result.Uploads = append(result.Uploads, xmlUpload{Key: u.key, UploadID: u.id, Initiated: timestamp(u.initiated)})
+	}
+	sort.Slice(result.Uploads, func(i, j int) bool { return result.Uploads[i].Key < result.Uploads[j].Key })
+	writeXML(w, http.StatusOK, result)
+}
+
+// splitPath splits the path into the bucket and the key
+func splitPath(path string) (string, string) {
+	parts := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+// metadata returns the user metadata of the X-Amz-Meta-* headers
+func metadata(h http.Header) map[string]string {
+	m := map[string]string{}
+	for k := range h {
+		if strings.HasPrefix(k, "X-Amz-Meta-") {
+			m[strings.TrimPrefix(k, "X-Amz-Meta-")] = h.Get(k)
+		}
+	}
+	return m
+}
+
+func has(q url.Values, key string) bool {
+	_, ok := q[key]
+	return ok
+}
+
+func timestamp(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+func writeXML(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(xml.Header))
+	_ = xml.NewEncoder(w).Encode(v)
+}
+
+// writeS3Error writes the S3 error, without the body for the HEAD requests
+func writeS3Error(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	if r.Method == http.MethodHead {
+		w.WriteHeader(status)
+		return
+	}
+	writeXML(w, status, struct {
+		XMLName  xml.Name `xml:"Error"`
+		Code     string   `xml:"Code"`
+		Message  string   `xml:"Message"`
+		Resource string   `xml:"Resource"`
+	}{Code: code, Message: message, Resource: r.URL.Path})
+}
@@ -0,0 +1,154 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// serviceKey is the service credential of a COS instance with the HMAC keys
+type serviceKey struct {
+	id, name             string
+	crn, sourceCRN       string
+	accessKey, secretKey string
+	created              time.Time
+}
+
+func (k *serviceKey) resource() map[string]interface{} {
+	return map[string]interface{}{
+		"id":         k.crn,
+		"guid":       k.id,
+		"name":       k.name,
+		"crn":        k.crn,
+		"source_crn": k.sourceCRN,
+		"state":      "active",
+		"created_at": k.created.UTC().Format(time.RFC3339),
+		"credentials": map[string]interface{}{
+			"cos_hmac_keys": map[string]interface{}{
+				"access_key_id":     k.accessKey,
+				"secret_access_key": k.secretKey,
+			},
+		},
+	}
+}
+
+// resourceInstance returns the resource controller view of the service instance
+func resourceInstance(guid, name, crn, region string, created time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"id":         crn,
+		"guid":       guid,
+		"name":       name,
+		"crn":        crn,
+		"region_id":  region,
+		"type":       "service_instance",
+		"state":      "active",
+		"created_at": created.UTC().Format(time.RFC3339),
+	}
+}
+
+// serveResourceController serves the resource instances and the resource keys of the resource controller
+func (s *Server) serveResourceController(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/v2/resource_instances":
+		resources := []interface{}{}
+		for _, ws := range s.workspaces {
+			resources = append(resources, resourceInstance(ws.ID, ws.Name, ws.CRN, ws.Zone, ws.created))
+		}
+		for _, cos := range s.cos {
+			resources = append(resources, resourceInstance(cos.ID, cos.Name, cos.CRN, "global", cos.created))
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"rows_count": len(resources), "next_url": nil, "resources": resources})
+	case r.Method == http.MethodGet && r.URL.Path == "/v1/resource_keys":
+		resources := []interface{}{}
+		for _, k := range s.keys {
+			resources = append(resources, k.resource())
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"rows_count": len(resources), "next_url": nil, "resources": resources})
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/resource_keys":
+		var req struct {
+			Name      string `json:"name"`
+			SourceCRN string `json:"source_crn"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		var cos *COSInstance
+		for _, c := range s.cos {
+			if c.CRN == req.SourceCRN {
+				cos = c
+			}
+		}
+		if cos == nil {
+			writeError(w, http.StatusBadRequest, "source_crn is not a Cloud Object Storage instance: "+req.SourceCRN)
+			return
+		}
+		id := s.newID()
+		k := &serviceKey{
+			id:        id,
+			name:      req.Name,
+			crn:       fmt.Sprintf("%s:resource-key:%s", strings.TrimSuffix(cos.CRN, "::"), id),
+			sourceCRN: cos.CRN,
+			accessKey: "access-" + id,
+			secretKey: "secret-" + id,
+			created:   time.Now(),
+		}
+		s.keys = append(s.keys, k)
+		writeJSON(w, http.StatusCreated, k.resource())
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// attachedTags returns the tags attached to the resource, all the distinct tags attached in the account if crn is empty
+func (s *Server) attachedTags(crn string) []string {
+	if crn != "" {
+		return s.tags[crn]
+	}
+	var tags []string
+	seen := map[string]bool{}
+	for _, list := range s.tags {
+		for _, tag := range list {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+// serveTags serves the user tags attached to the resources, the pagination is ignored
+func (s *Server) serveTags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet || r.URL.Path != "/v3/tags" {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	items := []interface{}{}
+	if r.URL.Query().Get("offset") == "" || r.URL.Query().Get("offset") == "0" {
+		for _, tag := range s.attachedTags(r.URL.Query().Get("attached_to")) {
+			items = append(items, map[string]string{"name": tag})
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"total_count": len(items), "offset": 0, "limit": len(items), "items": items})
+}
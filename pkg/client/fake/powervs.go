@@ -0,0 +1,598 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/IBM-Cloud/power-go-client/power/models"
+	"github.com/go-openapi/strfmt"
+)
+
+// PowerVS image states, the imported images are queued and turn active once fetched
+const (
+	ImageStateQueued = "queued"
+	ImageStateActive = "active"
+)
+
+// Workspace is a PowerVS service instance with its resources keyed by the ID
+type Workspace struct {
+	ID   string
+	Name string
+	Zone string
+	CRN  string
+
+	server    *Server
+	created   time.Time
+	instances *collection
+	volumes   *collection
+	images    *collection
+	networks  *collection
+	// pools holds the shared processor pools along with the vms placed in them
+	pools *collection
+	// ports of the networks keyed by the network ID
+	ports  map[string]*collection
+	events []*models.Event
+}
+
+// collection holds the resources of a kind in the order of creation
+type collection struct {
+	ids   []string
+	items map[string]interface{}
+}
+
+func newCollection() *collection {
+	return &collection{items: map[string]interface{}{}}
+}
+
+func (c *collection) add(id string, item interface{}) {
+	if _, ok := c.items[id]; !ok {
+		c.ids = append(c.ids, id)
+	}
+	c.items[id] = item
+}
+
+func (c *collection) remove(id string) bool {
+	if _, ok := c.items[id]; !ok {
+		return false
+	}
+	delete(c.items, id)
+	for i := range c.ids {
+		if c.ids[i] == id {
+			c.ids = append(c.ids[:i], c.ids[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+func (c *collection) list() []interface{} {
+	list := make([]interface{}, 0, len(c.ids))
+	for _, id := range c.ids {
+		list = append(list, c.items[id])
+	}
+	return list
+}
+
+func (c *collection) len() int {
+	return len(c.ids)
+}
+
+// AddWorkspace adds a PowerVS instance in the zone, e.g. dal12
+func (s *Server) AddWorkspace(name, zone string) *Workspace {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	id := s.newID()
+	ws := &Workspace{
+		ID:        id,
+		Name:      name,
+		Zone:      zone,
+		CRN:       fmt.Sprintf("crn:v1:bluemix:public:power-iaas:%s:a/%s:%s::", zone, s.Account, id),
+		server:    s,
+		created:   time.Now(),
+		instances: newCollection(),
+		volumes:   newCollection(),
+		images:    newCollection(),
+		networks:  newCollection(),
+		pools:     newCollection(),
+		ports:     map[string]*collection{},
+	}
+	s.workspaces = append(s.workspaces, ws)
+	return ws
+}
+
+// ResourceCRN returns the CRN of the resource of the type(pvm-instance, volume, image or network) in the workspace
+func (ws *Workspace) ResourceCRN(resourceType, id string) string {
+	return fmt.Sprintf("%s%s:%s", strings.TrimSuffix(ws.CRN, ":"), resourceType, id)
+}
+
+// Tag attaches the user tags to the resource of the type(pvm-instance, volume, image or network)
+func (ws *Workspace) Tag(resourceType, id string, tags ...string) {
+	ws.server.Tag(ws.ResourceCRN(resourceType, id), tags...)
+}
+
+// AddInstance adds a running vm created at the time
+func (ws *Workspace) AddInstance(name string, created time.Time) *models.PVMInstance {
+	ws.server.mutex.Lock()
+	defer ws.server.mutex.Unlock()
+	id := ws.server.newID()
+	ins := &models.PVMInstance{
+		PvmInstanceID: strPtr(id),
+		ServerName:    strPtr(name),
+		ImageID:       strPtr("image-" + id),
+		Status:        strPtr("ACTIVE"),
+		Health:        &models.PVMInstanceHealth{Status: "OK"},
+		Processors:    floatPtr(0.5),
+		ProcType:      strPtr("shared"),
+		Memory:        floatPtr(4),
+		DiskSize:      floatPtr(0),
+		OsType:        strPtr("rhel"),
+		CreationDate:  strfmt.DateTime(created),
+		UpdatedDate:   strfmt.DateTime(created),
+	}
+	ws.instances.add(id, ins)
+	return ins
+}
+
+// AddVolume adds a volume in the state, e.g. available or in-use, created and last updated at the time
+func (ws *Workspace) AddVolume(name, state string, created time.Time) *models.Volume {
+	ws.server.mutex.Lock()
+	defer ws.server.mutex.Unlock()
+	id := ws.server.newID()
+	date := strfmt.DateTime(created)
+	vol := &models.Volume{
+		VolumeID:       strPtr(id),
+		Name:           strPtr(name),
+		State:          state,
+		Size:           floatPtr(10),
+		DiskType:       "tier3",
+		CreationDate:   &date,
+		LastUpdateDate: &date,
+		PvmInstanceIds: []string{},
+	}
+	ws.volumes.add(id, vol)
+	return vol
+}
+
+// AttachVolume attaches the volume to the vm
+func (ws *Workspace) AttachVolume(volumeID, instanceID string) {
+	ws.server.mutex.Lock()
+	defer ws.server.mutex.Unlock()
+	vol := ws.volumes.items[volumeID].(*models.Volume)
+	vol.PvmInstanceIds = append(vol.PvmInstanceIds, instanceID)
+}
+
+// AddImage adds an active image created at the time
+func (ws *Workspace) AddImage(name string, created time.Time) *models.Image {
+	ws.server.mutex.Lock()
+	defer ws.server.mutex.Unlock()
+	return ws.addImage(name, ImageStateActive, "tier3", created)
+}
+
+func (ws *Workspace) addImage(name, state, storageType string, created time.Time) *models.Image {
+	id := ws.server.newID()
+	date := strfmt.DateTime(created)
+	img := &models.Image{
+		ImageID:        strPtr(id),
+		Name:           strPtr(name),
+		State:          state,
+		Size:           floatPtr(0),
+		StorageType:    strPtr(storageType),
+		StoragePool:    strPtr("Tier3-Flash-1"),
+		CreationDate:   &date,
+		LastUpdateDate: &date,
+		Specifications: &models.ImageSpecifications{OperatingSystem: "rhel", Architecture: "ppc64"},
+		Servers:        []string{},
+		Volumes:        []*models.ImageVolume{},
+	}
+	ws.images.add(id, img)
+	return img
+}
+
+// AddNetwork adds a private network with the cidr, e.g. 192.168.0.0/24
+func (ws *Workspace) AddNetwork(name, cidr string) *models.Network {
+	ws.server.mutex.Lock()
+	defer ws.server.mutex.Unlock()
+	id := ws.server.newID()
+	network := &models.Network{
+		NetworkID:       strPtr(id),
+		Name:            strPtr(name),
+		Type:            strPtr("vlan"),
+		VlanID:          floatPtr(float64(ws.networks.len() + 100)),
+		Jumbo:           boolPtr(false),
+		Cidr:            strPtr(cidr),
+		DNSServers:      []string{"127.0.0.1"},
+		IPAddressRanges: []*models.IPAddressRange{},
+	}
+	ws.networks.add(id, network)
+	ws.ports[id] = newCollection()
+	return network
+}
+
+// AddSharedProcessorPool adds a shared processor pool with the names of the vms placed in it, returns the ID
+func (ws *Workspace) AddSharedProcessorPool(name string, servers ...string) string {
+	ws.server.mutex.Lock()
+	defer ws.server.mutex.Unlock()
+	id := ws.server.newID()
+	detail := map[string]interface{}{
+		"sharedProcessorPool": map[string]interface{}{"id": id, "name": name, "reservedCores": 1, "allocatedCores": 0.5, "availableCores": 1, "status": "active"},
+		"servers":             []map[string]string{},
+	}
+	for _, server := range servers {
+		detail["servers"] = append(detail["servers"].([]map[string]string), map[string]string{"id": ws.server.newID(), "name": server})
+	}
+	ws.pools.add(id, detail)
+	return id
+}
+
+// SharedProcessorPools returns the IDs of the shared processor pools of the workspace
+func (ws *Workspace) SharedProcessorPools() []string {
+	ws.server.mutex.Lock()
+	defer ws.server.mutex.Unlock()
+	return append([]string(nil), ws.pools.ids...)
+}
+
+// AddPort adds a port to the network with the IP address
+func (ws *Workspace) AddPort(networkID, ipAddress, description string) *models.NetworkPort {
+	ws.server.mutex.Lock()
+	defer ws.server.mutex.Unlock()
+	ports, ok := ws.ports[networkID]
+	if !ok {
+		panic("fake: network not found: " + networkID)
+	}
+	return ws.addPort(ports, ipAddress, description)
+}
+
+func (ws *Workspace) addPort(ports *collection, ipAddress, description string) *models.NetworkPort {
+	id := ws.server.newID()
+	port := &models.NetworkPort{
+		PortID:      strPtr(id),
+		IPAddress:   strPtr(ipAddress),
+		MacAddress:  strPtr(fmt.Sprintf("fa:16:3e:00:%02x:%02x", ws.server.ids/256%256, ws.server.ids%256)),
+		Status:      strPtr("DOWN"),
+		Description: strPtr(description),
+	}
+	ports.add(id, port)
+	return port
+}
+
+// AddEvent adds an event of the resource at the time
+func (ws *Workspace) AddEvent(action, resource, message string, at time.Time) *models.Event {
+	ws.server.mutex.Lock()
+	defer ws.server.mutex.Unlock()
+	date := strfmt.DateTime(at)
+	e := &models.Event{
+		EventID:   strPtr(ws.server.newID()),
+		Action:    strPtr(action),
+		Resource:  strPtr(resource),
+		Message:   strPtr(message),
+		Level:     strPtr("info"),
+		Time:      &date,
+		Timestamp: int64Ptr(at.Unix()),
+	}
+	ws.events = append(ws.events, e)
+	return e
+}
+
+// Instances returns the vms of the workspace
+func (ws *Workspace) Instances() []*models.PVMInstance {
+	ws.server.mutex.Lock()
+	defer ws.server.mutex.Unlock()
+	var list []*models.PVMInstance
+	for _, item := range ws.instances.list() {
+		list = append(list, item.(*models.PVMInstance))
+	}
+	return list
+}
+
+// Volumes returns the volumes of the workspace
+func (ws *Workspace) Volumes() []*models.Volume {
+	ws.server.mutex.Lock()
+	defer ws.server.mutex.Unlock()
+	var list []*models.Volume
+	for _, item := range ws.volumes.list() {
+		list = append(list, item.(*models.Volume))
+	}
+	return list
+}
+
+// Images returns the images of the workspace
+func (ws *Workspace) Images() []*models.Image {
+	ws.server.mutex.Lock()
+	defer ws.server.mutex.Unlock()
+	var list []*models.Image
+	for _, item := range ws.images.list() {
+		list = append(list, item.(*models.Image))
+	}
+	return list
+}
+
+// Networks returns the networks of the workspace
+func (ws *Workspace) Networks() []*models.Network {
+	ws.server.mutex.Lock()
+	defer ws.server.mutex.Unlock()
+	var list []*models.Network
+	for _, item := range ws.networks.list() {
+		list = append(list, item.(*models.Network))
+	}
+	return list
+}
+
+// Ports returns the ports of the network
+func (ws *Workspace) Ports(networkID string) []*models.NetworkPort {
+	ws.server.mutex.Lock()
+	defer ws.server.mutex.Unlock()
+	var list []*models.NetworkPort
+	if ports, ok := ws.ports[networkID]; ok {
+		for _, item := range ports.list() {
+			list = append(list, item.(*models.NetworkPort))
+		}
+	}
+	return list
+}
+
+// usage returns the resources used by the workspace
+func (ws *Workspace) usage() *models.CloudInstanceUsageLimits {
+	var memory, procUnits, storage float64
+	for _, item := range ws.instances.list() {
+		ins := item.(*models.PVMInstance)
+		memory += *ins.Memory
+		procUnits += *ins.Processors
+	}
+	for _, item := range ws.volumes.list() {
+		storage += *item.(*models.Volume).Size
+	}
+	return &models.CloudInstanceUsageLimits{
+		Instances:       floatPtr(float64(ws.instances.len())),
+		Memory:          floatPtr(memory),
+		ProcUnits:       floatPtr(procUnits),
+		Processors:      floatPtr(procUnits),
+		Storage:         floatPtr(storage),
+		StorageSSD:      floatPtr(0),
+		StorageStandard: floatPtr(storage),
+	}
+}
+
+// servePowerVS serves the /pcloud/v1/cloud-instances/{cloud_instance_id} APIs of the workspaces
+func (s *Server) servePowerVS(w http.ResponseWriter, r *http.Request) {
+	const prefix = "/pcloud/v1/cloud-instances/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, prefix), "/"), "/")
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var ws *Workspace
+	for _, w := range s.workspaces {
+		if w.ID == parts[0] {
+			ws = w
+		}
+	}
+	if ws == nil {
+		writeError(w, http.StatusNotFound, "cloud instance not found: "+parts[0])
+		return
+	}
+
+	if len(parts) == 1 {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		writeJSON(w, http.StatusOK, &models.CloudInstance{
+			CloudInstanceID: strPtr(ws.ID),
+			Name:            strPtr(ws.Name),
+			Region:          strPtr(ws.Zone),
+			TenantID:        strPtr(s.Account),
+			OpenstackID:     strPtr(ws.ID),
+			Enabled:         boolPtr(true),
+			Initialized:     boolPtr(true),
+			Capabilities:    []string{},
+			Usage:           ws.usage(),
+			Limits:          ws.usage(),
+		})
+		return
+	}
+
+	switch parts[1] {
+	case "pvm-instances":
+		// volumes get detached from the deleted vm
+		if r.Method == http.MethodDelete && len(parts) == 3 && ws.instances.items[parts[2]] != nil {
+			for _, item := range ws.volumes.list() {
+				vol := item.(*models.Volume)
+				vol.PvmInstanceIds = removeString(vol.PvmInstanceIds, parts[2])
+			}
+		}
+		serveCollection(w, r, ws.instances, "pvmInstances", parts[2:])
+	case "volumes":
+		serveCollection(w, r, ws.volumes, "volumes", parts[2:])
+	case "images":
+		if r.Method == http.MethodPost && len(parts) == 2 {
+			s.importImage(w, r, ws)
+			return
+		}
+		// imported images turn active once fetched
+		if r.Method == http.MethodGet && len(parts) == 3 {
+			if img, ok := ws.images.items[parts[2]].(*models.Image); ok && img.State == ImageStateQueued {
+				img.State = ImageStateActive
+			}
+		}
+		serveCollection(w, r, ws.images, "images", parts[2:])
+	case "networks":
+		if len(parts) >= 4 && parts[3] == "ports" {
+			ports, ok := ws.ports[parts[2]]
+			if !ok {
+				writeError(w, http.StatusNotFound, "network not found: "+parts[2])
+				return
+			}
+			if r.Method == http.MethodPost && len(parts) == 4 {
+				ws.createPort(w, r, ports, ws.networks.items[parts[2]].(*models.Network))
+				return
+			}
+			serveCollection(w, r, ports, "ports", parts[4:])
+			return
+		}
+		if r.Method == http.MethodDelete && len(parts) == 3 {
+			delete(ws.ports, parts[2])
+		}
+		serveCollection(w, r, ws.networks, "networks", parts[2:])
+	case "shared-processor-pools":
+		if len(parts) == 2 && r.Method == http.MethodGet {
+			pools := []interface{}{}
+			for _, item := range ws.pools.list() {
+				pools = append(pools, item.(map[string]interface{})["sharedProcessorPool"])
+			}
+			writeJSON(w, http.StatusOK, map[string]interface{}{"sharedProcessorPools": pools})
+			return
+		}
+		serveCollection(w, r, ws.pools, "sharedProcessorPools", parts[2:])
+	case "events":
+		from := time.Time{}
+		if v := r.URL.Query().Get("from_time"); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "invalid from_time: "+v)
+				return
+			}
+			from = t
+		}
+		events := []*models.Event{}
+		for _, e := range ws.events {
+			if !time.Time(*e.Time).Before(from) {
+				events = append(events, e)
+			}
+		}
+		sort.Slice(events, func(i, j int) bool { return time.Time(*events[i].Time).Before(time.Time(*events[j].Time)) })
+		writeJSON(w, http.StatusOK, &models.Events{Events: events})
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// serveCollection lists, gets and deletes the resources of the collection, args are the path segments after the
+// collection
+func serveCollection(w http.ResponseWriter, r *http.Request, c *collection, key string, args []string) {
+	switch {
+	case len(args) == 0 && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]interface{}{key: c.list()})
+	case len(args) == 1 && r.Method == http.MethodGet:
+		item, ok := c.items[args[0]]
+		if !ok {
+			writeError(w, http.StatusNotFound, "resource not found: "+args[0])
+			return
+		}
+		writeJSON(w, http.StatusOK, item)
+	case len(args) == 1 && r.Method == http.MethodDelete:
+		if !c.remove(args[0]) {
+			writeError(w, http.StatusNotFound, "resource not found: "+args[0])
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{})
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// importImage starts the import of the image from the COS bucket, the object must be present in the bucket
+func (s *Server) importImage(w http.ResponseWriter, r *http.Request, ws *Workspace) {
+	var body models.CreateImage
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if body.Source == nil || *body.Source != "url" {
+		writeError(w, http.StatusBadRequest, "only the import from the url source is supported")
+		return
+	}
+	if body.ImageName == "" || body.BucketName == "" || body.ImageFilename == "" || body.Region == "" {
+		writeError(w, http.StatusBadRequest, "imageName, bucketName, imageFilename and region are required")
+		return
+	}
+	if body.AccessKey == "" || body.SecretKey == "" {
+		writeError(w, http.StatusBadRequest, "accessKey and secretKey are required")
+		return
+	}
+	bucket := s.findBucket(body.BucketName)
+	if bucket == nil {
+		writeError(w, http.StatusBadRequest, "bucket not found: "+body.BucketName)
+		return
+	}
+	if _, ok := bucket.objects[body.ImageFilename]; !ok {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("object %s not found in the bucket %s", body.ImageFilename, body.BucketName))
+		return
+	}
+	for _, item := range ws.images.list() {
+		if *item.(*models.Image).Name == body.ImageName {
+			writeError(w, http.StatusConflict, "image already exists: "+body.ImageName)
+			return
+		}
+	}
+	storageType := body.DiskType
+	if storageType == "" {
+		storageType = "tier3"
+	}
+	writeJSON(w, http.StatusCreated, ws.addImage(body.ImageName, ImageStateQueued, storageType, time.Now()))
+}
+
+// createPort creates a port in the network, the next free IP address is assigned if not requested
+func (ws *Workspace) createPort(w http.ResponseWriter, r *http.Request, ports *collection, network *models.Network) {
+	var body models.NetworkPortCreate
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	used := map[string]bool{}
+	for _, item := range ports.list() {
+		used[*item.(*models.NetworkPort).IPAddress] = true
+	}
+	ip := body.IPAddress
+	if ip == "" {
+		prefix := strings.TrimSuffix(strings.SplitN(*network.Cidr, "/", 2)[0], "0")
+		for i := 2; i < 255; i++ {
+			if candidate := fmt.Sprintf("%s%d", prefix, i); !used[candidate] {
+				ip = candidate
+				break
+			}
+		}
+	}
+	if used[ip] {
+		writeError(w, http.StatusConflict, "IP address already in use: "+ip)
+		return
+	}
+	writeJSON(w, http.StatusCreated, ws.addPort(ports, ip, body.Description))
+}
+
+func strPtr(s string) *string { return &s }
+
+func floatPtr(f float64) *float64 { return &f }
+func boolPtr(b bool) *bool        { return &b }
+func int64Ptr(i int64) *int64     { return &i }
+
+// removeString returns the list without the s
+func removeString(list []string, s string) []string {
+	r := []string{}
+	for _, e := range list {
+		if e != s {
+			r = append(r, e)
+		}
+	}
+	return r
+}
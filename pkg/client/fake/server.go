@@ -0,0 +1,265 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	// DefaultAccount is the account of the users authenticated by the server
+	DefaultAccount = "fake-account"
+	// DefaultAPIKey is the API key accepted by the server unless the Server.APIKey is changed
+	DefaultAPIKey = "fake-api-key"
+
+	tokenTTL = time.Hour
+)
+
+// Server is an in-process stand-in for the IBM Cloud endpoints used by pvsadm: the IAM token endpoint, the resource
+// controller, the global tagging, the PowerVS API and the S3 API of the Cloud Object Storage. The requests are
+// routed by the host name, hence the clients keep using the endpoints of the environment once the server is
+// installed as the http.DefaultTransport with Install.
+//
+// Resources are seeded with the Add* methods before running the commands and inspected afterwards, the server is
+// not meant for the tests running in parallel.
+type Server struct {
+	*httptest.Server
+	// Account of the users, set in the issued IAM access tokens
+	Account string
+	// APIKey is the only API key accepted by the IAM token endpoint
+	APIKey string
+
+	mutex      sync.Mutex
+	secret     []byte
+	ids        int
+	workspaces []*Workspace
+	cos        []*COSInstance
+	buckets    map[string]*bucket
+	keys       []*serviceKey
+	tags       map[string][]string
+	failures   []*failure
+	restore    http.RoundTripper
+}
+
+// failure is an injected error response for the matching requests
+type failure struct {
+	method, path string
+	status       int
+	times        int
+}
+
+// NewServer starts the server, the caller must Close it
+func NewServer() *Server {
+	s := &Server{
+		Account: DefaultAccount,
+		APIKey:  DefaultAPIKey,
+		secret:  []byte(fmt.Sprintf("fake-secret-%d", time.Now().UnixNano())),
+		tags:    map[string][]string{},
+		buckets: map[string]*bucket{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Install routes all the requests made with the http.DefaultTransport to the server till it is closed
+func (s *Server) Install() {
+	if s.restore == nil {
+		s.restore = http.DefaultTransport
+	}
+	http.DefaultTransport = s.Transport()
+}
+
+// Close restores the http.DefaultTransport if installed and shuts down the server
+func (s *Server) Close() {
+	if s.restore != nil {
+		http.DefaultTransport = s.restore
+		s.restore = nil
+	}
+	s.Server.Close()
+}
+
+// Transport returns the round tripper sending the requests for any host to the server, the original host is kept in
+// the Host header
+func (s *Server) Transport() http.RoundTripper {
+	return &transport{addr: s.Listener.Addr().String(), base: &http.Transport{}}
+}
+
+type transport struct {
+	addr string
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := req.Clone(req.Context())
+	r.URL.Scheme = "http"
+	r.URL.Host = t.addr
+	r.Host = req.URL.Host
+	return t.base.RoundTrip(r)
+}
+
+// Fail responds to the next times requests with the method and the path, along with the query, containing the path
+// with the status
+func (s *Server) Fail(method, path string, status, times int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.failures = append(s.failures, &failure{method: method, path: path, status: status, times: times})
+}
+
+// Tag attaches the user tags to the resource
+func (s *Server) Tag(crn string, tags ...string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.tags[crn] = append(s.tags[crn], tags...)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if i := strings.LastIndex(host, ":"); i != -1 {
+		host = host[:i]
+	}
+	if status := s.injectedFailure(r); status != 0 {
+		if strings.HasPrefix(host, "s3.") {
+			writeS3Error(w, r, status, "InternalError", "injected failure")
+			return
+		}
+		writeError(w, status, "injected failure")
+		return
+	}
+
+	switch {
+	case strings.HasPrefix(host, "iam."):
+		s.serveIAM(w, r)
+	case strings.HasPrefix(host, "s3."):
+		if !s.authorized(r) {
+			writeS3Error(w, r, http.StatusForbidden, "AccessDenied", "Access Denied")
+			return
+		}
+		s.serveCOS(w, r, host)
+	case !s.authorized(r):
+		writeError(w, http.StatusUnauthorized, "invalid or expired IAM access token")
+	case strings.HasPrefix(host, "resource-controller."):
+		s.serveResourceController(w, r)
+	case strings.HasPrefix(host, "tags."):
+		s.serveTags(w, r)
+	case strings.Contains(host, "power-iaas."):
+		s.servePowerVS(w, r)
+	default:
+		writeError(w, http.StatusNotFound, "unknown host: "+host)
+	}
+}
+
+func (s *Server) injectedFailure(r *http.Request) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, f := range s.failures {
+		if f.times > 0 && f.method == r.Method && strings.Contains(r.URL.RequestURI(), f.path) {
+			f.times--
+			return f.status
+		}
+	}
+	return 0
+}
+
+// newID returns a new unique ID shaped as an UUID
+func (s *Server) newID() string {
+	s.ids++
+	return fmt.Sprintf("00000000-0000-4000-8000-%012d", s.ids)
+}
+
+// serveIAM issues the access tokens for the API key grant
+func (s *Server) serveIAM(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.URL.Path != "/identity/token" {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if r.Form.Get("grant_type") != "urn:ibm:params:oauth:grant-type:apikey" || r.Form.Get("apikey") != s.APIKey {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"errorCode":    "BXNIM0415E",
+			"errorMessage": "Provided API key could not be found",
+		})
+		return
+	}
+	expiry := time.Now().Add(tokenTTL).Unix()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"id":      "IBMid-fake",
+		"iam_id":  "IBMid-fake",
+		"email":   "fake@example.com",
+		"account": map[string]string{"bss": s.Account},
+		"iss":     "https://iam.cloud.ibm.com/identity",
+		"exp":     expiry,
+	}).SignedString(s.secret)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expiration":   expiry,
+	})
+}
+
+// authorized verifies the bearer token issued by the server or the HMAC access key of a service credential
+func (s *Server) authorized(r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+	if strings.HasPrefix(auth, "Bearer ") {
+		_, err := jwt.Parse(strings.TrimPrefix(auth, "Bearer "), func(token *jwt.Token) (interface{}, error) {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+			return s.secret, nil
+		})
+		return err == nil
+	}
+	if strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=") {
+		accessKey := strings.SplitN(strings.TrimPrefix(auth, "AWS4-HMAC-SHA256 Credential="), "/", 2)[0]
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		for _, k := range s.keys {
+			if k.accessKey == accessKey {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes the error in the format of the PowerVS API, understood by the other APIs too
+func writeError(w http.ResponseWriter, status int, description string) {
+	writeJSON(w, status, map[string]interface{}{
+		"code":        status,
+		"error":       http.StatusText(status),
+		"description": description,
+		"message":     description,
+	})
+}
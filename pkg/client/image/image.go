@@ -18,6 +18,7 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/IBM-Cloud/power-go-client/clients/instance"
@@ -25,6 +26,7 @@ import (
 	"github.com/IBM-Cloud/power-go-client/power/client/p_cloud_images"
 	"github.com/IBM-Cloud/power-go-client/power/models"
 	"github.com/ppc64le-cloud/pvsadm/pkg"
+	"github.com/ppc64le-cloud/pvsadm/pkg/client/tagging"
 	"k8s.io/klog/v2"
 )
 
@@ -32,6 +34,7 @@ type Client struct {
 	session    *ibmpisession.IBMPISession
 	client     *instance.IBMPIImageClient
 	instanceID string
+	filter     *tagging.Filter
 }
 
 func NewClient(sess *ibmpisession.IBMPISession, powerinstanceid string) *Client {
@@ -43,6 +46,11 @@ func NewClient(sess *ibmpisession.IBMPISession, powerinstanceid string) *Client
 	return c
 }
 
+// SetFilter sets the tag filter applied on top of the regular expression and age in GetAllPurgeable
+func (c *Client) SetFilter(f *tagging.Filter) {
+	c.filter = f
+}
+
 func (c *Client) Get(id string) (*models.Image, error) {
 	return c.client.Get(id, c.instanceID)
 }
@@ -51,6 +59,30 @@ func (c *Client) GetAll() (*models.Images, error) {
 	return c.client.GetAll(c.instanceID)
 }
 
+// GetByNameOrID returns the image by ID or name(preference will be given to the ID over name)
+func (c *Client) GetByNameOrID(v string) (*models.Image, error) {
+	images, err := c.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the list of images: %v", err)
+	}
+	var ids []string
+	for _, image := range images.Images {
+		if *image.ImageID == v {
+			return c.Get(v)
+		}
+		if *image.Name == v {
+			ids = append(ids, *image.ImageID)
+		}
+	}
+	switch len(ids) {
+	case 0:
+		return nil, fmt.Errorf("image: %s not found", v)
+	case 1:
+		return c.Get(ids[0])
+	}
+	return nil, fmt.Errorf("more than one image found with the name: %s, use the ID instead: [%s]", v, strings.Join(ids, ", "))
+}
+
 func (c *Client) Delete(id string) error {
 	return c.client.Delete(id, c.instanceID)
 }
@@ -69,7 +101,7 @@ func (c *Client) ImportImage(instanceID, imageName, s3Filename, region, accessKe
 		Source:        &source,
 	}
 
-	params := p_cloud_images.NewPcloudCloudinstancesImagesPostParamsWithTimeout(pkg.TIMEOUT).WithCloudInstanceID(instanceID).WithBody(&body)
+	params := p_cloud_images.NewPcloudCloudinstancesImagesPostParamsWithTimeout(pkg.Options.APITimeout).WithCloudInstanceID(instanceID).WithBody(&body)
 	resp1, resp2, err := c.session.Power.PCloudImages.PcloudCloudinstancesImagesPost(params, ibmpisession.NewAuth(c.session, instanceID))
 
 	if err != nil {
@@ -104,6 +136,11 @@ func (c *Client) GetAllPurgeable(before, since time.Duration, expr string) ([]*m
 		if !pkg.IsPurgeable(time.Time(*image.CreationDate), before, since) {
 			continue
 		}
+		if ok, err := c.filter.Matches("image", *image.ImageID); err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
 		candidates = append(candidates, image)
 	}
 	return candidates, nil
@@ -20,13 +20,16 @@ import (
 	"github.com/IBM-Cloud/power-go-client/ibmpisession"
 	"github.com/IBM-Cloud/power-go-client/power/models"
 	"github.com/ppc64le-cloud/pvsadm/pkg"
+	"github.com/ppc64le-cloud/pvsadm/pkg/client/tagging"
 	"regexp"
+	"strings"
 	"time"
 )
 
 type Client struct {
 	client     *instance.IBMPIInstanceClient
 	instanceID string
+	filter     *tagging.Filter
 }
 
 func NewClient(sess *ibmpisession.IBMPISession, powerinstanceid string) *Client {
@@ -37,16 +40,45 @@ func NewClient(sess *ibmpisession.IBMPISession, powerinstanceid string) *Client
 	return c
 }
 
+// SetFilter sets the tag filter applied on top of the regular expression and age in GetAllPurgeable
+func (c *Client) SetFilter(f *tagging.Filter) {
+	c.filter = f
+}
+
 func (c *Client) Get(id string) (*models.PVMInstance, error) {
-	return c.client.Get(id, c.instanceID, pkg.TIMEOUT)
+	return c.client.Get(id, c.instanceID, pkg.Options.APITimeout)
 }
 
 func (c *Client) GetAll() (*models.PVMInstances, error) {
-	return c.client.GetAll(c.instanceID, pkg.TIMEOUT)
+	return c.client.GetAll(c.instanceID, pkg.Options.APITimeout)
+}
+
+// GetByNameOrID returns the instance by ID or name(preference will be given to the ID over name)
+func (c *Client) GetByNameOrID(v string) (*models.PVMInstance, error) {
+	instances, err := c.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the list of instances: %v", err)
+	}
+	var ids []string
+	for _, ins := range instances.PvmInstances {
+		if *ins.PvmInstanceID == v {
+			return c.Get(v)
+		}
+		if *ins.ServerName == v {
+			ids = append(ids, *ins.PvmInstanceID)
+		}
+	}
+	switch len(ids) {
+	case 0:
+		return nil, fmt.Errorf("instance: %s not found", v)
+	case 1:
+		return c.Get(ids[0])
+	}
+	return nil, fmt.Errorf("more than one instance found with the name: %s, use the ID instead: [%s]", v, strings.Join(ids, ", "))
 }
 
 func (c *Client) Delete(id string) error {
-	return c.client.Delete(id, c.instanceID, pkg.TIMEOUT)
+	return c.client.Delete(id, c.instanceID, pkg.Options.APITimeout)
 }
 
 func (c *Client) GetAllPurgeable(before, since time.Duration, expr string) ([]*models.PVMInstanceReference, error) {
@@ -65,6 +97,11 @@ func (c *Client) GetAllPurgeable(before, since time.Duration, expr string) ([]*m
 		if !pkg.IsPurgeable(time.Time(ins.CreationDate), before, since) {
 			continue
 		}
+		if ok, err := c.filter.Matches("pvm-instance", *ins.PvmInstanceID); err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
 		candidates = append(candidates, ins)
 	}
 	return candidates, nil
@@ -0,0 +1,197 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg"
+)
+
+// InstanceCacheTTL is the duration for which the cached PowerVS instances are used without listing them again
+const InstanceCacheTTL = 24 * time.Hour
+
+// Instance is the PowerVS service instance(workspace) as cached on the disk
+type Instance struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Zone string `json:"zone"`
+	CRN  string `json:"crn"`
+}
+
+type instanceCache struct {
+	Updated   time.Time  `json:"updated"`
+	Instances []Instance `json:"instances"`
+}
+
+// cacheDir returns the directory of the on-disk cache
+func cacheDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".pvsadm", "cache"), nil
+}
+
+// ListPowerVSInstances returns the PowerVS instances of the account, served from the on-disk cache unless it is
+// older than the InstanceCacheTTL or refresh is set
+func (c *Client) ListPowerVSInstances(refresh bool) ([]Instance, error) {
+	file, err := c.instanceCacheFile()
+	if err != nil {
+		klog.V(2).Infof("Not using the instance cache: %v", err)
+	}
+	if !refresh && file != "" {
+		if cache, err := readInstanceCache(file); err == nil && time.Since(cache.Updated) < InstanceCacheTTL {
+			klog.V(2).Infof("Using the cached PowerVS instances from %s", file)
+			return cache.Instances, nil
+		}
+	}
+
+	svcs, err := c.ListServiceInstancesByType(PowerVSServiceType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the PowerVS instances: %v", err)
+	}
+	var instances []Instance
+	for _, svc := range svcs {
+		klog.V(4).Infof("Service ID: %s, region_id: %s, Name: %s", svc.Guid, svc.RegionID, svc.Name)
+		instances = append(instances, Instance{ID: svc.Guid, Name: svc.Name, Zone: svc.RegionID, CRN: svc.Crn.String()})
+	}
+	if file != "" {
+		if err := writeInstanceCache(file, &instanceCache{Updated: time.Now(), Instances: instances}); err != nil {
+			klog.Warningf("Failed to write the instance cache: %v", err)
+		}
+	}
+	return instances, nil
+}
+
+// LookupInstance returns the PowerVS instance by ID or name(preference will be given to the ID over name), the
+// cached instances are listed again if not found in the cache
+func (c *Client) LookupInstance(id, name string) (*Instance, error) {
+	refresh := pkg.Options.Refresh
+	for {
+		instances, err := c.ListPowerVSInstances(refresh)
+		if err != nil {
+			return nil, err
+		}
+		ins, err := findInstance(instances, id, name)
+		if err == nil || refresh || !isNotFound(err) {
+			return ins, err
+		}
+		klog.V(2).Infof("Instance not found in the cache, listing the PowerVS instances again")
+		refresh = true
+	}
+}
+
+type notFoundError struct {
+	msg string
+}
+
+func (e *notFoundError) Error() string {
+	return e.msg
+}
+
+func isNotFound(err error) bool {
+	_, ok := err.(*notFoundError)
+	return ok
+}
+
+// findInstance finds the instance by ID or name, fails if more than one instance is found with the name
+func findInstance(instances []Instance, id, name string) (*Instance, error) {
+	var matched []Instance
+	for _, ins := range instances {
+		if id != "" && ins.ID == id {
+			return &ins, nil
+		}
+		if id == "" && ins.Name == name {
+			matched = append(matched, ins)
+		}
+	}
+	switch len(matched) {
+	case 0:
+		if id != "" {
+			return nil, &notFoundError{fmt.Sprintf("instance with ID: %s not found", id)}
+		}
+		return nil, &notFoundError{fmt.Sprintf("instance: %s not found", name)}
+	case 1:
+		return &matched[0], nil
+	}
+	return nil, ambiguousNameError(name, matched)
+}
+
+// ambiguousNameError is the error for the name matching more than one instance
+func ambiguousNameError(name string, matched []Instance) error {
+	var list []string
+	for _, ins := range matched {
+		list = append(list, fmt.Sprintf("%s(zone: %s)", ins.ID, ins.Zone))
+	}
+	return fmt.Errorf("more than one instance found with the name: %s, use --instance-id instead: [%s]", name, strings.Join(list, ", "))
+}
+
+// instanceCacheFile returns the cache file of the account
+func (c *Client) instanceCacheFile() (string, error) {
+	if c.User == nil || c.User.Account == "" {
+		return "", fmt.Errorf("account is not known")
+	}
+	dir, err := cacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "instances-"+c.User.Account+".json"), nil
+}
+
+func readInstanceCache(file string) (*instanceCache, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	cache := &instanceCache{}
+	if err := json.Unmarshal(content, cache); err != nil {
+		return nil, fmt.Errorf("failed to parse the instance cache %s: %v", file, err)
+	}
+	return cache, nil
+}
+
+func writeInstanceCache(file string, cache *instanceCache) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	content, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	// written to a unique temporary file in the same directory and renamed to avoid the partial reads and writes by
+	// the concurrent invocations
+	tmp, err := ioutil.TempFile(filepath.Dir(file), filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
@@ -0,0 +1,105 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_findInstance(t *testing.T) {
+	instances := []Instance{
+		{ID: "id-1", Name: "upstream-core", Zone: "dal12"},
+		{ID: "id-2", Name: "ci", Zone: "tok04"},
+		{ID: "id-3", Name: "ci", Zone: "syd05"},
+	}
+	tests := []struct {
+		name         string
+		id           string
+		insName      string
+		want         string
+		wantErr      string
+		wantNotFound bool
+	}{
+		{"by name", "", "upstream-core", "id-1", "", false},
+		{"by id", "id-3", "", "id-3", "", false},
+		{"id preferred over name", "id-2", "upstream-core", "id-2", "", false},
+		{"ambiguous name", "", "ci", "", "id-2(zone: tok04), id-3(zone: syd05)", false},
+		{"unknown name", "", "unknown", "", "instance: unknown not found", true},
+		{"unknown id", "id-4", "", "", "instance with ID: id-4 not found", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := findInstance(instances, tt.id, tt.insName)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("findInstance() error = %v, want %q", err, tt.wantErr)
+				}
+				if isNotFound(err) != tt.wantNotFound {
+					t.Errorf("isNotFound() = %v, want %v", isNotFound(err), tt.wantNotFound)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("findInstance() unexpected error = %v", err)
+			}
+			if got.ID != tt.want {
+				t.Errorf("findInstance() = %v, want %v", got.ID, tt.want)
+			}
+		})
+	}
+}
+
+func Test_instanceCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pvsadm-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "cache", "instances-account.json")
+	if _, err := readInstanceCache(file); err == nil {
+		t.Fatalf("readInstanceCache() expected error for the missing cache")
+	}
+	want := &instanceCache{Updated: time.Now().Add(-time.Hour).Round(time.Second), Instances: []Instance{{ID: "id-1", Name: "upstream-core", Zone: "dal12", CRN: "crn:v1"}}}
+	if err := writeInstanceCache(file, want); err != nil {
+		t.Fatalf("writeInstanceCache() error = %v", err)
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("cache file mode = %v, want 0600", info.Mode().Perm())
+	}
+	got, err := readInstanceCache(file)
+	if err != nil {
+		t.Fatalf("readInstanceCache() error = %v", err)
+	}
+	if !got.Updated.Equal(want.Updated) || len(got.Instances) != 1 || got.Instances[0] != want.Instances[0] {
+		t.Errorf("readInstanceCache() = %+v, want %+v", got, want)
+	}
+
+	if err := ioutil.WriteFile(file, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readInstanceCache(file); err == nil {
+		t.Errorf("readInstanceCache() expected error for the corrupted cache")
+	}
+}
@@ -0,0 +1,546 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IBM/ibm-cos-sdk-go/aws"
+	"github.com/IBM/ibm-cos-sdk-go/aws/awserr"
+	"github.com/IBM/ibm-cos-sdk-go/service/s3"
+	"k8s.io/klog/v2"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg/utils"
+)
+
+const (
+	// MinPartSize is the minimum size of the parts of the multipart upload, except the last part
+	MinPartSize int64 = 5 * 1024 * 1024
+	// DefaultPartSize is the size of the parts of the multipart upload
+	DefaultPartSize int64 = 64 * 1024 * 1024
+	// DefaultConcurrency is the number of the parts uploaded in parallel
+	DefaultConcurrency = 5
+	// MaxParts is the maximum number of the parts of the multipart upload
+	MaxParts = 10000
+
+	// ChecksumMetadataKey is the metadata of the objects holding the hex sha256 digest of the content
+	ChecksumMetadataKey = "sha256"
+)
+
+// UploadOptions tunes the multipart upload of the UploadObjectWithOptions, zero values use the defaults
+type UploadOptions struct {
+	// PartSize is the size of the parts in bytes
+	PartSize int64
+	// Concurrency is the number of the parts uploaded in parallel
+	Concurrency int
+	// StateFile checkpoints the progress of the upload, defaults to the UploadStateFile of the bucket and the object
+	StateFile string
+	// Checksum is the hex sha256 digest of the file stored in the ChecksumMetadataKey metadata of the object
+	Checksum string
+}
+
+// UploadState is the checkpoint of the multipart upload, the upload is resumed with the same upload ID as long as the
+// file is not modified
+type UploadState struct {
+	Bucket   string    `json:"bucket"`
+	Object   string    `json:"object"`
+	UploadID string    `json:"uploadId"`
+	File     string    `json:"file"`
+	Size     int64     `json:"size"`
+	ModTime  time.Time `json:"modTime"`
+	PartSize int64     `json:"partSize"`
+	Checksum string    `json:"checksum,omitempty"`
+	// Parts are the ETags of the uploaded parts keyed by the part number
+	Parts map[int64]string `json:"parts"`
+}
+
+// UploadStateFile returns the default state file of the multipart upload of the object,
+// ~/.pvsadm/uploads/<bucket>/<path escaped object>.json
+func UploadStateFile(bucket, object string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	// escaped for the object names with the / to map to distinct files, e.g. a/b and a_b
+	return filepath.Join(home, ".pvsadm", "uploads", bucket, url.PathEscape(object)+".json"), nil
+}
+
+// ReadUploadState reads the state file, nil if the file doesn't exist
+func ReadUploadState(file string) (*UploadState, error) {
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	state := &UploadState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("invalid upload state file %s: %v", file, err)
+	}
+	return state, nil
+}
+
+// write replaces the state file atomically, a crash never leaves a partially written state file behind
+func (s *UploadState) write(file string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// matches reports whether the state belongs to the upload of the file to the object
+func (s *UploadState) matches(bucket, object, file, checksum string, info os.FileInfo) bool {
+	return s.Bucket == bucket && s.Object == object && s.File == file && s.Size == info.Size() && s.ModTime.Equal(info.ModTime()) &&
+		s.Checksum == checksum
+}
+
+// UploadObjectWithOptions uploads the file to the bucket in parts, the progress is checkpointed in the state file and
+// the upload is resumed from the state file if present. The state file is removed once the upload completes and kept
+// if the upload fails or the ctx is canceled, the dangling upload is cleaned up by the AbortUploads otherwise. Every
+// request carries the Content-MD5 of the body for the COS to reject the corrupted content, and the ETag of the
+// uploaded object is verified against the one computed from the file.
+func (c *S3Client) UploadObjectWithOptions(ctx context.Context, fileName, objectName, bucketName string, opts UploadOptions) error {
+	if opts.PartSize == 0 {
+		opts.PartSize = DefaultPartSize
+	}
+	if opts.Concurrency == 0 {
+		opts.Concurrency = DefaultConcurrency
+	}
+	if opts.PartSize < MinPartSize {
+		return fmt.Errorf("part size %d is smaller than the minimum %d bytes", opts.PartSize, MinPartSize)
+	}
+	if opts.Concurrency < 1 {
+		return fmt.Errorf("concurrency must be at least 1")
+	}
+	if opts.StateFile == "" {
+		var err error
+		if opts.StateFile, err = UploadStateFile(bucketName, objectName); err != nil {
+			return err
+		}
+	}
+
+	klog.Infof("uploading the file %s\n", fileName)
+	path, err := filepath.Abs(fileName)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("err opening file %s: %s", fileName, err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	startTime := time.Now()
+	if info.Size() <= opts.PartSize {
+		md5sum, err := contentMD5(file)
+		if err != nil {
+			return err
+		}
+		progress := utils.NewProgress("Uploading "+objectName, info.Size())
+		_, err = c.S3Session.PutObjectWithContext(ctx, &s3.PutObjectInput{
+			Bucket:     aws.String(bucketName),
+			Key:        aws.String(objectName),
+			Body:       progress.ReadSeeker(file),
+			ContentMD5: aws.String(md5sum),
+			Metadata:   checksumMetadata(opts.Checksum),
+		})
+		progress.Done(err)
+		if err != nil {
+			return err
+		}
+		if err := c.verifyETag(ctx, file, info.Size(), 0, bucketName, objectName); err != nil {
+			return err
+		}
+		klog.Infof("Upload completed successfully in %f seconds\n", time.Since(startTime).Seconds())
+		return nil
+	}
+
+	state, err := c.resumableUpload(ctx, bucketName, objectName, path, info, opts)
+	if err != nil {
+		return err
+	}
+	progress := utils.NewProgress("Uploading "+objectName, state.Size)
+	for n := range state.Parts {
+		progress.Resume(sizeOfPart(n, state.Size, state.PartSize))
+	}
+	err = c.uploadParts(ctx, file, state, opts, progress)
+	progress.Done(err)
+	if err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return fmt.Errorf("upload of %s stopped, %d of %d parts uploaded: %v, rerun the command to resume the upload or clean up with the pvsadm image upload abort",
+			fileName, len(state.Parts), partCount(state.Size, state.PartSize), err)
+	}
+
+	var parts []*s3.CompletedPart
+	for n, etag := range state.Parts {
+		parts = append(parts, &s3.CompletedPart{PartNumber: aws.Int64(n), ETag: aws.String(etag)})
+	}
+	sort.Slice(parts, func(i, j int) bool { return *parts[i].PartNumber < *parts[j].PartNumber })
+	_, err = c.S3Session.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(bucketName),
+		Key:             aws.String(objectName),
+		UploadId:        aws.String(state.UploadID),
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to complete the upload of %s: %v", fileName, err)
+	}
+	if err := c.verifyETag(ctx, file, state.Size, state.PartSize, bucketName, objectName); err != nil {
+		return err
+	}
+	if err := os.Remove(opts.StateFile); err != nil && !os.IsNotExist(err) {
+		klog.Warningf("Failed to remove the upload state file %s: %v", opts.StateFile, err)
+	}
+	klog.Infof("Upload completed successfully in %f seconds to location %s/%s\n", time.Since(startTime).Seconds(), bucketName, objectName)
+	return nil
+}
+
+// resumableUpload returns the state of the upload resumed from the state file, a new upload is started if there is
+// nothing to resume
+func (c *S3Client) resumableUpload(ctx context.Context, bucketName, objectName, path string, info os.FileInfo, opts UploadOptions) (*UploadState, error) {
+	state, err := ReadUploadState(opts.StateFile)
+	if err != nil {
+		return nil, err
+	}
+	if state != nil && (state.Bucket != bucketName || state.Object != objectName) {
+		// the upload of another object is left alone, the state file is taken over by this upload
+		klog.Infof("Not resuming the upload %s, the state file %s belongs to the object %s in the bucket %s",
+			state.UploadID, opts.StateFile, state.Object, state.Bucket)
+		state = nil
+	}
+	if state != nil && !state.matches(bucketName, objectName, path, opts.Checksum, info) {
+		klog.Infof("Not resuming the upload %s, the file is modified since", state.UploadID)
+		c.abort(bucketName, objectName, state.UploadID)
+		state = nil
+	}
+	if state != nil {
+		uploaded, err := c.listParts(ctx, bucketName, objectName, state.UploadID)
+		if err != nil {
+			if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != "NoSuchUpload" {
+				return nil, fmt.Errorf("failed to list the parts of the upload %s: %v", state.UploadID, err)
+			}
+			klog.Infof("Not resuming the upload %s, the upload no longer exists", state.UploadID)
+			state = nil
+		} else {
+			// the parts are taken from the COS, the parts uploaded after the last checkpoint are not uploaded again
+			state.Parts = uploaded
+			klog.Infof("Resuming the upload %s, %d of %d parts already uploaded", state.UploadID, len(state.Parts), partCount(state.Size, state.PartSize))
+			if state.PartSize != opts.PartSize {
+				klog.Infof("Using the part size %d of the resumed upload", state.PartSize)
+			}
+		}
+	}
+	if state != nil {
+		return state, state.write(opts.StateFile)
+	}
+
+	if partCount(info.Size(), opts.PartSize) > MaxParts {
+		return nil, fmt.Errorf("file %s needs more than %d parts of %d bytes, use a larger part size", path, MaxParts, opts.PartSize)
+	}
+	out, err := c.S3Session.CreateMultipartUploadWithContext(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:   aws.String(bucketName),
+		Key:      aws.String(objectName),
+		Metadata: checksumMetadata(opts.Checksum),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start the upload of %s: %v", path, err)
+	}
+	state = &UploadState{
+		Bucket:   bucketName,
+		Object:   objectName,
+		UploadID: aws.StringValue(out.UploadId),
+		File:     path,
+		Size:     info.Size(),
+		ModTime:  info.ModTime(),
+		PartSize: opts.PartSize,
+		Checksum: opts.Checksum,
+		Parts:    map[int64]string{},
+	}
+	klog.Infof("Started the upload %s, the progress is saved in %s", state.UploadID, opts.StateFile)
+	return state, state.write(opts.StateFile)
+}
+
+// uploadParts uploads the missing parts of the state with the opts.Concurrency workers, the state file is updated
+// after every uploaded part
+func (c *S3Client) uploadParts(ctx context.Context, file io.ReaderAt, state *UploadState, opts UploadOptions, progress *utils.Progress) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// the missing parts are listed upfront, the state.Parts is updated by the workers
+	var missing []int64
+	for n := int64(1); n <= partCount(state.Size, state.PartSize); n++ {
+		if _, ok := state.Parts[n]; !ok {
+			missing = append(missing, n)
+		}
+	}
+	parts := make(chan int64)
+	go func() {
+		defer close(parts)
+		for _, n := range missing {
+			select {
+			case parts <- n:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var (
+		wg       sync.WaitGroup
+		mutex    sync.Mutex
+		firstErr error
+	)
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range parts {
+				part := io.NewSectionReader(file, (n-1)*state.PartSize, sizeOfPart(n, state.Size, state.PartSize))
+				md5sum, err := contentMD5(part)
+				var out *s3.UploadPartOutput
+				if err == nil {
+					out, err = c.S3Session.UploadPartWithContext(ctx, &s3.UploadPartInput{
+						Bucket:     aws.String(state.Bucket),
+						Key:        aws.String(state.Object),
+						UploadId:   aws.String(state.UploadID),
+						PartNumber: aws.Int64(n),
+						Body:       progress.ReadSeeker(part),
+						ContentMD5: aws.String(md5sum),
+					})
+				}
+
+				mutex.Lock()
+				if err == nil {
+					state.Parts[n] = aws.StringValue(out.ETag)
+					klog.V(2).Infof("Uploaded the part %d of %d", n, partCount(state.Size, state.PartSize))
+					err = state.write(opts.StateFile)
+				}
+				if err != nil && firstErr == nil {
+					firstErr = fmt.Errorf("part %d: %v", n, err)
+					cancel()
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// listParts returns the ETags of the uploaded parts keyed by the part number
+func (c *S3Client) listParts(ctx context.Context, bucketName, objectName, uploadID string) (map[int64]string, error) {
+	parts := map[int64]string{}
+	err := c.S3Session.ListPartsPagesWithContext(ctx, &s3.ListPartsInput{
+		Bucket:   aws.String(bucketName),
+		Key:      aws.String(objectName),
+		UploadId: aws.String(uploadID),
+	}, func(p *s3.ListPartsOutput, last bool) bool {
+		for _, part := range p.Parts {
+			parts[aws.Int64Value(part.PartNumber)] = aws.StringValue(part.ETag)
+		}
+		return true
+	})
+	return parts, err
+}
+
+// abort aborts the upload on the best effort basis
+func (c *S3Client) abort(bucketName, objectName, uploadID string) {
+	_, err := c.S3Session.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(bucketName),
+		Key:      aws.String(objectName),
+		UploadId: aws.String(uploadID),
+	})
+	if err != nil {
+		klog.Warningf("Failed to abort the upload %s of %s: %v", uploadID, objectName, err)
+	}
+}
+
+// MultipartUpload is an incomplete multipart upload of the bucket
+type MultipartUpload struct {
+	Object    string    `json:"object"`
+	UploadID  string    `json:"uploadId"`
+	Initiated time.Time `json:"initiated"`
+}
+
+// AbortUploads aborts the incomplete multipart uploads of the object, all the objects of the bucket if the object is
+// empty, and removes their state files. The aborted uploads are returned.
+func (c *S3Client) AbortUploads(bucketName, objectName string) ([]MultipartUpload, error) {
+	input := &s3.ListMultipartUploadsInput{Bucket: aws.String(bucketName)}
+	if objectName != "" {
+		input.Prefix = aws.String(objectName)
+	}
+	var uploads []MultipartUpload
+	err := c.S3Session.ListMultipartUploadsPages(input, func(p *s3.ListMultipartUploadsOutput, last bool) bool {
+		for _, u := range p.Uploads {
+			if objectName != "" && aws.StringValue(u.Key) != objectName {
+				continue
+			}
+			uploads = append(uploads, MultipartUpload{Object: aws.StringValue(u.Key), UploadID: aws.StringValue(u.UploadId), Initiated: aws.TimeValue(u.Initiated)})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the multipart uploads of the bucket %s: %v", bucketName, err)
+	}
+
+	var aborted []MultipartUpload
+	var errs []string
+	for _, u := range uploads {
+		_, err := c.S3Session.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(bucketName),
+			Key:      aws.String(u.Object),
+			UploadId: aws.String(u.UploadID),
+		})
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s(%s): %v", u.Object, u.UploadID, err))
+			continue
+		}
+		aborted = append(aborted, u)
+		if file, err := UploadStateFile(bucketName, u.Object); err == nil {
+			if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+				klog.Warningf("Failed to remove the upload state file %s: %v", file, err)
+			}
+		}
+	}
+	if len(errs) != 0 {
+		return aborted, errors.New("failed to abort the uploads: " + strings.Join(errs, ", "))
+	}
+	return aborted, nil
+}
+
+// partCount returns the number of the parts of the size
+func partCount(size, partSize int64) int64 {
+	return (size + partSize - 1) / partSize
+}
+
+// sizeOfPart returns the size of the part n of the size, the last part is smaller than the partSize
+func sizeOfPart(n, size, partSize int64) int64 {
+	if n*partSize > size {
+		return size - (n-1)*partSize
+	}
+	return partSize
+}
+
+// contentMD5 returns the base64 md5 digest of the content for the Content-MD5 header, r is rewound to the start
+func contentMD5(r io.ReadSeeker) (string, error) {
+	h := md5.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// verifyETag compares the ETag the COS computed for the stored object with the one computed from the file, partSize
+// is 0 for the object uploaded in a single request
+func (c *S3Client) verifyETag(ctx context.Context, file io.ReaderAt, size, partSize int64, bucketName, objectName string) error {
+	expected, err := fileETag(file, size, partSize)
+	if err != nil {
+		return err
+	}
+	out, err := c.S3Session.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(objectName),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get the metadata of the object %s: %v", objectName, err)
+	}
+	if etag := strings.ToLower(strings.Trim(aws.StringValue(out.ETag), `"`)); etag != expected {
+		return fmt.Errorf("ETag of the uploaded object %s is %q, expected %q", objectName, etag, expected)
+	}
+	return nil
+}
+
+// fileETag returns the S3 style ETag of the file, the hex md5 digest of the file for the single request upload and
+// the hex md5 digest of the concatenated md5 digests of the parts suffixed with -<number of parts> for the multipart
+// upload
+func fileETag(file io.ReaderAt, size, partSize int64) (string, error) {
+	if partSize == 0 {
+		h := md5.New()
+		if _, err := io.Copy(h, io.NewSectionReader(file, 0, size)); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+	sums := md5.New()
+	n := partCount(size, partSize)
+	for i := int64(1); i <= n; i++ {
+		h := md5.New()
+		if _, err := io.Copy(h, io.NewSectionReader(file, (i-1)*partSize, sizeOfPart(i, size, partSize))); err != nil {
+			return "", err
+		}
+		sums.Write(h.Sum(nil))
+	}
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(sums.Sum(nil)), n), nil
+}
+
+// checksumMetadata returns the metadata of the object for the checksum, nil if there is no checksum
+func checksumMetadata(checksum string) map[string]*string {
+	if checksum == "" {
+		return nil
+	}
+	return map[string]*string{ChecksumMetadataKey: aws.String(checksum)}
+}
+
+// ObjectChecksum returns the hex sha256 digest of the object from the ChecksumMetadataKey metadata, empty if the
+// object carries no checksum
+func (c *S3Client) ObjectChecksum(bucketName, objectName string) (string, error) {
+	out, err := c.S3Session.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(objectName),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get the metadata of the object %s: %v", objectName, err)
+	}
+	// the SDK canonicalizes the metadata keys from the response headers
+	for k, v := range out.Metadata {
+		if strings.EqualFold(k, ChecksumMetadataKey) {
+			return strings.ToLower(aws.StringValue(v)), nil
+		}
+	}
+	return "", nil
+}
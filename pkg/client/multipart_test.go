@@ -0,0 +1,141 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUploadState(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "rhcos.ova.gz")
+	if err := ioutil.WriteFile(file, []byte("rhcos image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stateFile := filepath.Join(dir, "state", "rhcos.ova.gz.json")
+	if state, err := ReadUploadState(stateFile); err != nil || state != nil {
+		t.Fatalf("ReadUploadState() of the missing file = %v, %v, want nil, nil", state, err)
+	}
+	state := &UploadState{Bucket: "images", Object: "rhcos.ova.gz", UploadID: "upload-1", File: file, Size: info.Size(),
+		ModTime: info.ModTime(), PartSize: MinPartSize, Checksum: "0a1b", Parts: map[int64]string{1: `"etag-1"`}}
+	if err := state.write(stateFile); err != nil {
+		t.Fatalf("write() error = %v", err)
+	}
+	got, err := ReadUploadState(stateFile)
+	if err != nil {
+		t.Fatalf("ReadUploadState() error = %v", err)
+	}
+	if got.UploadID != "upload-1" || got.Parts[1] != `"etag-1"` {
+		t.Errorf("ReadUploadState() = %+v, want %+v", got, state)
+	}
+
+	tests := []struct {
+		name     string
+		bucket   string
+		object   string
+		checksum string
+		modify   bool
+		want     bool
+	}{
+		{"same file", "images", "rhcos.ova.gz", "0a1b", false, true},
+		{"another object", "images", "centos.ova.gz", "0a1b", false, false},
+		{"another bucket", "ova", "rhcos.ova.gz", "0a1b", false, false},
+		{"another checksum", "images", "rhcos.ova.gz", "2c3d", false, false},
+		{"modified file", "images", "rhcos.ova.gz", "0a1b", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := info
+			if tt.modify {
+				later := info.ModTime().Add(time.Second)
+				if err := os.Chtimes(file, later, later); err != nil {
+					t.Fatal(err)
+				}
+				if info, err = os.Stat(file); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := got.matches(tt.bucket, tt.object, file, tt.checksum, info); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUploadStateFile(t *testing.T) {
+	home := os.Getenv("HOME")
+	os.Setenv("HOME", t.TempDir())
+	defer os.Setenv("HOME", home)
+	files := map[string]string{}
+	for _, object := range []string{"rhcos.ova.gz", "images/rhcos.ova.gz", "images_rhcos.ova.gz", "images%2Frhcos.ova.gz"} {
+		file, err := UploadStateFile("images", object)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if other, ok := files[file]; ok {
+			t.Errorf("UploadStateFile() of the objects %s and %s = %s, want distinct files", other, object, file)
+		}
+		if filepath.Base(filepath.Dir(file)) != "images" {
+			t.Errorf("UploadStateFile() of the object %s = %s, want under the bucket directory", object, file)
+		}
+		files[file] = object
+	}
+}
+
+func Test_partCount(t *testing.T) {
+	tests := []struct {
+		size, partSize, want int64
+	}{
+		{1, MinPartSize, 1},
+		{MinPartSize, MinPartSize, 1},
+		{MinPartSize + 1, MinPartSize, 2},
+		{11 * 1024 * 1024, MinPartSize, 3},
+	}
+	for _, tt := range tests {
+		if got := partCount(tt.size, tt.partSize); got != tt.want {
+			t.Errorf("partCount(%d, %d) = %d, want %d", tt.size, tt.partSize, got, tt.want)
+		}
+	}
+}
+
+func Test_fileETag(t *testing.T) {
+	data := []byte("hello world!")
+	tests := []struct {
+		partSize int64
+		want     string
+	}{
+		{0, "fc3ff98e8c6a0d3087d515c0473f8677"},
+		{5, "bf75f3a0356d639886fa9f195803a660-3"},
+	}
+	for _, tt := range tests {
+		got, err := fileETag(bytes.NewReader(data), int64(len(data)), tt.partSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("fileETag(%d) = %s, want %s", tt.partSize, got, tt.want)
+		}
+	}
+}
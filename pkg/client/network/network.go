@@ -22,8 +22,10 @@ import (
 	"github.com/IBM-Cloud/power-go-client/power/client/p_cloud_networks"
 	"github.com/IBM-Cloud/power-go-client/power/models"
 	"github.com/ppc64le-cloud/pvsadm/pkg"
+	"github.com/ppc64le-cloud/pvsadm/pkg/client/tagging"
 	"k8s.io/klog/v2"
 	"regexp"
+	"strings"
 	"time"
 )
 
@@ -31,6 +33,7 @@ type Client struct {
 	session    *ibmpisession.IBMPISession
 	client     *instance.IBMPINetworkClient
 	instanceID string
+	filter     *tagging.Filter
 }
 
 func NewClient(sess *ibmpisession.IBMPISession, powerinstanceid string) *Client {
@@ -42,16 +45,21 @@ func NewClient(sess *ibmpisession.IBMPISession, powerinstanceid string) *Client
 	return c
 }
 
+// SetFilter sets the tag filter applied on top of the regular expression and age in GetAllPurgeable
+func (c *Client) SetFilter(f *tagging.Filter) {
+	c.filter = f
+}
+
 func (c *Client) Get(id string) (*models.Network, error) {
-	return c.client.Get(id, c.instanceID, pkg.TIMEOUT)
+	return c.client.Get(id, c.instanceID, pkg.Options.APITimeout)
 }
 
 func (c *Client) GetPublic() (*models.Networks, error) {
-	return c.client.GetPublic(c.instanceID, pkg.TIMEOUT)
+	return c.client.GetPublic(c.instanceID, pkg.Options.APITimeout)
 }
 
 func (c *Client) GetAll() (*models.Networks, error) {
-	params := p_cloud_networks.NewPcloudNetworksGetallParamsWithTimeout(pkg.TIMEOUT).WithCloudInstanceID(c.instanceID)
+	params := p_cloud_networks.NewPcloudNetworksGetallParamsWithTimeout(pkg.Options.APITimeout).WithCloudInstanceID(c.instanceID)
 	resp, err := c.session.Power.PCloudNetworks.PcloudNetworksGetall(params, ibmpisession.NewAuth(c.session, c.instanceID))
 
 	if err != nil || resp.Payload == nil {
@@ -62,8 +70,32 @@ func (c *Client) GetAll() (*models.Networks, error) {
 	return resp.Payload, nil
 }
 
+// GetByNameOrID returns the network by ID or name(preference will be given to the ID over name)
+func (c *Client) GetByNameOrID(v string) (*models.Network, error) {
+	networks, err := c.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the list of networks: %v", err)
+	}
+	var ids []string
+	for _, network := range networks.Networks {
+		if *network.NetworkID == v {
+			return c.Get(v)
+		}
+		if *network.Name == v {
+			ids = append(ids, *network.NetworkID)
+		}
+	}
+	switch len(ids) {
+	case 0:
+		return nil, fmt.Errorf("network: %s not found", v)
+	case 1:
+		return c.Get(ids[0])
+	}
+	return nil, fmt.Errorf("more than one network found with the name: %s, use the ID instead: [%s]", v, strings.Join(ids, ", "))
+}
+
 func (c *Client) Delete(id string) error {
-	return c.client.Delete(id, c.instanceID, pkg.TIMEOUT)
+	return c.client.Delete(id, c.instanceID, pkg.Options.APITimeout)
 }
 
 func (c *Client) GetAllPurgeable(before, since time.Duration, expr string) ([]*models.NetworkReference, error) {
@@ -79,23 +111,68 @@ func (c *Client) GetAllPurgeable(before, since time.Duration, expr string) ([]*m
 				continue
 			}
 		}
+		if ok, err := c.filter.Matches("network", *network.NetworkID); err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
 		candidates = append(candidates, network)
 	}
 	return candidates, nil
 }
 
 func (c *Client) CreatePort(id string, params *p_cloud_networks.PcloudNetworksPortsPostParams) (*models.NetworkPort, error) {
-	return c.client.CreatePort(id, c.instanceID, params, pkg.TIMEOUT)
+	return c.client.CreatePort(id, c.instanceID, params, pkg.Options.APITimeout)
 }
 
 func (c *Client) DeletePort(id, portID string) (*models.Object, error) {
-	return c.client.DeletePort(id, c.instanceID, portID, pkg.TIMEOUT)
+	return c.client.DeletePort(id, c.instanceID, portID, pkg.Options.APITimeout)
 }
 
 func (c *Client) GetPort(id, portID string) (*models.NetworkPort, error) {
-	return c.client.GetPort(id, c.instanceID, portID, pkg.TIMEOUT)
+	return c.client.GetPort(id, c.instanceID, portID, pkg.Options.APITimeout)
 }
 
 func (c *Client) GetAllPort(id string) (*models.NetworkPorts, error) {
-	return c.client.GetAllPort(id, c.instanceID, pkg.TIMEOUT)
+	return c.client.GetAllPort(id, c.instanceID, pkg.Options.APITimeout)
+}
+
+// GetAllPurgeablePorts returns the ports of the network which aren't attached to any vm, expr is matched against the
+// IP address and descExpr against the description of the ports
+func (c *Client) GetAllPurgeablePorts(id, expr, descExpr string) ([]*models.NetworkPort, error) {
+	ports, err := c.GetAllPort(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the list of ports: %v", err)
+	}
+	return filterPorts(ports.Ports, expr, descExpr)
+}
+
+func filterPorts(ports []*models.NetworkPort, expr, descExpr string) ([]*models.NetworkPort, error) {
+	r, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression: %v", err)
+	}
+	d, err := regexp.Compile(descExpr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid description regular expression: %v", err)
+	}
+
+	var candidates []*models.NetworkPort
+	for _, port := range ports {
+		if port.PvmInstance != nil && port.PvmInstance.PvmInstanceID != "" {
+			continue
+		}
+		if port.IPAddress == nil || !r.MatchString(*port.IPAddress) {
+			continue
+		}
+		var description string
+		if port.Description != nil {
+			description = *port.Description
+		}
+		if !d.MatchString(description) {
+			continue
+		}
+		candidates = append(candidates, port)
+	}
+	return candidates, nil
 }
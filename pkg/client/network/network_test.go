@@ -0,0 +1,66 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package network
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/IBM-Cloud/power-go-client/power/models"
+)
+
+func port(id, ip, description, vm string) *models.NetworkPort {
+	p := &models.NetworkPort{PortID: &id, IPAddress: &ip, Description: &description}
+	if vm != "" {
+		p.PvmInstance = &models.NetworkPortPvmInstance{PvmInstanceID: vm}
+	}
+	return p
+}
+
+func Test_filterPorts(t *testing.T) {
+	ports := []*models.NetworkPort{
+		port("port-1", "192.168.0.10", "bootstrap", ""),
+		port("port-2", "192.168.0.11", "master", "vm-1"),
+		port("port-3", "192.168.1.12", "", ""),
+		port("port-4", "192.168.0.13", "master", ""),
+	}
+	tests := []struct {
+		name     string
+		expr     string
+		descExpr string
+		want     []string
+		wantErr  bool
+	}{
+		{"all the unattached ports", "", "", []string{"port-1", "port-3", "port-4"}, false},
+		{"by ip address", `^192\.168\.0\.`, "", []string{"port-1", "port-4"}, false},
+		{"by description", "", "^master$", []string{"port-4"}, false},
+		{"invalid regexp", "[", "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := filterPorts(ports, tt.expr, tt.descExpr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("filterPorts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var ids []string
+			for _, p := range got {
+				ids = append(ids, *p.PortID)
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("filterPorts() = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
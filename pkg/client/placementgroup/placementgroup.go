@@ -0,0 +1,66 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package placementgroup
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/IBM-Cloud/power-go-client/clients/instance"
+	"github.com/IBM-Cloud/power-go-client/ibmpisession"
+	"github.com/IBM-Cloud/power-go-client/power/models"
+)
+
+type Client struct {
+	client     *instance.IBMPIPlacementGroupClient
+	instanceID string
+}
+
+func NewClient(sess *ibmpisession.IBMPISession, powerinstanceid string) *Client {
+	return &Client{
+		client:     instance.NewIBMPIPlacementGroupClient(sess, powerinstanceid),
+		instanceID: powerinstanceid,
+	}
+}
+
+func (c *Client) Get(id string) (*models.PlacementGroup, error) {
+	return c.client.Get(id, c.instanceID)
+}
+
+func (c *Client) GetAll() (*models.PlacementGroups, error) {
+	return c.client.GetAll(c.instanceID)
+}
+
+func (c *Client) Delete(id string) error {
+	return c.client.Delete(id, c.instanceID)
+}
+
+// GetAllPurgeable returns the placement groups matching the regular expression(all if nil), placement groups don't carry any
+// date hence no age filter
+func (c *Client) GetAllPurgeable(expr *regexp.Regexp) ([]*models.PlacementGroup, error) {
+	groups, err := c.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the list of placement groups: %v", err)
+	}
+
+	var candidates []*models.PlacementGroup
+	for _, group := range groups.PlacementGroups {
+		if expr != nil && !expr.MatchString(*group.Name) {
+			continue
+		}
+		candidates = append(candidates, group)
+	}
+	return candidates, nil
+}
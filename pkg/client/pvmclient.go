@@ -16,27 +16,35 @@ package client
 
 import (
 	"fmt"
-	"k8s.io/klog/v2"
-	"os"
-	"time"
+	"net/http"
 
-	"github.com/IBM-Cloud/bluemix-go/api/resource/resourcev2/controllerv2"
+	"github.com/IBM-Cloud/bluemix-go/crn"
 	"github.com/IBM-Cloud/power-go-client/ibmpisession"
 	"github.com/ppc64le-cloud/powervs-utils"
 
 	"github.com/ppc64le-cloud/pvsadm/pkg"
+	"github.com/ppc64le-cloud/pvsadm/pkg/client/cloudconnection"
 	"github.com/ppc64le-cloud/pvsadm/pkg/client/events"
 	"github.com/ppc64le-cloud/pvsadm/pkg/client/image"
 	"github.com/ppc64le-cloud/pvsadm/pkg/client/instance"
 	"github.com/ppc64le-cloud/pvsadm/pkg/client/network"
+	"github.com/ppc64le-cloud/pvsadm/pkg/client/placementgroup"
+	"github.com/ppc64le-cloud/pvsadm/pkg/client/sharedprocessorpool"
+	"github.com/ppc64le-cloud/pvsadm/pkg/client/snapshot"
+	"github.com/ppc64le-cloud/pvsadm/pkg/client/sshkey"
+	"github.com/ppc64le-cloud/pvsadm/pkg/client/tagging"
 	"github.com/ppc64le-cloud/pvsadm/pkg/client/volume"
 )
 
+// PowerVSServiceType is the service name of the PowerVS service instances
+const PowerVSServiceType = "power-iaas"
+
 type PVMClient struct {
 	InstanceName string
 	InstanceID   string
 	Region       string
 	Zone         string
+	CRN          crn.CRN
 
 	PISession      *ibmpisession.IBMPISession
 	InstanceClient *instance.Client
@@ -44,48 +52,42 @@ type PVMClient struct {
 	VolumeClient   *volume.Client
 	NetworkClient  *network.Client
 	EventsClient   *events.Client
+
+	SnapshotClient            *snapshot.Client
+	SSHKeyClient              *sshkey.Client
+	CloudConnectionClient     *cloudconnection.Client
+	PlacementGroupClient      *placementgroup.Client
+	SharedProcessorPoolClient *sharedprocessorpool.Client
+
+	tagger *tagging.Client
 }
 
 func NewPVMClient(c *Client, instanceID, instanceName, ep string) (*PVMClient, error) {
-	pvmclient := &PVMClient{}
-	if instanceID == "" {
-		svcs, err := c.ResourceClientV2.ListInstances(controllerv2.ServiceInstanceQuery{
-			Type: "service_instance",
-		})
-		if err != nil {
-			return pvmclient, fmt.Errorf("failed to list the resource instances: %v", err)
-		}
-		found := false
-		for _, svc := range svcs {
-			klog.V(4).Infof("Service ID: %s, region_id: %s, Name: %s", svc.Guid, svc.RegionID, svc.Name)
-			klog.V(4).Infof("crn: %v", svc.Crn)
-			if svc.Name == instanceName {
-				instanceID = svc.Guid
-				found = true
-				break
-			}
-		}
-		if !found {
-			return nil, fmt.Errorf("instance: %s not found", instanceName)
-		}
+	ins, err := c.LookupInstance(instanceID, instanceName)
+	if err != nil {
+		return nil, err
 	}
+	return NewPVMClientForInstance(c, ins, ep)
+}
 
-	pvmclient.InstanceID = instanceID
-	svc, err := c.ResourceClientV2.GetInstance(instanceID)
+// NewPVMClientForInstance returns the client for the PowerVS instance already looked up
+func NewPVMClientForInstance(c *Client, ins *Instance, ep string) (*PVMClient, error) {
+	var err error
+	instanceID := ins.ID
+	pvmclient := &PVMClient{}
+	pvmclient.InstanceID = ins.ID
+	pvmclient.InstanceName = ins.Name
+	pvmclient.Zone = ins.Zone
+	pvmclient.CRN, err = crn.Parse(ins.CRN)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get a service with ID: %s, err: %v", instanceID, err)
+		return nil, fmt.Errorf("failed to parse the crn of the instance: %s, err: %v", ins.Name, err)
 	}
-
-	pvmclient.InstanceName = svc.Name
-	pvmclient.Zone = svc.RegionID
 	pvmclient.Region, err = utils.GetRegion(pvmclient.Zone)
 	if err != nil {
 		return nil, err
 	}
 
-	os.Setenv("IBMCLOUD_POWER_API_ENDPOINT", fmt.Sprintf("%s.%s", pvmclient.Region, ep))
-
-	pvmclient.PISession, err = ibmpisession.New(c.Config.IAMAccessToken, pvmclient.Region, pkg.Options.Debug, 60*time.Minute, c.User.Account, pvmclient.Zone)
+	pvmclient.PISession, err = newPISession(c, RegionalEndpoint(ep, pvmclient.Region), pvmclient.Region, pvmclient.Zone)
 	if err != nil {
 		return nil, err
 	}
@@ -95,5 +97,46 @@ func NewPVMClient(c *Client, instanceID, instanceName, ep string) (*PVMClient, e
 	pvmclient.InstanceClient = instance.NewClient(pvmclient.PISession, instanceID)
 	pvmclient.NetworkClient = network.NewClient(pvmclient.PISession, instanceID)
 	pvmclient.EventsClient = events.NewClient(pvmclient.PISession, instanceID)
+	pvmclient.SnapshotClient = snapshot.NewClient(pvmclient.PISession, instanceID)
+	pvmclient.SSHKeyClient = sshkey.NewClient(pvmclient.PISession, instanceID)
+	pvmclient.CloudConnectionClient = cloudconnection.NewClient(pvmclient.PISession, instanceID)
+	pvmclient.PlacementGroupClient = placementgroup.NewClient(pvmclient.PISession, instanceID)
+	pvmclient.SharedProcessorPoolClient = sharedprocessorpool.NewClient(pvmclient.PISession, instanceID)
 	return pvmclient, nil
 }
+
+// setTagger sets the global tagging client used for resolving the tags of the resources
+func (pvmclient *PVMClient) setTagger(c *Client, endpoint string) (err error) {
+	pvmclient.tagger, err = tagging.NewClient(c.TokenSource.CoreAuthenticator(), endpoint, &http.Client{Transport: c.transport(nil)})
+	if err != nil {
+		return fmt.Errorf("failed to create the global tagging client: %v", err)
+	}
+	return nil
+}
+
+// NewTagFilter returns a filter matching the resources of the instance by the selector
+func (pvmclient *PVMClient) NewTagFilter(selector pkg.Selector) (*tagging.Filter, error) {
+	if pvmclient.tagger == nil {
+		return nil, fmt.Errorf("global tagging client is not configured for the instance: %s", pvmclient.InstanceName)
+	}
+	return tagging.NewFilter(pvmclient.tagger, selector, pvmclient.CRN), nil
+}
+
+// setSelector filters the resources returned by the GetAllPurgeable methods by the tags
+func (pvmclient *PVMClient) setSelector(s string) error {
+	selector, err := pkg.ParseSelector(s)
+	if err != nil {
+		return err
+	}
+	filter, err := pvmclient.NewTagFilter(selector)
+	if err != nil {
+		return err
+	}
+	pvmclient.InstanceClient.SetFilter(filter)
+	pvmclient.ImgClient.SetFilter(filter)
+	pvmclient.VolumeClient.SetFilter(filter)
+	pvmclient.NetworkClient.SetFilter(filter)
+	pvmclient.SnapshotClient.SetFilter(filter)
+	pvmclient.CloudConnectionClient.SetFilter(filter)
+	return nil
+}
@@ -0,0 +1,144 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg"
+)
+
+var (
+	// retryBaseDelay is the delay before the first retry, doubled for every retry
+	retryBaseDelay = time.Second
+	// retryMaxDelay caps the backoff and the Retry-After delays
+	retryMaxDelay = 2 * time.Minute
+)
+
+// RetryTransport returns the round tripper retrying the requests failed with 429, 5xx or the network errors up to the
+// --max-retries times, with the exponential backoff and jitter or the delay asked by the Retry-After header. Requests
+// of the non-idempotent methods are retried only if the server hasn't processed them, i.e. 429 and 503.
+func RetryTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &retryTransport{base: base}
+}
+
+type retryTransport struct {
+	base http.RoundTripper
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		r := req
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r = req.Clone(req.Context())
+			r.Body = body
+		}
+		resp, err := t.base.RoundTrip(r)
+		if attempt >= pkg.Options.MaxRetries || !retryable(req, resp, err) {
+			return resp, err
+		}
+
+		delay := backoff(attempt)
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = resp.Status
+			if d, ok := retryAfter(resp); ok {
+				delay = d
+			}
+			resp.Body.Close()
+		}
+		klog.V(2).Infof("Retrying %s %s in %s(%d/%d): %s", req.Method, req.URL.Path, delay.Round(time.Millisecond), attempt+1, pkg.Options.MaxRetries, reason)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryable reports whether the request can be retried for the response or the error
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	// body can't be replayed
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	if err != nil {
+		return req.Context().Err() == nil && idempotent(req.Method)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotent(req.Method)
+	}
+	return false
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// backoff returns the exponential delay with the full jitter for the attempt
+func backoff(attempt int) time.Duration {
+	d := retryBaseDelay << uint(attempt)
+	if d <= 0 || d > retryMaxDelay {
+		d = retryMaxDelay
+	}
+	return time.Duration(rand.Int63n(int64(d))) + time.Millisecond
+}
+
+// retryAfter returns the delay asked by the Retry-After header in seconds or HTTP date
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	var d time.Duration
+	if seconds, err := strconv.Atoi(v); err == nil {
+		d = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(v); err == nil {
+		d = time.Until(date)
+	} else {
+		return 0, false
+	}
+	if d < 0 {
+		d = 0
+	}
+	if d > retryMaxDelay {
+		d = retryMaxDelay
+	}
+	return d, true
+}
@@ -0,0 +1,116 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg"
+)
+
+func TestRetryTransport(t *testing.T) {
+	defer func(d time.Duration, n int) { retryBaseDelay, pkg.Options.MaxRetries = d, n }(retryBaseDelay, pkg.Options.MaxRetries)
+	retryBaseDelay = time.Millisecond
+	pkg.Options.MaxRetries = 2
+
+	tests := []struct {
+		name         string
+		method       string
+		statuses     []int
+		retryAfter   string
+		wantStatus   int
+		wantRequests int
+	}{
+		{"succeeded after 503", http.MethodGet, []int{503, 200}, "", 200, 2},
+		{"Retry-After honored", http.MethodPost, []int{429, 200}, "0", 200, 2},
+		{"POST not retried on 500", http.MethodPost, []int{500, 200}, "", 500, 1},
+		{"GET retried on 500", http.MethodGet, []int{500, 502, 200}, "", 200, 3},
+		{"retries exhausted", http.MethodPut, []int{503, 503, 503, 200}, "", 503, 3},
+		{"client errors not retried", http.MethodGet, []int{404, 200}, "", 404, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := ioutil.ReadAll(r.Body)
+				if string(body) != "payload" {
+					t.Errorf("body = %q, want %q", body, "payload")
+				}
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(tt.statuses[requests])
+				requests++
+			}))
+			defer server.Close()
+
+			req, err := http.NewRequest(tt.method, server.URL, strings.NewReader("payload"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := (&http.Client{Transport: RetryTransport(nil)}).Do(req)
+			if err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if requests != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", requests, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func Test_retryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   time.Duration
+		wantOk bool
+	}{
+		{"seconds", "7", 7 * time.Second, true},
+		{"capped", "86400", retryMaxDelay, true},
+		{"date in the past", "Mon, 02 Jan 2006 15:04:05 GMT", 0, true},
+		{"missing", "", 0, false},
+		{"invalid", "soon", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}}
+			if tt.header != "" {
+				resp.Header.Set("Retry-After", tt.header)
+			}
+			got, ok := retryAfter(resp)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("retryAfter() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func Test_backoff(t *testing.T) {
+	for attempt := 0; attempt < 40; attempt++ {
+		if d := backoff(attempt); d <= 0 || d > retryMaxDelay+time.Millisecond {
+			t.Errorf("backoff(%d) = %v, want within (0, %v]", attempt, d, retryMaxDelay)
+		}
+	}
+}
@@ -15,24 +15,21 @@
 package client
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"os"
+	"net/http"
 	"regexp"
-	"time"
 
 	"github.com/IBM-Cloud/bluemix-go/api/resource/resourcev2/controllerv2"
 	"github.com/IBM/ibm-cos-sdk-go/aws"
-	"github.com/IBM/ibm-cos-sdk-go/aws/credentials/ibmiam"
 	"github.com/IBM/ibm-cos-sdk-go/aws/session"
 	"github.com/IBM/ibm-cos-sdk-go/service/s3"
-	"github.com/IBM/ibm-cos-sdk-go/service/s3/s3manager"
 	"github.com/ppc64le-cloud/pvsadm/pkg"
 	"k8s.io/klog/v2"
 )
 
 type S3Client struct {
-	ApiKey       string
 	InstanceName string
 	InstanceID   string
 	Region       string
@@ -41,12 +38,8 @@ type S3Client struct {
 	S3Session    *s3.S3
 }
 
-const (
-	AuthEndpoint = "https://iam.cloud.ibm.com/identity/token"
-)
-
-//Func NewS3Client accepts apikey, instanceid of the IBM COS instance and return the s3 client
-//to perform different s3 operations like upload, delete etc.,
+// Func NewS3Client accepts apikey, instanceid of the IBM COS instance and return the s3 client
+// to perform different s3 operations like upload, delete etc.,
 func NewS3Client(c *Client, instanceName, region string) (s3client *S3Client, err error) {
 	s3client = &S3Client{}
 	var instanceID string
@@ -72,26 +65,30 @@ func NewS3Client(c *Client, instanceName, region string) (s3client *S3Client, er
 	}
 	s3client.InstanceID = instanceID
 
-	if pkg.Options.APIKey == "" {
-		s3client.ApiKey = os.Getenv("IBMCLOUD_API_KEY")
-	} else {
-		s3client.ApiKey = pkg.Options.APIKey
+	env := c.Environment
+	if env == nil {
+		if env, err = GetEnvironment(DefaultEnv); err != nil {
+			return nil, err
+		}
 	}
-	s3client.SvcEndpoint = fmt.Sprintf("https://s3.%s.cloud-object-storage.appdomain.cloud", region)
+	s3client.SvcEndpoint = RegionalEndpoint(env[COSEndpoint], region)
 	s3client.StorageClass = fmt.Sprintf("%s-standard", region)
 	conf := aws.NewConfig().
 		WithRegion(s3client.StorageClass).
 		WithEndpoint(s3client.SvcEndpoint).
-		WithCredentials(ibmiam.NewStaticCredentials(aws.NewConfig(), AuthEndpoint, s3client.ApiKey, s3client.InstanceID)).
+		WithCredentials(c.TokenSource.COSCredentials(s3client.InstanceID)).
+		WithHTTPClient(&http.Client{Transport: contextTransport(c.Context, c.TokenSource.Transport(nil))}).
+		WithMaxRetries(pkg.Options.MaxRetries).
 		WithS3ForcePathStyle(true)
 
 	// Create client connection
-	sess := session.Must(session.NewSession()) // Creating a new session
-	s3client.S3Session = s3.New(sess, conf)    // Creating a new client
+	// own HTTP client, the session otherwise sets the transport of the http.DefaultClient for the AWS_CA_BUNDLE
+	sess := session.Must(session.NewSession(aws.NewConfig().WithHTTPClient(&http.Client{})))
+	s3client.S3Session = s3.New(sess, conf)
 	return s3client, nil
 }
 
-//Func CheckBucketExists will verify for the existence of the bucket in the particular account
+// Func CheckBucketExists will verify for the existence of the bucket in the particular account
 func (c *S3Client) CheckBucketExists(bucketName string) (bool, error) {
 	result, err := c.S3Session.ListBuckets(nil)
 	if err != nil {
@@ -134,7 +131,30 @@ func (c *S3Client) SelectObjects(bucketName string, regex string) ([]string, err
 	return matchedObjects, err
 }
 
-//Func CheckBucketLocationConstraint will verify the existence of the bucket in the particular locationConstraint
+// ObjectSizes returns the sizes of the objects matching the regex in the bucket, keyed by the object name
+func (c *S3Client) ObjectSizes(bucketName string, regex string) (map[string]int64, error) {
+	r, err := regexp.Compile(regex)
+	if err != nil {
+		return nil, err
+	}
+	sizes := map[string]int64{}
+	err = c.S3Session.ListObjectsPages(&s3.ListObjectsInput{
+		Bucket: &bucketName,
+	}, func(p *s3.ListObjectsOutput, last bool) bool {
+		for _, obj := range p.Contents {
+			if r.MatchString(*obj.Key) {
+				sizes[*obj.Key] = aws.Int64Value(obj.Size)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sizes, nil
+}
+
+// Func CheckBucketLocationConstraint will verify the existence of the bucket in the particular locationConstraint
 func (c *S3Client) CheckBucketLocationConstraint(bucketName string, bucketLocationConstraint string) (bool, error) {
 
 	getParams := &s3.GetBucketLocationInput{
@@ -167,7 +187,7 @@ func (c *S3Client) CheckIfObjectExists(bucketName, objectName string) bool {
 	return true
 }
 
-//To create a new bucket in the provided instance
+// To create a new bucket in the provided instance
 func (c *S3Client) CreateBucket(bucketName string) error {
 	_, err := c.S3Session.CreateBucket(&s3.CreateBucketInput{
 		Bucket: aws.String(bucketName), // New Bucket Name
@@ -185,7 +205,7 @@ func (c *S3Client) CreateBucket(bucketName string) error {
 	return err
 }
 
-//To copy the object from src bucket to target bucket
+// To copy the object from src bucket to target bucket
 func (c *S3Client) CopyObjectToBucket(srcBucketName string, destBucketName string, objectName string) error {
 	copyParams := s3.CopyObjectInput{
 		Bucket:     aws.String(destBucketName),
@@ -202,34 +222,8 @@ func (c *S3Client) CopyObjectToBucket(srcBucketName string, destBucketName strin
 	return err
 }
 
-//To upload a object to S3 bucket
-func (c *S3Client) UploadObject(fileName, objectName, bucketName string) error {
-	klog.Infof("uploading the file %s\n", fileName)
-	//Read the content of the file
-	file, err := os.Open(fileName)
-	if err != nil {
-		return fmt.Errorf("err opening file %s: %s", fileName, err)
-	}
-	defer file.Close()
-
-	// Create an uploader with S3 client
-	uploader := s3manager.NewUploaderWithClient(c.S3Session, func(u *s3manager.Uploader) {
-		u.PartSize = 64 * 1024 * 1024
-	})
-
-	// Upload input parameters
-	upParams := &s3manager.UploadInput{
-		Bucket: aws.String(bucketName),
-		Key:    aws.String(objectName),
-		Body:   file,
-	}
-
-	// Perform an upload.
-	startTime := time.Now()
-	result, err := uploader.Upload(upParams)
-	if err != nil {
-		return err
-	}
-	klog.Infof("Upload completed successfully in %f seconds to location %s\n", time.Since(startTime).Seconds(), result.Location)
-	return err
+// To upload a object to S3 bucket with the default UploadOptions, upload is aborted along with the ctx and resumed
+// by the next upload of the same file to the object
+func (c *S3Client) UploadObject(ctx context.Context, fileName, objectName, bucketName string) error {
+	return c.UploadObjectWithOptions(ctx, fileName, objectName, bucketName, UploadOptions{})
 }
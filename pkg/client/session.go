@@ -0,0 +1,189 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/IBM-Cloud/power-go-client/ibmpisession"
+	"github.com/IBM-Cloud/power-go-client/power/client"
+	"github.com/go-openapi/runtime"
+	httptransport "github.com/go-openapi/runtime/client"
+	"k8s.io/klog/v2"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg"
+)
+
+// Transport returns the round tripper authenticating the bearer token requests with the token of the TokenSource,
+// the request is retried once with a new token if the token is rejected with 401
+func (s *TokenSource) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &authTransport{source: s, base: base}
+}
+
+// HTTPClient returns the HTTP client with the Transport over the RetryTransport
+func (s *TokenSource) HTTPClient() *http.Client {
+	return &http.Client{Transport: s.Transport(RetryTransport(nil))}
+}
+
+type authTransport struct {
+	source *TokenSource
+	base   http.RoundTripper
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// requests with the other credentials like the HMAC keys or the IAM grants are passed through
+	if !strings.HasPrefix(req.Header.Get("Authorization"), "Bearer ") {
+		return t.base.RoundTrip(req)
+	}
+	token, err := t.source.Token()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(withToken(req, token))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	// request body can't be replayed
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return resp, nil
+	}
+
+	newToken, err := t.source.Refresh(token)
+	if err != nil || newToken == token {
+		klog.V(2).Infof("IAM access token rejected and can't be refreshed: %v", err)
+		return resp, nil
+	}
+	retry := withToken(req, newToken)
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return resp, nil
+		}
+	}
+	resp.Body.Close()
+	klog.V(2).Infof("Retrying %s %s with a new IAM access token", req.Method, req.URL.Path)
+	return t.base.RoundTrip(retry)
+}
+
+func withToken(req *http.Request, token string) *http.Request {
+	r := req.Clone(req.Context())
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
+
+// transport returns the round tripper bound to the context of the client, authenticating with the TokenSource and
+// retrying with the RetryTransport over the base
+func (c *Client) transport(base http.RoundTripper) http.RoundTripper {
+	return contextTransport(c.Context, c.TokenSource.Transport(RetryTransport(base)))
+}
+
+// contextTransport returns the round tripper cancelling the requests along with the ctx, in addition to the context
+// of the request itself. Needed for the SDKs which don't take the context for the API calls.
+func contextTransport(ctx context.Context, base http.RoundTripper) http.RoundTripper {
+	if ctx == nil || ctx.Done() == nil {
+		return base
+	}
+	return &ctxTransport{ctx: ctx, base: base}
+}
+
+type ctxTransport struct {
+	ctx  context.Context
+	base http.RoundTripper
+}
+
+func (t *ctxTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.ctx.Err(); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(req.Context())
+	go func() {
+		select {
+		case <-t.ctx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// response body is read after the round trip, hence cancelled only once closed
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// newPISession returns the PowerVS session for the host authenticated by the TokenSource of the client. Replaces the
+// ibmpisession.New which takes the endpoint from the environment and captures the token at the start.
+func newPISession(c *Client, host, region, zone string) (*ibmpisession.IBMPISession, error) {
+	token, err := c.TokenSource.Token()
+	if err != nil {
+		return nil, err
+	}
+	transport := httptransport.New(host, "/", []string{"https"})
+	transport.Transport = c.transport(nil)
+	if c.Context != nil {
+		transport.Context = c.Context
+	}
+	transport.Debug = pkg.Options.Debug
+	transport.Consumers[runtime.JSONMime] = powerJSONConsumer()
+	return &ibmpisession.IBMPISession{
+		IAMToken:    "Bearer " + token,
+		UserAccount: c.User.Account,
+		Region:      region,
+		Zone:        zone,
+		Power:       client.New(transport, nil),
+		Timeout:     pkg.Options.APITimeout,
+	}, nil
+}
+
+// powerJSONConsumer decodes the PowerVS responses tolerating the null and malformed bodies, same as the consumer of
+// the ibmpisession
+func powerJSONConsumer() runtime.Consumer {
+	return runtime.ConsumerFunc(func(reader io.Reader, data interface{}) error {
+		buf := new(bytes.Buffer)
+		if _, err := buf.ReadFrom(reader); err != nil {
+			return err
+		}
+		b := buf.Bytes()
+		if len(b) == 0 || string(b) == "null" {
+			return nil
+		}
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber() // preserve number formats
+		if err := dec.Decode(data); err != nil {
+			klog.V(4).Infof("failed to decode the response: %v", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,124 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenSource_Transport(t *testing.T) {
+	var authorizations, bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+		bodies = append(bodies, string(body))
+		// first token is revoked
+		if r.Header.Get("Authorization") == "Bearer token-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name               string
+		authorization      string
+		body               string
+		wantStatus         int
+		wantAuthorizations []string
+	}{
+		{"retried with a new token", "Bearer stale", "", http.StatusOK, []string{"Bearer token-1", "Bearer token-2"}},
+		{"body replayed", "Bearer stale", "payload", http.StatusOK, []string{"Bearer token-1", "Bearer token-2"}},
+		{"other credentials passed through", "Basic Yng6Yng=", "", http.StatusOK, []string{"Basic Yng6Yng="}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authorizations, bodies = nil, nil
+			client := NewTokenSource(&fakeAuthenticator{ttl: time.Hour}, "").HTTPClient()
+			req, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", tt.authorization)
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if strings.Join(authorizations, ",") != strings.Join(tt.wantAuthorizations, ",") {
+				t.Errorf("authorizations = %v, want %v", authorizations, tt.wantAuthorizations)
+			}
+			for _, b := range bodies {
+				if b != tt.body {
+					t.Errorf("body = %q, want %q", b, tt.body)
+				}
+			}
+		})
+	}
+}
+
+func TestTokenSource_Refresh(t *testing.T) {
+	s := NewTokenSource(&fakeAuthenticator{ttl: time.Hour}, "")
+	stale, _ := s.Token()
+	fresh, err := s.Refresh(stale)
+	if err != nil || fresh == stale {
+		t.Fatalf("Refresh() = %v, %v, want a new token", fresh, err)
+	}
+	// token already refreshed by another request is reused
+	if got, _ := s.Refresh(stale); got != fresh {
+		t.Errorf("Refresh() = %v, want %v", got, fresh)
+	}
+}
+
+func Test_contextTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-r.Context().Done()
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client := &http.Client{Transport: contextTransport(ctx, http.DefaultTransport)}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "ok" {
+		t.Errorf("body = %q, want %q", body, "ok")
+	}
+
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if _, err := client.Get(server.URL + "/slow"); err == nil {
+		t.Errorf("Get() in-flight request is not cancelled along with the context")
+	}
+	if _, err := client.Get(server.URL); err == nil {
+		t.Errorf("Get() request is sent after the context is cancelled")
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sharedprocessorpool
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/IBM-Cloud/power-go-client/ibmpisession"
+	"github.com/IBM-Cloud/power-go-client/power/models"
+	"github.com/go-openapi/runtime"
+	"github.com/go-openapi/strfmt"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg"
+)
+
+// power-go-client in use doesn't cover the shared processor pools API, hence the operations are submitted over the
+// transport of the session
+const (
+	poolsPath = "/pcloud/v1/cloud-instances/{cloud_instance_id}/shared-processor-pools"
+	poolPath  = poolsPath + "/{shared_processor_pool_id}"
+)
+
+// SharedProcessorPool is the shared processor pool of the PowerVS instance
+type SharedProcessorPool struct {
+	ID             *string `json:"id"`
+	Name           *string `json:"name"`
+	HostGroup      string  `json:"hostGroup,omitempty"`
+	ReservedCores  int64   `json:"reservedCores"`
+	AllocatedCores float64 `json:"allocatedCores"`
+	AvailableCores int64   `json:"availableCores"`
+	Status         string  `json:"status,omitempty"`
+}
+
+// SharedProcessorPoolServer is a vm placed in the shared processor pool
+type SharedProcessorPoolServer struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// SharedProcessorPoolDetail is the shared processor pool along with the vms placed in it
+type SharedProcessorPoolDetail struct {
+	SharedProcessorPool *SharedProcessorPool         `json:"sharedProcessorPool"`
+	Servers             []*SharedProcessorPoolServer `json:"servers"`
+}
+
+type sharedProcessorPools struct {
+	SharedProcessorPools []*SharedProcessorPool `json:"sharedProcessorPools"`
+}
+
+type Client struct {
+	session    *ibmpisession.IBMPISession
+	instanceID string
+}
+
+func NewClient(sess *ibmpisession.IBMPISession, powerinstanceid string) *Client {
+	return &Client{
+		session:    sess,
+		instanceID: powerinstanceid,
+	}
+}
+
+func (c *Client) Get(id string) (*SharedProcessorPoolDetail, error) {
+	detail := &SharedProcessorPoolDetail{}
+	if err := c.submit("pcloud.sharedprocessorpools.get", http.MethodGet, poolPath, id, detail); err != nil {
+		return nil, err
+	}
+	return detail, nil
+}
+
+func (c *Client) GetAll() ([]*SharedProcessorPool, error) {
+	pools := &sharedProcessorPools{}
+	if err := c.submit("pcloud.sharedprocessorpools.getall", http.MethodGet, poolsPath, "", pools); err != nil {
+		return nil, err
+	}
+	return pools.SharedProcessorPools, nil
+}
+
+func (c *Client) Delete(id string) error {
+	return c.submit("pcloud.sharedprocessorpools.delete", http.MethodDelete, poolPath, id, nil)
+}
+
+// GetAllPurgeable returns the shared processor pools matching the regular expression(all if nil), shared processor pools don't
+// carry any date hence no age filter
+func (c *Client) GetAllPurgeable(expr *regexp.Regexp) ([]*SharedProcessorPool, error) {
+	pools, err := c.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the list of shared processor pools: %v", err)
+	}
+
+	var candidates []*SharedProcessorPool
+	for _, pool := range pools {
+		if expr != nil && !expr.MatchString(*pool.Name) {
+			continue
+		}
+		candidates = append(candidates, pool)
+	}
+	return candidates, nil
+}
+
+// submit sends the operation for the pool ID(empty for the operations on all the pools) and decodes the response into
+// the result, the failures are returned as the runtime.APIError along with the status code
+func (c *Client) submit(op, method, path, id string, result interface{}) error {
+	_, err := c.session.Power.Transport.Submit(&runtime.ClientOperation{
+		ID:                 op,
+		Method:             method,
+		PathPattern:        path,
+		ProducesMediaTypes: []string{runtime.JSONMime},
+		ConsumesMediaTypes: []string{runtime.JSONMime},
+		Schemes:            []string{"https"},
+		Params: runtime.ClientRequestWriterFunc(func(r runtime.ClientRequest, _ strfmt.Registry) error {
+			if err := r.SetTimeout(pkg.Options.APITimeout); err != nil {
+				return err
+			}
+			if err := r.SetPathParam("cloud_instance_id", c.instanceID); err != nil {
+				return err
+			}
+			if id != "" {
+				return r.SetPathParam("shared_processor_pool_id", id)
+			}
+			return nil
+		}),
+		Reader: runtime.ClientResponseReaderFunc(func(resp runtime.ClientResponse, consumer runtime.Consumer) (interface{}, error) {
+			if resp.Code() != http.StatusOK && resp.Code() != http.StatusAccepted {
+				payload := &models.Error{}
+				if err := consumer.Consume(resp.Body(), payload); err != nil {
+					return nil, runtime.NewAPIError(op, resp.Message(), resp.Code())
+				}
+				return nil, runtime.NewAPIError(op, payload, resp.Code())
+			}
+			if result == nil {
+				return nil, nil
+			}
+			return nil, consumer.Consume(resp.Body(), result)
+		}),
+		AuthInfo: ibmpisession.NewAuth(c.session, c.instanceID),
+	})
+	return err
+}
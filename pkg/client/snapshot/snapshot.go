@@ -0,0 +1,94 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/IBM-Cloud/power-go-client/clients/instance"
+	"github.com/IBM-Cloud/power-go-client/errors"
+	"github.com/IBM-Cloud/power-go-client/ibmpisession"
+	"github.com/IBM-Cloud/power-go-client/power/client/p_cloud_snapshots"
+	"github.com/IBM-Cloud/power-go-client/power/models"
+	"k8s.io/klog/v2"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg"
+	"github.com/ppc64le-cloud/pvsadm/pkg/client/tagging"
+)
+
+type Client struct {
+	session    *ibmpisession.IBMPISession
+	client     *instance.IBMPISnapshotClient
+	instanceID string
+	filter     *tagging.Filter
+}
+
+func NewClient(sess *ibmpisession.IBMPISession, powerinstanceid string) *Client {
+	return &Client{
+		session:    sess,
+		client:     instance.NewIBMPISnapshotClient(sess, powerinstanceid),
+		instanceID: powerinstanceid,
+	}
+}
+
+// SetFilter sets the tag filter applied on top of the regular expression and age in GetAllPurgeable
+func (c *Client) SetFilter(f *tagging.Filter) {
+	c.filter = f
+}
+
+func (c *Client) Get(id string) (*models.Snapshot, error) {
+	return c.client.Get(id, c.instanceID, pkg.Options.APITimeout)
+}
+
+func (c *Client) GetAll() (*models.Snapshots, error) {
+	klog.Infof("Calling the Power Snapshots GetAll Method")
+	params := p_cloud_snapshots.NewPcloudCloudinstancesSnapshotsGetallParamsWithTimeout(pkg.Options.APITimeout).WithCloudInstanceID(c.instanceID)
+	resp, err := c.session.Power.PCloudSnapshots.PcloudCloudinstancesSnapshotsGetall(params, ibmpisession.NewAuth(c.session, c.instanceID))
+	if err != nil {
+		return nil, errors.ToError(err)
+	}
+	return resp.Payload, nil
+}
+
+func (c *Client) Delete(id string) error {
+	return c.client.Delete(id, c.instanceID, pkg.Options.APITimeout)
+}
+
+// GetAllPurgeable returns the snapshots matching the regular expression(all if nil) and the creation date
+func (c *Client) GetAllPurgeable(before, since time.Duration, expr *regexp.Regexp) ([]*models.Snapshot, error) {
+	snapshots, err := c.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the list of snapshots: %v", err)
+	}
+
+	var candidates []*models.Snapshot
+	for _, snapshot := range snapshots.Snapshots {
+		if expr != nil && !expr.MatchString(*snapshot.Name) {
+			continue
+		}
+		if !pkg.IsPurgeable(time.Time(snapshot.CreationDate), before, since) {
+			continue
+		}
+		if ok, err := c.filter.Matches("snapshot", *snapshot.SnapshotID); err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+		candidates = append(candidates, snapshot)
+	}
+	return candidates, nil
+}
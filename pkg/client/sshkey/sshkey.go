@@ -0,0 +1,88 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sshkey
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/IBM-Cloud/power-go-client/clients/instance"
+	"github.com/IBM-Cloud/power-go-client/errors"
+	"github.com/IBM-Cloud/power-go-client/ibmpisession"
+	"github.com/IBM-Cloud/power-go-client/power/client/p_cloud_tenants_ssh_keys"
+	"github.com/IBM-Cloud/power-go-client/power/models"
+	"k8s.io/klog/v2"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg"
+)
+
+// Client manages the SSH keys, keys belong to the tenant(account) and are shared by all the PowerVS instances
+type Client struct {
+	session    *ibmpisession.IBMPISession
+	client     *instance.IBMPIKeyClient
+	instanceID string
+}
+
+func NewClient(sess *ibmpisession.IBMPISession, powerinstanceid string) *Client {
+	return &Client{
+		session:    sess,
+		client:     instance.NewIBMPIKeyClient(sess, powerinstanceid),
+		instanceID: powerinstanceid,
+	}
+}
+
+// Get returns the SSH key by name, SSH keys are identified by the name
+func (c *Client) Get(name string) (*models.SSHKey, error) {
+	return c.client.Get(name, c.instanceID)
+}
+
+func (c *Client) GetAll() (*models.SSHKeys, error) {
+	klog.Infof("Calling the Power SSH Keys GetAll Method")
+	params := p_cloud_tenants_ssh_keys.NewPcloudTenantsSshkeysGetallParamsWithTimeout(pkg.Options.APITimeout).WithTenantID(c.session.UserAccount)
+	resp, err := c.session.Power.PCloudTenantsSSHKeys.PcloudTenantsSshkeysGetall(params, ibmpisession.NewAuth(c.session, c.instanceID))
+	if err != nil {
+		return nil, errors.ToError(err)
+	}
+	return resp.Payload, nil
+}
+
+func (c *Client) Delete(name string) error {
+	return c.client.Delete(name, c.instanceID)
+}
+
+// GetAllPurgeable returns the SSH keys matching the regular expression(all if nil) and the creation date
+func (c *Client) GetAllPurgeable(before, since time.Duration, expr *regexp.Regexp) ([]*models.SSHKey, error) {
+	keys, err := c.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the list of ssh keys: %v", err)
+	}
+
+	var candidates []*models.SSHKey
+	for _, key := range keys.SSHKeys {
+		if expr != nil && !expr.MatchString(*key.Name) {
+			continue
+		}
+		var created time.Time
+		if key.CreationDate != nil {
+			created = time.Time(*key.CreationDate)
+		}
+		if !pkg.IsPurgeable(created, before, since) {
+			continue
+		}
+		candidates = append(candidates, key)
+	}
+	return candidates, nil
+}
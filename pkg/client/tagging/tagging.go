@@ -0,0 +1,193 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagging
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/IBM-Cloud/bluemix-go/crn"
+	"github.com/IBM/go-sdk-core/v4/core"
+	"github.com/IBM/platform-services-go-sdk/globaltaggingv1"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg"
+)
+
+const pageLimit = 1000
+
+// Tagger returns the tags attached to a resource
+type Tagger interface {
+	Tags(crn string) ([]string, error)
+}
+
+// Client resolves the user tags attached to the IBM Cloud resources using the global tagging service
+type Client struct {
+	service *globaltaggingv1.GlobalTaggingV1
+	// mutex guards the cache and the attached, never held across the calls to the service
+	mutex    sync.Mutex
+	cache    map[string][]string
+	attached []string
+}
+
+// NewClient returns the client for the global tagging service, the default HTTP client is used if httpClient is nil
+func NewClient(authenticator core.Authenticator, url string, httpClient *http.Client) (*Client, error) {
+	service, err := globaltaggingv1.NewGlobalTaggingV1(&globaltaggingv1.GlobalTaggingV1Options{
+		URL:           url,
+		Authenticator: authenticator,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if httpClient != nil {
+		service.Service.SetHTTPClient(httpClient)
+	}
+	return &Client{
+		service: service,
+		cache:   map[string][]string{},
+	}, nil
+}
+
+// Tags returns the user tags attached to the resource, results are cached for the lifetime of the client
+func (c *Client) Tags(crn string) ([]string, error) {
+	c.mutex.Lock()
+	tags, ok := c.cache[crn]
+	c.mutex.Unlock()
+	if ok {
+		return tags, nil
+	}
+
+	tags, err := c.listTags(c.service.NewListTagsOptions().SetAttachedTo(crn))
+	if err != nil {
+		return nil, err
+	}
+	c.mutex.Lock()
+	c.cache[crn] = tags
+	c.mutex.Unlock()
+	return tags, nil
+}
+
+// AttachedTags returns the user tags attached to any resource of the account, results are cached for the lifetime
+// of the client
+func (c *Client) AttachedTags() ([]string, error) {
+	c.mutex.Lock()
+	tags := c.attached
+	c.mutex.Unlock()
+	if tags != nil {
+		return tags, nil
+	}
+
+	tags, err := c.listTags(c.service.NewListTagsOptions().SetAttachedOnly(true))
+	if err != nil {
+		return nil, err
+	}
+	if tags == nil {
+		tags = []string{}
+	}
+	c.mutex.Lock()
+	c.attached = tags
+	c.mutex.Unlock()
+	return tags, nil
+}
+
+// listTags returns the names of the user tags listed by the options across all the pages
+func (c *Client) listTags(options *globaltaggingv1.ListTagsOptions) ([]string, error) {
+	var tags []string
+	options.SetTagType(globaltaggingv1.ListTagsOptions_TagType_User).SetLimit(pageLimit)
+	for offset := int64(0); ; offset += pageLimit {
+		list, _, err := c.service.ListTags(options.SetOffset(offset))
+		if err != nil {
+			return nil, err
+		}
+		for _, tag := range list.Items {
+			if tag.Name != nil {
+				tags = append(tags, *tag.Name)
+			}
+		}
+		if len(list.Items) < pageLimit {
+			return tags, nil
+		}
+	}
+}
+
+// AccountTagger returns the tags attached to any resource of the account
+type AccountTagger interface {
+	AttachedTags() ([]string, error)
+}
+
+// Filter selects the resources of a PowerVS instance by their tags
+type Filter struct {
+	tagger      Tagger
+	selector    pkg.Selector
+	instanceCRN crn.CRN
+
+	once sync.Once
+	// none is set when no resource of the account can satisfy the selector
+	none bool
+	err  error
+}
+
+func NewFilter(tagger Tagger, selector pkg.Selector, instanceCRN crn.CRN) *Filter {
+	return &Filter{
+		tagger:      tagger,
+		selector:    selector,
+		instanceCRN: instanceCRN,
+	}
+}
+
+// Matches reports whether the resource of the type(pvm-instance, volume, image, network, snapshot or cloud-connection)
+// and ID satisfies the selector, nil filter matches all the resources
+func (f *Filter) Matches(resourceType, id string) (bool, error) {
+	if f == nil || len(f.selector) == 0 {
+		return true, nil
+	}
+	f.once.Do(f.checkAccount)
+	if f.err != nil {
+		return false, f.err
+	}
+	if f.none {
+		return false, nil
+	}
+	r := f.instanceCRN
+	r.ResourceType, r.Resource = resourceType, id
+	tags, err := f.tagger.Tags(r.String())
+	if err != nil {
+		return false, fmt.Errorf("failed to get the tags of the %s: %s, err: %v", resourceType, id, err)
+	}
+	return f.selector.Matches(tags), nil
+}
+
+// checkAccount sets the none if a requirement asking for a tag is not satisfied by the tags attached in the account,
+// saves a lookup per resource when the tag is not in use, e.g. no resource is tagged with do-not-delete
+func (f *Filter) checkAccount() {
+	account, ok := f.tagger.(AccountTagger)
+	if !ok {
+		return
+	}
+	tags, err := account.AttachedTags()
+	if err != nil {
+		f.err = fmt.Errorf("failed to get the tags attached in the account, err: %v", err)
+		return
+	}
+	for _, r := range f.selector {
+		if r.Operator != pkg.SelectorExists && r.Operator != pkg.SelectorEquals {
+			continue
+		}
+		if !(pkg.Selector{r}).Matches(tags) {
+			f.none = true
+			return
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagging
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/IBM-Cloud/bluemix-go/crn"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg"
+)
+
+type fakeTagger map[string][]string
+
+func (f fakeTagger) Tags(crn string) ([]string, error) {
+	tags, ok := f[crn]
+	if !ok {
+		return nil, errors.New("resource not found")
+	}
+	return tags, nil
+}
+
+func TestFilter_Matches(t *testing.T) {
+	instance, err := crn.Parse("crn:v1:bluemix:public:power-iaas:dal12:a/account-id:instance-id::")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tagger := fakeTagger{
+		"crn:v1:bluemix:public:power-iaas:dal12:a/account-id:instance-id:volume:vol-1":      {"owner:ci"},
+		"crn:v1:bluemix:public:power-iaas:dal12:a/account-id:instance-id:pvm-instance:vm-1": {"owner:alice"},
+	}
+	selector, _ := pkg.ParseSelector("owner=ci")
+
+	tests := []struct {
+		name         string
+		filter       *Filter
+		resourceType string
+		id           string
+		want         bool
+		wantErr      bool
+	}{
+		{"nil filter matches all", nil, "volume", "vol-2", true, false},
+		{"matching tags", NewFilter(tagger, selector, instance), "volume", "vol-1", true, false},
+		{"non matching tags", NewFilter(tagger, selector, instance), "pvm-instance", "vm-1", false, false},
+		{"failed to get the tags", NewFilter(tagger, selector, instance), "image", "img-1", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.filter.Matches(tt.resourceType, tt.id)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Matches() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type accountTagger struct {
+	fakeTagger
+	attached []string
+	lookups  int
+}
+
+func (a *accountTagger) Tags(crn string) ([]string, error) {
+	a.lookups++
+	return a.fakeTagger.Tags(crn)
+}
+
+func (a *accountTagger) AttachedTags() ([]string, error) {
+	return a.attached, nil
+}
+
+func TestFilter_MatchesUnusedTag(t *testing.T) {
+	instance, err := crn.Parse("crn:v1:bluemix:public:power-iaas:dal12:a/account-id:instance-id::")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tagger := &accountTagger{
+		fakeTagger: fakeTagger{"crn:v1:bluemix:public:power-iaas:dal12:a/account-id:instance-id:volume:vol-1": {"owner:ci"}},
+		attached:   []string{"owner:ci"},
+	}
+
+	tests := []struct {
+		selector    string
+		want        bool
+		wantLookups int
+	}{
+		{"do-not-delete", false, 0},
+		{"owner=alice", false, 0},
+		{"owner=ci", true, 1},
+		{"!do-not-delete", true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			tagger.lookups = 0
+			selector, _ := pkg.ParseSelector(tt.selector)
+			got, err := NewFilter(tagger, selector, instance).Matches("volume", "vol-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+			if tagger.lookups != tt.wantLookups {
+				t.Errorf("Matches() looked up the tags %d times, want %d", tagger.lookups, tt.wantLookups)
+			}
+		})
+	}
+}
@@ -23,16 +23,27 @@ import (
 	"github.com/IBM-Cloud/power-go-client/power/models"
 	"github.com/go-openapi/strfmt"
 	"github.com/ppc64le-cloud/pvsadm/pkg"
+	"github.com/ppc64le-cloud/pvsadm/pkg/client/tagging"
 	"k8s.io/klog/v2"
 	"reflect"
 	"regexp"
+	"strings"
 	"time"
 )
 
+const (
+	DateFieldCreation   = "creation"
+	DateFieldLastUpdate = "lastupdate"
+)
+
+// DateFields are the volume dates supported for the age thresholds
+var DateFields = []string{DateFieldCreation, DateFieldLastUpdate}
+
 type Client struct {
 	session    *ibmpisession.IBMPISession
 	client     *instance.IBMPIVolumeClient
 	instanceID string
+	filter     *tagging.Filter
 }
 
 func NewClient(sess *ibmpisession.IBMPISession, powerinstanceid string) *Client {
@@ -44,17 +55,46 @@ func NewClient(sess *ibmpisession.IBMPISession, powerinstanceid string) *Client
 	return c
 }
 
+// SetFilter sets the tag filter applied on top of the regular expression and age in GetAllPurgeable
+func (c *Client) SetFilter(f *tagging.Filter) {
+	c.filter = f
+}
+
 func (c *Client) Get(id string) (*models.Volume, error) {
-	return c.client.Get(id, c.instanceID, pkg.TIMEOUT)
+	return c.client.Get(id, c.instanceID, pkg.Options.APITimeout)
+}
+
+// GetByNameOrID returns the volume by ID or name(preference will be given to the ID over name)
+func (c *Client) GetByNameOrID(v string) (*models.Volume, error) {
+	volumes, err := c.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the list of volumes: %v", err)
+	}
+	var ids []string
+	for _, vol := range volumes.Volumes {
+		if *vol.VolumeID == v {
+			return c.Get(v)
+		}
+		if *vol.Name == v {
+			ids = append(ids, *vol.VolumeID)
+		}
+	}
+	switch len(ids) {
+	case 0:
+		return nil, fmt.Errorf("volume: %s not found", v)
+	case 1:
+		return c.Get(ids[0])
+	}
+	return nil, fmt.Errorf("more than one volume found with the name: %s, use the ID instead: [%s]", v, strings.Join(ids, ", "))
 }
 
 func (c *Client) DeleteVolume(id string) error {
-	return c.client.DeleteVolume(id, c.instanceID, pkg.TIMEOUT)
+	return c.client.DeleteVolume(id, c.instanceID, pkg.Options.APITimeout)
 }
 
 func (c *Client) GetAll() (*models.Volumes, error) {
 	klog.Infof("Calling the Power Volumes GetAll Method")
-	params := p_cloud_volumes.NewPcloudCloudinstancesVolumesGetallParamsWithTimeout(pkg.TIMEOUT).WithCloudInstanceID(c.instanceID)
+	params := p_cloud_volumes.NewPcloudCloudinstancesVolumesGetallParamsWithTimeout(pkg.Options.APITimeout).WithCloudInstanceID(c.instanceID)
 	resp, err := c.session.Power.PCloudVolumes.PcloudCloudinstancesVolumesGetall(params, ibmpisession.NewAuth(c.session, c.instanceID))
 	if err != nil {
 		return nil, errors.ToError(err)
@@ -81,6 +121,11 @@ func (c *Client) getAllPurgeable(field string, before, since time.Duration, expr
 		if !pkg.IsPurgeable(time.Time(*fieldValue.(*strfmt.DateTime)), before, since) {
 			continue
 		}
+		if ok, err := c.filter.Matches("volume", *vol.VolumeID); err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
 		candidates = append(candidates, vol)
 	}
 	return candidates, nil
@@ -90,3 +135,55 @@ func (c *Client) getAllPurgeable(field string, before, since time.Duration, expr
 func (c *Client) GetAllPurgeableByLastUpdateDate(before, since time.Duration, expr string) ([]*models.VolumeReference, error) {
 	return c.getAllPurgeable("LastUpdateDate", before, since, expr)
 }
+
+// Returns all the Purgeable volumes by Creation Date
+func (c *Client) GetAllPurgeableByCreationDate(before, since time.Duration, expr string) ([]*models.VolumeReference, error) {
+	return c.getAllPurgeable("CreationDate", before, since, expr)
+}
+
+// GetAllPurgeableByDateField returns all the Purgeable volumes by the date field, one of the DateFields
+func (c *Client) GetAllPurgeableByDateField(dateField string, before, since time.Duration, expr string) ([]*models.VolumeReference, error) {
+	switch dateField {
+	case DateFieldCreation:
+		return c.GetAllPurgeableByCreationDate(before, since, expr)
+	case DateFieldLastUpdate:
+		return c.GetAllPurgeableByLastUpdateDate(before, since, expr)
+	}
+	return nil, fmt.Errorf("unsupported date field: %s, supported are: [%s]", dateField, strings.Join(DateFields, ", "))
+}
+
+// FilterByState returns the volumes in any of the states, states are compared case insensitively
+func FilterByState(volumes []*models.VolumeReference, states ...string) []*models.VolumeReference {
+	var filtered []*models.VolumeReference
+	for _, vol := range volumes {
+		for _, state := range states {
+			if vol.State != nil && strings.EqualFold(*vol.State, state) {
+				filtered = append(filtered, vol)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// Orphaned returns the volumes attached only to the vms which no longer exist, vms holds the IDs of the
+// existing vms
+func Orphaned(volumes []*models.VolumeReference, vms map[string]bool) []*models.VolumeReference {
+	var orphaned []*models.VolumeReference
+	for _, vol := range volumes {
+		if len(vol.PvmInstanceIds) == 0 {
+			continue
+		}
+		owned := false
+		for _, id := range vol.PvmInstanceIds {
+			if vms[id] {
+				owned = true
+				break
+			}
+		}
+		if !owned {
+			orphaned = append(orphaned, vol)
+		}
+	}
+	return orphaned
+}
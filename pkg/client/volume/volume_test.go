@@ -0,0 +1,68 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package volume
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/IBM-Cloud/power-go-client/power/models"
+)
+
+func vol(id, state string, vms ...string) *models.VolumeReference {
+	return &models.VolumeReference{VolumeID: &id, Name: &id, State: &state, PvmInstanceIds: vms}
+}
+
+func ids(volumes []*models.VolumeReference) []string {
+	var r []string
+	for _, v := range volumes {
+		r = append(r, *v.VolumeID)
+	}
+	return r
+}
+
+func TestFilterByState(t *testing.T) {
+	volumes := []*models.VolumeReference{vol("vol-1", "available"), vol("vol-2", "in-use", "vm-1"), vol("vol-3", "error")}
+	tests := []struct {
+		name   string
+		states []string
+		want   []string
+	}{
+		{"available", []string{"available"}, []string{"vol-1"}},
+		{"case insensitive", []string{"ERROR"}, []string{"vol-3"}},
+		{"multiple states", []string{"available", "error"}, []string{"vol-1", "vol-3"}},
+		{"no match", []string{"creating"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ids(FilterByState(volumes, tt.states...)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FilterByState() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrphaned(t *testing.T) {
+	volumes := []*models.VolumeReference{
+		vol("unattached", "available"),
+		vol("attached", "in-use", "vm-1"),
+		vol("orphaned", "in-use", "deleted-vm"),
+		vol("shared", "in-use", "deleted-vm", "vm-1"),
+	}
+	want := []string{"orphaned"}
+	if got := ids(Orphaned(volumes, map[string]bool{"vm-1": true})); !reflect.DeepEqual(got, want) {
+		t.Errorf("Orphaned() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"k8s.io/klog/v2"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg"
+)
+
+// WorkspaceFields are the columns added by the Workspace, excluded from the table when only one workspace is selected
+var WorkspaceFields = []string{"workspace", "zone"}
+
+// Workspace identifies the PowerVS instance of a resource in the merged results of the multiple instances
+type Workspace struct {
+	Workspace string `json:"workspace"`
+	Zone      string `json:"zone"`
+}
+
+func (pvmclient *PVMClient) Workspace() Workspace {
+	return Workspace{Workspace: pvmclient.InstanceName, Zone: pvmclient.Zone}
+}
+
+// ExcludeWorkspace returns the exclude list for the table along with the WorkspaceFields if there is only one workspace
+func ExcludeWorkspace(pvmclients []*PVMClient, exclude []string) []string {
+	if len(pvmclients) > 1 {
+		return exclude
+	}
+	return append(append([]string{}, exclude...), WorkspaceFields...)
+}
+
+// NewPVMClientsWithEnv returns the clients for all the PowerVS instances selected by the comma separated
+// --instance-id and --instance-name, --instance-regexp or --all-instances options, sorted by the instance name.
+// Clients are created concurrently.
+func NewPVMClientsWithEnv(c *Client, env string) ([]*PVMClient, error) {
+	opt := pkg.Options
+	if !opt.MultipleInstances() {
+		pvmclient, err := NewPVMClientWithEnv(c, opt.InstanceID, opt.InstanceName, env)
+		if err != nil {
+			return nil, err
+		}
+		return []*PVMClient{pvmclient}, nil
+	}
+
+	// instances missing in the cache are listed again once, same as the LookupInstance
+	var selected []Instance
+	refresh := opt.Refresh
+	for {
+		instances, err := c.ListPowerVSInstances(refresh)
+		if err != nil {
+			return nil, err
+		}
+		selected, err = selectInstances(instances, splitList(opt.InstanceID), splitList(opt.InstanceName), opt.InstanceRegexp, opt.AllInstances)
+		if err == nil {
+			break
+		}
+		if refresh || !isNotFound(err) {
+			return nil, err
+		}
+		klog.V(2).Infof("Instances not found in the cache, listing the PowerVS instances again")
+		refresh = true
+	}
+
+	pvmclients := make([]*PVMClient, len(selected))
+	err := ForEach(pvmclients, func(i int, _ *PVMClient) (err error) {
+		// instances are already resolved, looking them up again would list them for every instance with --refresh
+		pvmclients[i], err = newPVMClientForInstance(c, &selected[i], env)
+		if err != nil {
+			return fmt.Errorf("failed to create the client for the instance %s: %v", selected[i].Name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pvmclients, nil
+}
+
+// ForEach calls the fn concurrently for all the clients, returns the first error occurred
+func ForEach(pvmclients []*PVMClient, fn func(i int, pvmclient *PVMClient) error) error {
+	errs := make([]error, len(pvmclients))
+	var wg sync.WaitGroup
+	for i := range pvmclients {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = fn(i, pvmclients[i])
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// selectInstances returns the instances matching any of the IDs, names or the regular expression, or all the instances
+// if all is set. Every ID and name has to match an instance and a name can't match more than one instance.
+func selectInstances(instances []Instance, ids, names []string, expr string, all bool) ([]Instance, error) {
+	var r *regexp.Regexp
+	if expr != "" {
+		var err error
+		if r, err = regexp.Compile(expr); err != nil {
+			return nil, fmt.Errorf("invalid instance regular expression: %v", err)
+		}
+	}
+
+	found := map[string]bool{}
+	byName := map[string][]Instance{}
+	var selected []Instance
+	for _, svc := range instances {
+		match := all
+		if r != nil && r.MatchString(svc.Name) {
+			match = true
+		}
+		for _, id := range ids {
+			if id == svc.ID {
+				found[id], match = true, true
+			}
+		}
+		for _, name := range names {
+			if name == svc.Name {
+				found[name], match = true, true
+				byName[name] = append(byName[name], svc)
+			}
+		}
+		if match {
+			selected = append(selected, svc)
+		}
+	}
+
+	for _, name := range names {
+		if len(byName[name]) > 1 {
+			return nil, ambiguousNameError(name, byName[name])
+		}
+	}
+	var missing []string
+	for _, v := range append(append([]string{}, ids...), names...) {
+		if !found[v] {
+			missing = append(missing, v)
+		}
+	}
+	if len(missing) != 0 {
+		return nil, &notFoundError{fmt.Sprintf("instances not found: [%s]", strings.Join(missing, ", "))}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no instances found")
+	}
+	sort.SliceStable(selected, func(i, j int) bool {
+		return selected[i].Name < selected[j].Name
+	})
+	return selected, nil
+}
+
+// splitList splits the comma separated list, empty entries are ignored
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
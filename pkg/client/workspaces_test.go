@@ -0,0 +1,69 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_selectInstances(t *testing.T) {
+	svcs := []Instance{{ID: "id-3", Name: "ci-tok04"}, {ID: "id-1", Name: "upstream-core"}, {ID: "id-2", Name: "ci-dal12"},
+		{ID: "id-4", Name: "dup"}, {ID: "id-5", Name: "dup"}}
+	tests := []struct {
+		name    string
+		ids     []string
+		names   []string
+		expr    string
+		all     bool
+		want    []string
+		wantErr bool
+	}{
+		{"all instances sorted by name", nil, nil, "", true, []string{"ci-dal12", "ci-tok04", "dup", "dup", "upstream-core"}, false},
+		{"by regexp", nil, nil, "^ci-", false, []string{"ci-dal12", "ci-tok04"}, false},
+		{"by ids and names", []string{"id-1"}, []string{"ci-tok04"}, "", false, []string{"ci-tok04", "upstream-core"}, false},
+		{"regexp along with a name", nil, []string{"upstream-core"}, "dal", false, []string{"ci-dal12", "upstream-core"}, false},
+		{"missing name", nil, []string{"upstream-core", "unknown"}, "", false, nil, true},
+		{"duplicate name", nil, []string{"upstream-core", "dup"}, "", false, nil, true},
+		{"duplicate names by regexp", nil, nil, "^d", false, []string{"dup", "dup"}, false},
+		{"nothing matched", nil, nil, "^prod-", false, nil, true},
+		{"invalid regexp", nil, nil, "[", false, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectInstances(svcs, tt.ids, tt.names, tt.expr, tt.all)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("selectInstances() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var names []string
+			for _, s := range got {
+				names = append(names, s.Name)
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("selectInstances() = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func Test_splitList(t *testing.T) {
+	want := []string{"a", "b"}
+	if got := splitList(" a,, b ,"); !reflect.DeepEqual(got, want) {
+		t.Errorf("splitList() = %v, want %v", got, want)
+	}
+	if got := splitList(""); got != nil {
+		t.Errorf("splitList() = %v, want nil", got)
+	}
+}
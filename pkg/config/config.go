@@ -0,0 +1,206 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	flag "github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// EnvProfile is the environment variable selecting the profile, overridden by the --profile
+	EnvProfile = "PVSADM_PROFILE"
+	// DefaultProfile is the profile created by the config set when no profile is in use
+	DefaultProfile = "default"
+)
+
+// Profile holds the defaults for the global options, flags and environment variables override them
+type Profile struct {
+	APIKey          string `yaml:"api-key,omitempty"`
+	TrustedProfile  string `yaml:"trusted-profile,omitempty"`
+	Account         string `yaml:"account,omitempty"`
+	Env             string `yaml:"env,omitempty"`
+	AuditFile       string `yaml:"audit-file,omitempty"`
+	InstanceName    string `yaml:"instance-name,omitempty"`
+	InstanceID      string `yaml:"instance-id,omitempty"`
+	COSInstanceName string `yaml:"cos-instance-name,omitempty"`
+	Bucket          string `yaml:"bucket,omitempty"`
+	BucketRegion    string `yaml:"bucket-region,omitempty"`
+}
+
+// Config is the pvsadm configuration file with the named profiles
+type Config struct {
+	CurrentProfile string              `yaml:"current-profile,omitempty"`
+	Profiles       map[string]*Profile `yaml:"profiles,omitempty"`
+	// Environments are the user-defined IBM Cloud environments, the endpoints of the environment by the name
+	Environments map[string]map[string]string `yaml:"environments,omitempty"`
+}
+
+// key is a profile setting along with the flags it's the default for
+type key struct {
+	flags []string
+	// env is the environment variable overriding the setting
+	env string
+	// workspace settings are applied only if the workspace isn't selected by any of the workspaceFlags
+	workspace bool
+}
+
+var keys = map[string]key{
+	"api-key":           {flags: []string{"api-key"}, env: "IBMCLOUD_API_KEY"},
+	"trusted-profile":   {flags: []string{"trusted-profile"}, env: "IBMCLOUD_TRUSTED_PROFILE"},
+	"account":           {},
+	"env":               {flags: []string{"env"}},
+	"audit-file":        {flags: []string{"audit-file"}},
+	"instance-name":     {flags: []string{"instance-name", "pvs-instance-name"}, workspace: true},
+	"instance-id":       {flags: []string{"instance-id", "pvs-instance-id"}, workspace: true},
+	"cos-instance-name": {flags: []string{"cos-instance-name"}},
+	"bucket":            {flags: []string{"bucket"}},
+	"bucket-region":     {flags: []string{"bucket-region"}},
+}
+
+// workspaceFlags select the PowerVS workspaces, the workspace settings are ignored if any of them is set
+var workspaceFlags = []string{"instance-id", "instance-name", "instance-regexp", "all-instances", "pvs-instance-id", "pvs-instance-name", "policy"}
+
+// Keys returns the supported profile settings
+func Keys() []string {
+	var list []string
+	for k := range keys {
+		list = append(list, k)
+	}
+	sort.Strings(list)
+	return list
+}
+
+func (p *Profile) fields() map[string]*string {
+	return map[string]*string{
+		"api-key":           &p.APIKey,
+		"trusted-profile":   &p.TrustedProfile,
+		"account":           &p.Account,
+		"env":               &p.Env,
+		"audit-file":        &p.AuditFile,
+		"instance-name":     &p.InstanceName,
+		"instance-id":       &p.InstanceID,
+		"cos-instance-name": &p.COSInstanceName,
+		"bucket":            &p.Bucket,
+		"bucket-region":     &p.BucketRegion,
+	}
+}
+
+// Set sets the profile setting, empty value unsets it
+func (p *Profile) Set(name, value string) error {
+	field, ok := p.fields()[name]
+	if !ok {
+		return fmt.Errorf("unsupported setting: %s, supported are: %v", name, Keys())
+	}
+	*field = value
+	return nil
+}
+
+// Apply sets the flags not set on the command line with the profile settings. Settings are skipped if their
+// environment variable is set, and the workspace settings if the workspace is selected by the flags.
+func (p *Profile) Apply(flags *flag.FlagSet) error {
+	workspaceSelected := false
+	for _, name := range workspaceFlags {
+		if f := flags.Lookup(name); f != nil && f.Changed {
+			workspaceSelected = true
+		}
+	}
+	for name, field := range p.fields() {
+		k := keys[name]
+		if *field == "" || (k.env != "" && os.Getenv(k.env) != "") || (k.workspace && workspaceSelected) {
+			continue
+		}
+		for _, fname := range k.flags {
+			f := flags.Lookup(fname)
+			if f == nil || f.Changed {
+				continue
+			}
+			if err := flags.Set(fname, *field); err != nil {
+				return fmt.Errorf("invalid %s in the profile: %v", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// File returns the default configuration file, ~/.pvsadm/config.yaml
+func File() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".pvsadm", "config.yaml"), nil
+}
+
+// Load reads the configuration file, an empty configuration is returned if the file doesn't exist
+func Load(file string) (*Config, error) {
+	c := &Config{}
+	content, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return c, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read the config file: %v", err)
+	}
+	if err := yaml.Unmarshal(content, c); err != nil {
+		return nil, fmt.Errorf("failed to parse the config file %s: %v", file, err)
+	}
+	return c, nil
+}
+
+// Save writes the configuration file readable only by the user, it may hold the API keys
+func (c *Config) Save(file string) error {
+	content, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return fmt.Errorf("failed to create the config directory: %v", err)
+	}
+	if err := ioutil.WriteFile(file, content, 0600); err != nil {
+		return fmt.Errorf("failed to write the config file: %v", err)
+	}
+	return nil
+}
+
+// ProfileName returns the profile in use, selected by the name(--profile), PVSADM_PROFILE or the current-profile
+// in that order
+func (c *Config) ProfileName(name string) string {
+	if name != "" {
+		return name
+	}
+	if name = os.Getenv(EnvProfile); name != "" {
+		return name
+	}
+	return c.CurrentProfile
+}
+
+// Profile returns the profile in use, nil if no profile is in use
+func (c *Config) Profile(name string) (*Profile, error) {
+	name = c.ProfileName(name)
+	if name == "" {
+		return nil, nil
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile: %s not found in the config file", name)
+	}
+	return p, nil
+}
@@ -0,0 +1,149 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	flag "github.com/spf13/pflag"
+)
+
+func TestProfile_Apply(t *testing.T) {
+	p := &Profile{APIKey: "key", Env: "test", InstanceName: "upstream-core", Bucket: "ci-images", BucketRegion: "us-east"}
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+		want map[string]string
+	}{
+		{
+			"profile settings",
+			nil,
+			nil,
+			map[string]string{"api-key": "key", "env": "test", "instance-name": "upstream-core", "instance-regexp": "", "bucket": "ci-images"},
+		},
+		{
+			"flags override the profile",
+			[]string{"--env", "prod", "--bucket", "other"},
+			nil,
+			map[string]string{"env": "prod", "bucket": "other", "instance-name": "upstream-core"},
+		},
+		{
+			"environment variable overrides the profile",
+			nil,
+			map[string]string{"IBMCLOUD_API_KEY": "env-key"},
+			map[string]string{"api-key": ""},
+		},
+		{
+			"workspace selected by the flags",
+			[]string{"--instance-regexp", "^ci-"},
+			nil,
+			map[string]string{"instance-name": "", "instance-regexp": "^ci-", "env": "test"},
+		},
+	}
+	if key, ok := os.LookupEnv("IBMCLOUD_API_KEY"); ok {
+		os.Unsetenv("IBMCLOUD_API_KEY")
+		defer os.Setenv("IBMCLOUD_API_KEY", key)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				os.Setenv(k, v)
+				defer os.Unsetenv(k)
+			}
+			flags := flag.NewFlagSet("test", flag.ContinueOnError)
+			for _, name := range []string{"api-key", "env", "instance-name", "instance-regexp", "bucket"} {
+				flags.String(name, "", "")
+			}
+			if err := flags.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			if err := p.Apply(flags); err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			for name, want := range tt.want {
+				if got, _ := flags.GetString(name); got != want {
+					t.Errorf("Apply() %s = %v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestProfile_Set(t *testing.T) {
+	p := &Profile{}
+	for _, k := range Keys() {
+		if err := p.Set(k, k); err != nil {
+			t.Errorf("Set(%s) error = %v", k, err)
+		}
+	}
+	if p.BucketRegion != "bucket-region" || p.APIKey != "api-key" {
+		t.Errorf("Set() = %+v", p)
+	}
+	if err := p.Set("unknown", "value"); err == nil {
+		t.Errorf("Set() expected error for the unsupported setting")
+	}
+	if len(p.fields()) != len(keys) {
+		t.Errorf("fields() has %d settings, want %d", len(p.fields()), len(keys))
+	}
+}
+
+func TestConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pvsadm-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, ".pvsadm", "config.yaml")
+
+	c, err := Load(file)
+	if err != nil {
+		t.Fatalf("Load() error = %v for the missing file", err)
+	}
+	if p, err := c.Profile(""); p != nil || err != nil {
+		t.Errorf("Profile() = %v, %v, want no profile", p, err)
+	}
+
+	want := &Config{CurrentProfile: "ci", Profiles: map[string]*Profile{"ci": {Env: "prod"}, "staging": {Env: "test"}}}
+	if err := want.Save(file); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	c, err = Load(file)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("Load() = %+v, want %+v", c, want)
+	}
+
+	if p, _ := c.Profile(""); p.Env != "prod" {
+		t.Errorf("Profile() = %+v, want the current profile", p)
+	}
+	os.Setenv(EnvProfile, "staging")
+	defer os.Unsetenv(EnvProfile)
+	if p, _ := c.Profile(""); p.Env != "test" {
+		t.Errorf("Profile() = %+v, want the profile from %s", p, EnvProfile)
+	}
+	if p, _ := c.Profile("ci"); p.Env != "prod" {
+		t.Errorf("Profile() = %+v, want the profile from the flag", p)
+	}
+	if _, err := c.Profile("unknown"); err == nil {
+		t.Errorf("Profile() expected error for the unknown profile")
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"context"
+	"testing"
+)
+
+func TestImport_invalidRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		req  ImportRequest
+	}{
+		{"instance missing", ImportRequest{Bucket: "b", Object: "o", ImageName: "i"}},
+		{"unsupported storage type", ImportRequest{InstanceName: "ins", StorageType: "tier2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// validated before using the client
+			if _, err := Import(context.TODO(), nil, tt.req); err == nil {
+				t.Errorf("Import() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestUpload_invalidRequest(t *testing.T) {
+	if _, err := Upload(context.TODO(), nil, UploadRequest{File: "rhcos.ova.gz"}); err == nil {
+		t.Errorf("Upload() error = nil, want an error for the missing bucket")
+	}
+}
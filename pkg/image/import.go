@@ -0,0 +1,331 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/IBM-Cloud/bluemix-go/api/resource/resourcev1/controller"
+	"github.com/IBM-Cloud/bluemix-go/api/resource/resourcev2/controllerv2"
+	"github.com/IBM-Cloud/bluemix-go/crn"
+	"github.com/IBM-Cloud/bluemix-go/models"
+	pmodels "github.com/IBM-Cloud/power-go-client/power/models"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg/client"
+	"github.com/ppc64le-cloud/pvsadm/pkg/utils"
+)
+
+const (
+	// ServiceCredPrefix is the prefix of the name of the service credential generated for the import
+	ServiceCredPrefix = "pvsadm-service-cred"
+	// DefaultWatchTimeout is the default time waited for the imported image to be active
+	DefaultWatchTimeout = time.Hour
+	// ImageStateActive is the state of the imported image ready to use
+	ImageStateActive = "active"
+)
+
+// StorageTypes are the PowerVS storage types supported for the imported images
+var StorageTypes = []string{"tier3", "tier1"}
+
+// WatchInterval is the interval between the checks of the imported image state
+var WatchInterval = 2 * time.Minute
+
+// ImportRequest holds the options of the Import
+type ImportRequest struct {
+	// InstanceID or InstanceName of the PowerVS instance to import the image into
+	InstanceID   string
+	InstanceName string
+	// COSInstanceName narrows down the lookup of the bucket, looked up in all the COS instances if empty
+	COSInstanceName string
+	Bucket          string
+	Region          string
+	// COS is the COS instance holding the bucket as returned by the FindCOSInstance, saves the lookup by the
+	// COSInstanceName for the imports of the same object into the multiple instances
+	COS *COSInstance
+	// Object is the image object in the bucket
+	Object string
+	// ImageName is the name of the imported image
+	ImageName string
+	// StorageType is one of the StorageTypes, defaults to tier3
+	StorageType string
+	// AccessKey and SecretKey are the HMAC keys of the bucket, read from the ServiceCredName of the COS instance if
+	// not set, the service credential is generated if not present
+	AccessKey       string
+	SecretKey       string
+	ServiceCredName string
+	// Watch waits for the image to be active for the WatchTimeout, defaults to DefaultWatchTimeout
+	Watch        bool
+	WatchTimeout time.Duration
+	// SkipExisting skips the import if an image with the ImageName is already present in the PowerVS instance, the
+	// present image is watched instead if not active
+	SkipExisting bool
+	// Checksum is the expected hex sha256 digest of the Object, the import is refused if the checksum stored in the
+	// object metadata by the Upload is missing or doesn't match. The stored checksum isn't verified if empty.
+	Checksum string
+}
+
+// ImportResult is the outcome of the Import
+type ImportResult struct {
+	// Image is the imported image, state is active if watched
+	Image *pmodels.Image
+	// InstanceID is the PowerVS instance the image is imported into
+	InstanceID      string
+	COSInstanceName string
+	// Existing reports whether the Image was already present in the PowerVS instance and not imported again
+	Existing bool
+}
+
+// Import imports the image object from the bucket into the PowerVS instance. Result is returned along with the error
+// if the import is started but the image doesn't become active while watching. Watching stops along with the ctx.
+func Import(ctx context.Context, c *client.Client, req ImportRequest) (*ImportResult, error) {
+	if req.InstanceID == "" && req.InstanceName == "" {
+		return nil, fmt.Errorf("PowerVS instance ID or name is required")
+	}
+	if req.StorageType == "" {
+		req.StorageType = StorageTypes[0]
+	}
+	if !utils.Contains(StorageTypes, strings.ToLower(req.StorageType)) {
+		return nil, fmt.Errorf("unsupported storage type: %s, supported are: [%s]", req.StorageType, strings.Join(StorageTypes, ", "))
+	}
+	if req.WatchTimeout == 0 {
+		req.WatchTimeout = DefaultWatchTimeout
+	}
+
+	// Step 1: Find where COS for the bucket
+	cos := req.COS
+	if cos == nil {
+		var err error
+		if cos, err = FindCOSInstance(c, req.COSInstanceName, req.Bucket, req.Region); err != nil {
+			return nil, err
+		}
+	}
+	s3client, cosOfBucket := cos.s3client, cos.resource.Name
+
+	//Step 2: Check if s3 object exists
+	if !s3client.CheckIfObjectExists(req.Bucket, req.Object) {
+		return nil, fmt.Errorf("failed to found the object %s in %s bucket", req.Object, req.Bucket)
+	}
+	klog.Infof("%s object found in the %s bucket\n", req.Object, req.Bucket)
+	if err := verifyObjectChecksum(s3client, req.Bucket, req.Object, req.Checksum); err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	pvmclient, err := client.NewPVMClientWithEndpoints(c, req.InstanceID, req.InstanceName, c.Environment)
+	if err != nil {
+		return nil, err
+	}
+
+	var image *pmodels.Image
+	if req.SkipExisting {
+		if image, err = findImage(pvmclient, req.ImageName); err != nil {
+			return nil, err
+		}
+	}
+	result := &ImportResult{Image: image, InstanceID: pvmclient.InstanceID, COSInstanceName: cosOfBucket, Existing: image != nil}
+	if result.Existing {
+		klog.Infof("Image %s with ID: %s is already present in the instance %s, state: %s", *image.Name, *image.ImageID, pvmclient.InstanceName, image.State)
+		if image.State == ImageStateActive || !req.Watch {
+			return result, nil
+		}
+	} else {
+		if req.AccessKey == "" || req.SecretKey == "" {
+			if req.AccessKey, req.SecretKey, err = hmacKeys(c, req.ServiceCredName, cosOfBucket, cos.resource.Guid, cos.resource.Crn); err != nil {
+				return nil, err
+			}
+		}
+		image, err = pvmclient.ImgClient.ImportImage(pvmclient.InstanceID, req.ImageName, req.Object, req.Region,
+			req.AccessKey, req.SecretKey, req.Bucket, strings.ToLower(req.StorageType))
+		if err != nil {
+			return nil, err
+		}
+		result.Image = image
+		if !req.Watch {
+			return result, nil
+		}
+	}
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, req.WatchTimeout)
+	defer cancel()
+	pollErr := wait.PollImmediateUntil(WatchInterval, func() (bool, error) {
+		img, err := pvmclient.ImgClient.Get(*image.ImageID)
+		if err != nil {
+			return false, err
+		}
+		if img.State == ImageStateActive {
+			result.Image.State = img.State
+			return true, nil
+		}
+		klog.Infof("Import in-progress, current state: %s", img.State)
+		return false, nil
+	}, ctx.Done())
+	if pollErr == wait.ErrWaitTimeout {
+		pollErr = fmt.Errorf("timed out while waiting for image to become ready state")
+		if ctx.Err() == context.Canceled {
+			// import carries on in the PowerVS, only the watch is stopped
+			klog.Infof("Stopped watching the image %s with ID: %s, import continues in the background", *image.Name, *image.ImageID)
+			pollErr = fmt.Errorf("interrupted while waiting for image to become ready state")
+		}
+	}
+	if pollErr != nil {
+		return result, pollErr
+	}
+	klog.Infof("Successfully imported the image: %s with ID: %s within %s", *image.Name, *image.ImageID, time.Since(start))
+	return result, nil
+}
+
+// COSInstance is the COS instance along with the client of the region
+type COSInstance struct {
+	resource models.ServiceInstanceV2
+	s3client *client.S3Client
+}
+
+// FindCOSInstance returns the COS instance holding the bucket, the bucket is looked up in all the COS instances if
+// the name is empty
+func FindCOSInstance(c *client.Client, name, bucket, region string) (*COSInstance, error) {
+	query := controllerv2.ServiceInstanceQuery{Type: "service_instance", Name: name}
+	svcs, err := c.ResourceClientV2.ListInstances(query)
+	if err != nil {
+		return nil, err
+	}
+	for _, resource := range svcs {
+		if resource.Crn.ServiceName != ServiceType {
+			continue
+		}
+		s3client, err := client.NewS3Client(c, resource.Name, region)
+		if err != nil {
+			continue
+		}
+		buckets, err := s3client.S3Session.ListBuckets(nil)
+		if err != nil {
+			continue
+		}
+		for _, b := range buckets.Buckets {
+			if *b.Name == bucket {
+				klog.Infof("%s bucket found in the %s[ID:%s] COS instance", bucket, resource.Name, resource.Guid)
+				return &COSInstance{resource: resource, s3client: s3client}, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("failed to find the COS instance for the bucket mentioned: %s", bucket)
+}
+
+// HMACKeys returns the HMAC keys of the COS instance holding the bucket from the service credential, the credential is
+// generated if not present. The bucket is looked up in all the COS instances if the cosInstanceName is empty.
+func HMACKeys(c *client.Client, cosInstanceName, bucket, region, serviceCredName string) (string, string, error) {
+	cos, err := FindCOSInstance(c, cosInstanceName, bucket, region)
+	if err != nil {
+		return "", "", err
+	}
+	return cos.HMACKeys(c, serviceCredName)
+}
+
+// HMACKeys is the HMACKeys for the COS instance already looked up
+func (cos *COSInstance) HMACKeys(c *client.Client, serviceCredName string) (string, string, error) {
+	return hmacKeys(c, serviceCredName, cos.resource.Name, cos.resource.Guid, cos.resource.Crn)
+}
+
+// FindImage returns the image with the name in the PowerVS instance, nil if not present
+func FindImage(c *client.Client, ins *client.Instance, name string) (*pmodels.Image, error) {
+	pvmclient, err := client.NewPVMClientForInstanceWithEndpoints(c, ins, c.Environment)
+	if err != nil {
+		return nil, err
+	}
+	return findImage(pvmclient, name)
+}
+
+// findImage returns the image with the name in the PowerVS instance, nil if not present
+func findImage(pvmclient *client.PVMClient, name string) (*pmodels.Image, error) {
+	images, err := pvmclient.ImgClient.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the list of images: %v", err)
+	}
+	for _, image := range images.Images {
+		if *image.Name == name {
+			return pvmclient.ImgClient.Get(*image.ImageID)
+		}
+	}
+	return nil, nil
+}
+
+// verifyObjectChecksum fails if the checksum stored in the object metadata doesn't match the expected hex digest, the
+// objects without the checksum are refused only if the checksum is expected
+func verifyObjectChecksum(s3client *client.S3Client, bucket, object, expected string) error {
+	stored, err := s3client.ObjectChecksum(bucket, object)
+	if err != nil {
+		return err
+	}
+	switch {
+	case expected == "" && stored == "":
+		klog.Infof("%s object carries no checksum, skipping the verification", object)
+	case expected == "":
+		klog.Infof("%s object checksum: %s:%s", object, utils.ChecksumSHA256, stored)
+	case stored == "":
+		return fmt.Errorf("refusing to import the object %s, the object carries no checksum to verify against %s:%s", object, utils.ChecksumSHA256, expected)
+	case stored != strings.ToLower(expected):
+		return fmt.Errorf("refusing to import the object %s, checksum mismatch, got %s:%s, expected %s:%s", object, utils.ChecksumSHA256, stored, utils.ChecksumSHA256, expected)
+	default:
+		klog.Infof("Verified the checksum of the object %s: %s:%s", object, utils.ChecksumSHA256, stored)
+	}
+	return nil
+}
+
+// hmacKeys returns the HMAC keys from the service credential of the COS instance, the credential is generated if the
+// instance has none
+func hmacKeys(c *client.Client, name, cosName, cosID string, cosCRN crn.CRN) (string, string, error) {
+	// frame the unique name for the service credential
+	if name == "" {
+		name = ServiceCredPrefix + "-" + cosName
+	}
+
+	keys, err := c.GetResourceKeys(cosID)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to list the service credentials: %v", err)
+	}
+
+	var cred map[string]interface{}
+	var ok bool
+	if len(keys) == 0 {
+		// Create the service credential if does not exist
+		klog.Infof("Auto Generating the COS Service credential for importing the image with name: %s", name)
+		CreateServiceKeyRequest := controller.CreateServiceKeyRequest{
+			Name:       name,
+			SourceCRN:  cosCRN,
+			Parameters: map[string]interface{}{"HMAC": true},
+		}
+		newKey, err := c.ResourceServiceKey.CreateKey(CreateServiceKeyRequest)
+		if err != nil {
+			return "", "", err
+		}
+		cred, ok = newKey.Credentials["cos_hmac_keys"].(map[string]interface{})
+	} else {
+		// Use the service credential already created
+		klog.Infof("Reading the existing service credential: %s", name)
+		cred, ok = keys[0].Credentials["cos_hmac_keys"].(map[string]interface{})
+	}
+
+	if !ok {
+		return "", "", fmt.Errorf("failed to get the accessKey and secretKey from service credential")
+	}
+	return cred["access_key_id"].(string), cred["secret_access_key"].(string), nil
+}
@@ -0,0 +1,189 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg/client"
+	"github.com/ppc64le-cloud/pvsadm/pkg/utils"
+)
+
+// ServiceType is the service of the Cloud Object Storage instances
+const ServiceType = "cloud-object-storage"
+
+// ErrBucketNotFound is returned by the Upload if the bucket isn't found in any of the COS instances
+var ErrBucketNotFound = errors.New("bucket not found in any of the Cloud Object Storage instances")
+
+// UploadRequest holds the options of the Upload
+type UploadRequest struct {
+	// File is the path of the image to be uploaded
+	File string
+	// InstanceName is the COS instance of the bucket, the bucket is looked up in all the COS instances if empty
+	InstanceName string
+	// Bucket is created in the InstanceName if not present
+	Bucket string
+	Region string
+	// ObjectName defaults to the base name of the File
+	ObjectName string
+	// PartSize and Concurrency of the multipart upload, defaults to the client.DefaultPartSize and
+	// client.DefaultConcurrency
+	PartSize    int64
+	Concurrency int
+	// Checksum is the expected hex sha256 digest of the File, the File is also verified against the checksum file
+	// written along with it(File + utils.ChecksumSuffix) if present
+	Checksum string
+}
+
+// UploadResult is the outcome of the Upload
+type UploadResult struct {
+	InstanceName  string
+	Bucket        string
+	ObjectName    string
+	BucketCreated bool
+	// Checksum is the hex sha256 digest of the uploaded object
+	Checksum string
+}
+
+// Upload uploads the image file to the bucket, fails if the object already exists. Upload is aborted along with the
+// ctx and resumed by the next Upload of the same file. The uploaded object is verified by its ETag, the sha256 digest of
+// the file is stored in the client.ChecksumMetadataKey metadata of the object for the Import to verify.
+func Upload(ctx context.Context, c *client.Client, req UploadRequest) (*UploadResult, error) {
+	if req.File == "" || req.Bucket == "" {
+		return nil, fmt.Errorf("file and bucket are required")
+	}
+	result := &UploadResult{InstanceName: req.InstanceName, Bucket: req.Bucket, ObjectName: req.ObjectName}
+	if result.ObjectName == "" {
+		result.ObjectName = filepath.Base(req.File)
+	}
+
+	bucketExists := false
+	if result.InstanceName == "" {
+		name, err := FindBucket(c, req.Bucket, req.Region)
+		if err != nil {
+			return nil, err
+		}
+		if name == "" {
+			return nil, ErrBucketNotFound
+		}
+		result.InstanceName, bucketExists = name, true
+	}
+
+	s3Cli, err := client.NewS3Client(c, result.InstanceName, req.Region)
+	if err != nil {
+		return nil, err
+	}
+	if !bucketExists {
+		if bucketExists, err = s3Cli.CheckBucketExists(req.Bucket); err != nil {
+			return nil, err
+		}
+	}
+
+	if bucketExists && s3Cli.CheckIfObjectExists(req.Bucket, result.ObjectName) {
+		return nil, fmt.Errorf("%s object already exists in the %s bucket", result.ObjectName, req.Bucket)
+	}
+
+	// verified before anything is created in the COS
+	sum, err := fileChecksum(req.File, req.Checksum)
+	if err != nil {
+		return nil, err
+	}
+	result.Checksum = sum
+
+	if !bucketExists {
+		klog.Infof("Creating a new bucket %s\n", req.Bucket)
+		if err := s3Cli.CreateBucket(req.Bucket); err != nil {
+			return nil, err
+		}
+		result.BucketCreated = true
+	}
+
+	opts := client.UploadOptions{PartSize: req.PartSize, Concurrency: req.Concurrency, Checksum: sum}
+	if err := s3Cli.UploadObjectWithOptions(ctx, req.File, result.ObjectName, req.Bucket, opts); err != nil {
+		return nil, err
+	}
+	klog.Infof("Uploaded %s with the checksum %s:%s", result.ObjectName, utils.ChecksumSHA256, sum)
+	return result, nil
+}
+
+// fileChecksum returns the hex sha256 digest of the file, fails if it doesn't match the expected digest or the
+// checksum file written along with the file
+func fileChecksum(file, expected string) (string, error) {
+	sidecar, err := utils.ReadChecksumFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the checksum file %s: %v", file+utils.ChecksumSuffix, err)
+	}
+	sum, err := utils.FileSHA256(file)
+	if err != nil {
+		return "", err
+	}
+	if sidecar != "" && sum != sidecar {
+		return "", fmt.Errorf("checksum mismatch for %s, got %s:%s, expected %s:%s from %s", file, utils.ChecksumSHA256, sum,
+			utils.ChecksumSHA256, sidecar, file+utils.ChecksumSuffix)
+	}
+	if expected != "" && sum != strings.ToLower(expected) {
+		return "", fmt.Errorf("checksum mismatch for %s, got %s:%s, expected %s:%s", file, utils.ChecksumSHA256, sum, utils.ChecksumSHA256, expected)
+	}
+	return sum, nil
+}
+
+// AbortUploads aborts the incomplete uploads of the object, all the objects if empty, to the bucket. The bucket is
+// looked up in all the COS instances if the instanceName is empty.
+func AbortUploads(c *client.Client, instanceName, bucket, region, object string) ([]client.MultipartUpload, error) {
+	if instanceName == "" {
+		name, err := FindBucket(c, bucket, region)
+		if err != nil {
+			return nil, err
+		}
+		if name == "" {
+			return nil, ErrBucketNotFound
+		}
+		instanceName = name
+	}
+	s3Cli, err := client.NewS3Client(c, instanceName, region)
+	if err != nil {
+		return nil, err
+	}
+	return s3Cli.AbortUploads(bucket, object)
+}
+
+// FindBucket returns the name of the COS instance holding the bucket, empty if not found in any of the instances
+func FindBucket(c *client.Client, bucket, region string) (string, error) {
+	instances, err := c.ListServiceInstances(ServiceType)
+	if err != nil {
+		return "", err
+	}
+	for name := range instances {
+		s3Cli, err := client.NewS3Client(c, name, region)
+		if err != nil {
+			return "", err
+		}
+		exists, err := s3Cli.CheckBucketExists(bucket)
+		if err != nil {
+			return "", err
+		}
+		if exists {
+			klog.Infof("Found bucket %s in the %s instance", bucket, name)
+			return name, nil
+		}
+	}
+	return "", nil
+}
@@ -14,37 +14,86 @@
 
 package pkg
 
-import "time"
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
 
-var Options = &options{}
+var Options = &options{APITimeout: TIMEOUT, MaxRetries: DefaultMaxRetries}
 
 type options struct {
-	InstanceID   string
-	APIKey       string
-	Environment  string
-	Region       string
-	Zone         string
-	DryRun       bool
-	Debug        bool
-	Since        time.Duration
-	Before       time.Duration
-	InstanceName string
-	NoPrompt     bool
-	IgnoreErrors bool
-	AuditFile    string
-	Expr         string
+	InstanceID     string
+	APIKey         string
+	TrustedProfile string
+	CRTokenFile    string
+	Profile        string
+	Account        string
+	Environment    string
+	Region         string
+	Zone           string
+	DryRun         bool
+	Debug          bool
+	Since          time.Duration
+	Before         time.Duration
+	InstanceName   string
+	InstanceRegexp string
+	AllInstances   bool
+	Refresh        bool
+	APITimeout     time.Duration
+	MaxRetries     int
+	NoPrompt       bool
+	IgnoreErrors   bool
+	AuditFile      string
+	Expr           string
+	// ExprRegexp is the compiled Expr, set while validating the options of the get and purge commands, nil if the
+	// Expr is empty
+	ExprRegexp     *regexp.Regexp
+	Selector       string
+	ExcludeExpr    string
+	ExcludeIDsFile string
+	Output         string
+	Parallel       int
+	Wait           bool
+	WaitTimeout    time.Duration
+}
+
+// InstanceSelected reports whether any PowerVS instance is selected by the options
+func (o *options) InstanceSelected() bool {
+	return o.InstanceID != "" || o.InstanceName != "" || o.InstanceRegexp != "" || o.AllInstances
+}
+
+// MultipleInstances reports whether the options may select more than one PowerVS instance, i.e. comma separated
+// --instance-id or --instance-name, --instance-regexp or --all-instances
+func (o *options) MultipleInstances() bool {
+	return strings.Contains(o.InstanceID, ",") || strings.Contains(o.InstanceName, ",") || o.InstanceRegexp != "" || o.AllInstances
+}
+
+// ParseExpr compiles the --regexp option, returns nil for the empty expression
+func ParseExpr(expr string) (*regexp.Regexp, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	r, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --regexp: %v", err)
+	}
+	return r, nil
 }
 
 // Options for pvsadm image command
-var ImageCMDOptions = &imageCMDOptions{}
+var ImageCMDOptions = &ImageOptions{}
 
-type imageCMDOptions struct {
+// ImageOptions are the options of the pvsadm image commands
+type ImageOptions struct {
 	//qcow2ova options
 	ImageDist           string
 	ImageName           string
 	ImageSize           uint64
 	TargetDiskSize      int64
 	ImageURL            string
+	ImageChecksum       string
 	OSPassword          string
 	PreflightSkip       []string
 	RHNUser             string
@@ -59,6 +108,8 @@ type imageCMDOptions struct {
 	ResourceGrp  string
 	ServicePlan  string
 	ObjectName   string
+	PartSize     int64
+	Concurrency  int
 	//import options
 	COSInstanceName string
 	ImageFilename   string
@@ -0,0 +1,177 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+)
+
+// Policy is the declarative purge policy, holds the purge rules per PowerVS instance
+type Policy struct {
+	Instances []PolicyInstance `yaml:"instances"`
+}
+
+// PolicyInstance holds the purge rules per resource kind for a PowerVS instance, kinds without rule aren't purged
+type PolicyInstance struct {
+	ID       string      `yaml:"id"`
+	Name     string      `yaml:"name"`
+	VMs      *PolicyRule `yaml:"vms"`
+	Volumes  *PolicyRule `yaml:"volumes"`
+	Images   *PolicyRule `yaml:"images"`
+	Networks *PolicyRule `yaml:"networks"`
+}
+
+// PolicyRule selects the purgeable candidates of a resource kind
+type PolicyRule struct {
+	// Include the resources matching any of the regular expressions, all the resources if empty
+	Include []string `yaml:"include"`
+	// Exclude the resources matching any of the regular expressions
+	Exclude []string `yaml:"exclude"`
+	// Before and Since are the age thresholds, same as --before and --since options, not supported for the networks
+	Before time.Duration `yaml:"before"`
+	Since  time.Duration `yaml:"since"`
+	// KeepNewest retains the newest N resources out of the selection, not supported for the networks
+	KeepNewest int `yaml:"keepNewest"`
+	// ProtectedIDs are never purged
+	ProtectedIDs []string `yaml:"protectedIDs"`
+}
+
+// PurgeCandidate is a resource selected for the purge
+type PurgeCandidate struct {
+	// Instance is the name of the PowerVS instance for the display, names aren't unique and the InstanceID identifies
+	// the instance
+	Instance   string `json:"instance"`
+	InstanceID string `json:"instanceID"`
+	Kind       string `json:"kind"`
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	// Date is used for the age thresholds and the retention, creation date for most of the resources
+	Date strfmt.DateTime `json:"date"`
+	// Action is DELETE or SKIPPED, protected candidates are skipped with the Reason
+	Action string `json:"action,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Validate verifies the policy for the mandatory fields and the rules
+func (p *Policy) Validate() error {
+	if len(p.Instances) == 0 {
+		return fmt.Errorf("no instances found in the policy")
+	}
+	for i, ins := range p.Instances {
+		if ins.ID == "" && ins.Name == "" {
+			return fmt.Errorf("instances[%d]: id or name required", i)
+		}
+		// networks carry no date, the age thresholds and the retention can't be applied
+		if ins.Networks != nil && (ins.Networks.Before != 0 || ins.Networks.Since != 0 || ins.Networks.KeepNewest != 0) {
+			return fmt.Errorf("instances[%d].networks: before, since and keepNewest are not supported for networks", i)
+		}
+		for kind, rule := range ins.Rules() {
+			if err := rule.Validate(); err != nil {
+				return fmt.Errorf("instances[%d].%s: %v", i, kind, err)
+			}
+		}
+	}
+	return nil
+}
+
+// PolicyKinds is the list of resource kinds supported in the policy, in the order of the purge
+var PolicyKinds = []string{"vms", "volumes", "images", "networks"}
+
+// Rules returns the rules set for the instance by the resource kind
+func (p *PolicyInstance) Rules() map[string]*PolicyRule {
+	rules := map[string]*PolicyRule{}
+	for kind, rule := range map[string]*PolicyRule{"vms": p.VMs, "volumes": p.Volumes, "images": p.Images, "networks": p.Networks} {
+		if rule != nil {
+			rules[kind] = rule
+		}
+	}
+	return rules
+}
+
+// Validate verifies the regular expressions and the thresholds of the rule
+func (r *PolicyRule) Validate() error {
+	if r.Before != 0 && r.Since != 0 {
+		return fmt.Errorf("before and since can not be set at a time")
+	}
+	if r.KeepNewest < 0 {
+		return fmt.Errorf("keepNewest can't be negative")
+	}
+	for _, expr := range append(append([]string{}, r.Include...), r.Exclude...) {
+		if _, err := regexp.Compile(expr); err != nil {
+			return fmt.Errorf("invalid regular expression: %s, err: %v", expr, err)
+		}
+	}
+	return nil
+}
+
+// Filter returns the candidates qualify to be purged as per the rule, in the following order:
+//   - keeps the candidates matching any of the include expressions and none of the exclude expressions
+//   - skips the protected IDs
+//   - retains the newest N candidates
+//   - keeps the candidates qualify the age thresholds
+func (r *PolicyRule) Filter(candidates []*PurgeCandidate) []*PurgeCandidate {
+	var selected []*PurgeCandidate
+	for _, c := range candidates {
+		if len(r.Include) != 0 && !matchAny(r.Include, c.Name) {
+			continue
+		}
+		if matchAny(r.Exclude, c.Name) {
+			continue
+		}
+		if containsString(r.ProtectedIDs, c.ID) {
+			continue
+		}
+		selected = append(selected, c)
+	}
+
+	sort.SliceStable(selected, func(i, j int) bool {
+		return time.Time(selected[i].Date).After(time.Time(selected[j].Date))
+	})
+	if r.KeepNewest >= len(selected) {
+		return nil
+	}
+	selected = selected[r.KeepNewest:]
+
+	var purgeable []*PurgeCandidate
+	for _, c := range selected {
+		if IsPurgeable(time.Time(c.Date), r.Before, r.Since) {
+			purgeable = append(purgeable, c)
+		}
+	}
+	return purgeable
+}
+
+func matchAny(exprs []string, s string) bool {
+	for _, expr := range exprs {
+		if r, _ := regexp.Compile(expr); r.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(s []string, e string) bool {
+	for _, a := range s {
+		if a == e {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+)
+
+func TestPolicyRule_Filter(t *testing.T) {
+	now := time.Now()
+	candidate := func(id, name string, age time.Duration) *PurgeCandidate {
+		return &PurgeCandidate{ID: id, Name: name, Date: strfmt.DateTime(now.Add(-age))}
+	}
+	candidates := []*PurgeCandidate{
+		candidate("1", "k8s-master", 1*time.Hour),
+		candidate("2", "k8s-worker-1", 5*time.Hour),
+		candidate("3", "k8s-worker-2", 10*time.Hour),
+		candidate("4", "bastion", 20*time.Hour),
+	}
+	ids := func(candidates []*PurgeCandidate) (ids []string) {
+		for _, c := range candidates {
+			ids = append(ids, c.ID)
+		}
+		return
+	}
+	tests := []struct {
+		name string
+		rule PolicyRule
+		want []string
+	}{
+		{"empty rule selects all, newest first", PolicyRule{}, []string{"1", "2", "3", "4"}},
+		{"include", PolicyRule{Include: []string{"^k8s-"}}, []string{"1", "2", "3"}},
+		{"include and exclude", PolicyRule{Include: []string{"^k8s-"}, Exclude: []string{"master"}}, []string{"2", "3"}},
+		{"protected IDs", PolicyRule{ProtectedIDs: []string{"1", "4"}}, []string{"2", "3"}},
+		{"before", PolicyRule{Before: 8 * time.Hour}, []string{"3", "4"}},
+		{"since", PolicyRule{Since: 8 * time.Hour}, []string{"1", "2"}},
+		{"keep newest", PolicyRule{Include: []string{"worker"}, KeepNewest: 1}, []string{"3"}},
+		{"keep newest before the age threshold", PolicyRule{KeepNewest: 3, Since: 8 * time.Hour}, nil},
+		{"keep newest more than the selection", PolicyRule{KeepNewest: 10}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ids(tt.rule.Filter(candidates)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Filter() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicy_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  Policy
+		wantErr bool
+	}{
+		{"valid policy", Policy{Instances: []PolicyInstance{{Name: "upstream-core", VMs: &PolicyRule{Before: time.Hour}}}}, false},
+		{"no instances", Policy{}, true},
+		{"instance without id and name", Policy{Instances: []PolicyInstance{{VMs: &PolicyRule{}}}}, true},
+		{"both before and since", Policy{Instances: []PolicyInstance{{ID: "id", Images: &PolicyRule{Before: time.Hour, Since: time.Hour}}}}, true},
+		{"age threshold for networks", Policy{Instances: []PolicyInstance{{ID: "id", Networks: &PolicyRule{Before: time.Hour}}}}, true},
+		{"keep newest for networks", Policy{Instances: []PolicyInstance{{ID: "id", Networks: &PolicyRule{KeepNewest: 2}}}}, true},
+		{"invalid regular expression", Policy{Instances: []PolicyInstance{{ID: "id", Volumes: &PolicyRule{Exclude: []string{"("}}}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
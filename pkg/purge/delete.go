@@ -0,0 +1,298 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package purge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg"
+	"github.com/ppc64le-cloud/pvsadm/pkg/audit"
+	"github.com/ppc64le-cloud/pvsadm/pkg/client"
+	"github.com/ppc64le-cloud/pvsadm/pkg/utils"
+)
+
+const (
+	StatusDeleted = "DELETED"
+	StatusFailed  = "FAILED"
+	StatusSkipped = "SKIPPED"
+	// StatusNotAttempted is set for the items left out after a failure without --ignore-errors
+	StatusNotAttempted = "NOT ATTEMPTED"
+)
+
+// ErrNotFound is returned by the Item.Get once the resource is gone, for the resources looked up without the API
+// returning 404
+var ErrNotFound = errors.New("not found")
+
+// WaitInterval is the interval between the checks for the deleted resources to be gone
+var WaitInterval = 15 * time.Second
+
+// Item is a resource to be deleted
+type Item struct {
+	Name   string
+	ID     string
+	Delete func() error
+	// Get fetches the resource, used for waiting till the resource returns 404 or ErrNotFound after the deletion
+	Get func() error
+	// SkipReason is set for the protected resources, such items are not deleted
+	SkipReason string
+}
+
+// Lister returns the IDs of the resources present, lets the waiting check all the deleted items with a single list
+// per poll instead of an Item.Get per item
+type Lister func() (map[string]bool, error)
+
+// Result is the outcome of the deletion of an Item
+type Result struct {
+	Name   string `json:"name"`
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// isNotFound tells whether the Item.Get failed since the resource is gone. The power-go-client wrappers return the
+// 404 responses of the API as the plain errors, with the "(status 404)" or the "[404]" in the message.
+func isNotFound(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrNotFound) {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "(status 404)") || strings.Contains(msg, "][404]")
+}
+
+// DeleteAll deletes the items using pkg.Options.Parallel workers, failed requests are retried by the client as per
+// the --max-retries. Optionally waits for them to be gone, prints the
+// per item summary and records the outcome in the audit log. Error is returned if any of the item failed to delete
+// unless --ignore-errors is set. No new deletion is started once the ctx is cancelled, the summary and the audit log
+// still cover all the items.
+func DeleteAll(ctx context.Context, kind, instanceName string, items []Item) error {
+	return DeleteAllWithList(ctx, kind, instanceName, items, nil)
+}
+
+// DeleteAllWithList is same as the DeleteAll, the --wait lists the resources with the list once per poll instead of
+// the Item.Get per item if the list is set
+func DeleteAllWithList(ctx context.Context, kind, instanceName string, items []Item, list Lister) error {
+	return deleteAllInstances(ctx, kind, []string{instanceName}, [][]Item{items}, list)
+}
+
+// DeleteAllInstances deletes the items of the multiple PowerVS instances, items[i] belong to the pvmclients[i]. A
+// failure in an instance doesn't stop the deletion in the other instances, the summary covers all the instances and
+// the error lists the instances with the failures.
+func DeleteAllInstances(ctx context.Context, kind string, pvmclients []*client.PVMClient, items [][]Item) error {
+	names := make([]string, len(pvmclients))
+	for i, pvmclient := range pvmclients {
+		names[i] = pvmclient.InstanceName
+	}
+	return deleteAllInstances(ctx, kind, names, items, nil)
+}
+
+// instanceResult is the Result along with the name of the PowerVS instance, used in the summary of the multiple
+// instances
+type instanceResult struct {
+	Instance string `json:"instance"`
+	Result
+}
+
+func deleteAllInstances(ctx context.Context, kind string, instanceNames []string, items [][]Item, list Lister) error {
+	var (
+		all             []instanceResult
+		deleted, failed int
+		failedInstances []string
+	)
+	for i, instanceName := range instanceNames {
+		if len(instanceNames) > 1 && Deletable(items[i]) != 0 {
+			klog.Infof("Deleting the %s of the instance: %s", kind, instanceName)
+		}
+		results := deleteAll(ctx, items[i], pkg.Options.Parallel, !pkg.Options.IgnoreErrors)
+		if pkg.Options.Wait && ctx.Err() == nil && Deletable(items[i]) != 0 {
+			klog.Infof("Waiting for the deleted %s to be gone", kind)
+			waitForDeletion(ctx, items[i], results, list, WaitInterval, pkg.Options.WaitTimeout)
+		}
+
+		var n int
+		for _, r := range results {
+			switch r.Status {
+			case StatusNotAttempted:
+				audit.Log(kind, "not-attempted", instanceName+":"+r.Name)
+			case StatusDeleted:
+				deleted++
+				audit.Log(kind, "delete", instanceName+":"+r.Name)
+			case StatusFailed:
+				n++
+				audit.Log(kind, "delete-failed", instanceName+":"+r.Name)
+			case StatusSkipped:
+				audit.Log(kind, "skip", instanceName+":"+r.Name)
+			}
+			all = append(all, instanceResult{Instance: instanceName, Result: r})
+		}
+		if n != 0 {
+			failed += n
+			failedInstances = append(failedInstances, instanceName)
+		}
+	}
+
+	t := utils.NewTable()
+	if t.Structured() {
+		for _, r := range all {
+			klog.Infof("%s: %s, ID: %s %s%s", r.Status, r.Name, r.ID, r.Error, r.Reason)
+		}
+	} else {
+		fmt.Println("Summary:")
+		var err error
+		if len(instanceNames) > 1 {
+			err = t.Render(all, nil)
+		} else {
+			results := make([]Result, len(all))
+			for i := range all {
+				results[i] = all[i].Result
+			}
+			err = t.Render(results, nil)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	klog.Infof("Deleted %d out of %d %s, failed: %d", deleted, len(all), kind, failed)
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("deletion of the %s interrupted: %v", kind, err)
+	}
+	if failed != 0 && !pkg.Options.IgnoreErrors {
+		if len(instanceNames) > 1 {
+			return fmt.Errorf("failed to delete %d %s in the instances: %s", failed, kind, strings.Join(failedInstances, ", "))
+		}
+		return fmt.Errorf("failed to delete %d %s", failed, kind)
+	}
+	return nil
+}
+
+// deleteAll deletes the items with the given number of workers and returns the results in the order of items,
+// no new deletion is started after a failure if stopOnError is set or once the ctx is cancelled.
+func deleteAll(ctx context.Context, items []Item, workers int, stopOnError bool) []Result {
+	if workers < 1 {
+		workers = 1
+	}
+	results := make([]Result, len(items))
+	jobs := make(chan int, len(items))
+	for i := range items {
+		if items[i].SkipReason != "" {
+			results[i] = Result{Name: items[i].Name, ID: items[i].ID, Status: StatusSkipped, Reason: items[i].SkipReason}
+			continue
+		}
+		results[i] = Result{Name: items[i].Name, ID: items[i].ID, Status: StatusNotAttempted}
+		jobs <- i
+	}
+	close(jobs)
+
+	var (
+		wg      sync.WaitGroup
+		mutex   sync.Mutex
+		stopped bool
+	)
+	worker := func() {
+		defer wg.Done()
+		for i := range jobs {
+			mutex.Lock()
+			stop := stopped
+			mutex.Unlock()
+			if stop || ctx.Err() != nil {
+				continue
+			}
+			klog.Infof("Deleting the %s, and ID: %s", items[i].Name, items[i].ID)
+			if err := items[i].Delete(); err != nil {
+				klog.Infof("error occurred while deleting the %s: %v", items[i].Name, err)
+				results[i].Status, results[i].Error = StatusFailed, err.Error()
+				if stopOnError {
+					mutex.Lock()
+					stopped = true
+					mutex.Unlock()
+				}
+				continue
+			}
+			results[i].Status = StatusDeleted
+		}
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go worker()
+	}
+	wg.Wait()
+	return results
+}
+
+// waitForDeletion polls the deleted items till all of them return 404 or are missing from the list if set, the
+// items still present after the timeout are marked as failed. Waiting stops once the ctx is cancelled.
+func waitForDeletion(ctx context.Context, items []Item, results []Result, list Lister, interval, timeout time.Duration) {
+	pending := map[int]bool{}
+	for i, r := range results {
+		if r.Status == StatusDeleted && (list != nil || items[i].Get != nil) {
+			pending[i] = true
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := wait.PollImmediateUntil(interval, func() (bool, error) {
+		if list != nil {
+			present, err := list()
+			if err != nil {
+				klog.Infof("failed to list the resources, will be retried: %v", err)
+				return false, nil
+			}
+			for i := range pending {
+				if _, ok := present[items[i].ID]; !ok {
+					delete(pending, i)
+				}
+			}
+		} else {
+			for i := range pending {
+				err := items[i].Get()
+				if isNotFound(err) {
+					delete(pending, i)
+					continue
+				}
+				if err != nil {
+					klog.Infof("failed to get the %s, will be retried: %v", items[i].Name, err)
+				}
+			}
+		}
+		if len(pending) != 0 {
+			klog.Infof("Waiting for %d resources to be deleted", len(pending))
+			return false, nil
+		}
+		return true, nil
+	}, ctx.Done())
+	if err == nil {
+		return
+	}
+	msg := fmt.Sprintf("still present after waiting for %s", timeout)
+	if ctx.Err() == context.Canceled {
+		msg = "still present when the waiting was interrupted"
+	}
+	for i := range pending {
+		results[i].Status = StatusFailed
+		results[i].Error = msg
+	}
+}
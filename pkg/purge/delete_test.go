@@ -0,0 +1,202 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package purge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func Test_isNotFound(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil error", nil, false},
+		{"not found", ErrNotFound, true},
+		{"api error", errors.New("pcloud.volumes.get (status 404): {}"), true},
+		{"typed error", fmt.Errorf("failed to perform Get Image Operation for image  abc with error %v", errors.New("[GET /pcloud/v1/cloud-instances/{cloud_instance_id}/images/{image_id}][404] pcloudCloudinstancesImagesGetNotFound")), true},
+		{"server error", errors.New("[GET /pcloud/v1/cloud-instances/{cloud_instance_id}/images/{image_id}][500] pcloudCloudinstancesImagesGetInternalServerError"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isNotFound(tt.err); got != tt.want {
+				t.Errorf("isNotFound() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// failing returns a delete func which fails with err for the first n calls
+func failing(n int, err error) func() error {
+	calls := 0
+	return func() error {
+		calls++
+		if calls <= n {
+			return err
+		}
+		return nil
+	}
+}
+
+func Test_deleteAll(t *testing.T) {
+	serverError := errors.New("(status 500): internal server error")
+	tests := []struct {
+		name        string
+		items       []Item
+		stopOnError bool
+		wantStatus  []string
+	}{
+		{
+			name: "all deleted",
+			items: []Item{
+				{Name: "a", Delete: failing(0, nil)},
+				{Name: "b", Delete: failing(0, nil)},
+			},
+			wantStatus: []string{StatusDeleted, StatusDeleted},
+		},
+		{
+			// retries are left to the client as per the --max-retries
+			name: "not retried on errors",
+			items: []Item{
+				{Name: "a", Delete: failing(1, serverError)},
+				{Name: "b", Delete: failing(0, nil)},
+			},
+			wantStatus: []string{StatusFailed, StatusDeleted},
+		},
+		{
+			name: "protected items are skipped",
+			items: []Item{
+				{Name: "a", Delete: failing(0, nil), SkipReason: "tagged with do-not-delete"},
+				{Name: "b", Delete: failing(0, nil)},
+			},
+			wantStatus: []string{StatusSkipped, StatusDeleted},
+		},
+		{
+			name: "stop on error",
+			items: []Item{
+				{Name: "a", Delete: failing(1, serverError)},
+				{Name: "b", Delete: failing(0, nil)},
+			},
+			stopOnError: true,
+			wantStatus:  []string{StatusFailed, StatusNotAttempted},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// single worker to make the stop on error deterministic
+			results := deleteAll(context.TODO(), tt.items, 1, tt.stopOnError)
+			for i, r := range results {
+				if r.Status != tt.wantStatus[i] {
+					t.Errorf("deleteAll() %s = %s, want %s", r.Name, r.Status, tt.wantStatus[i])
+				}
+			}
+		})
+	}
+}
+
+func Test_deleteAll_parallel(t *testing.T) {
+	var items []Item
+	for i := 0; i < 50; i++ {
+		items = append(items, Item{Name: fmt.Sprintf("item-%d", i), Delete: failing(0, nil)})
+	}
+	for _, r := range deleteAll(context.TODO(), items, 8, false) {
+		if r.Status != StatusDeleted {
+			t.Errorf("deleteAll() %s = %s, want %s", r.Name, r.Status, StatusDeleted)
+		}
+	}
+}
+
+func Test_deleteAll_interrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	items := []Item{
+		// interrupted while the first deletion is in-flight
+		{Name: "a", Delete: func() error { cancel(); return nil }},
+		{Name: "b", Delete: failing(0, nil)},
+		{Name: "c", Delete: failing(0, nil)},
+	}
+	want := []string{StatusDeleted, StatusNotAttempted, StatusNotAttempted}
+	for i, r := range deleteAll(ctx, items, 1, false) {
+		if r.Status != want[i] {
+			t.Errorf("deleteAll() %s = %s, want %s", r.Name, r.Status, want[i])
+		}
+	}
+}
+
+func Test_waitForDeletion(t *testing.T) {
+	notFound := errors.New("[GET /pcloud/v1/cloud-instances/{cloud_instance_id}/volumes/{volume_id}][404] pcloudCloudinstancesVolumesGetNotFound")
+	// gone returns a get func which returns 404 after n calls
+	gone := func(n int) func() error {
+		calls := 0
+		return func() error {
+			calls++
+			if calls > n {
+				return notFound
+			}
+			return nil
+		}
+	}
+	items := []Item{
+		{Name: "gone", Get: gone(0)},
+		{Name: "gone-later", Get: gone(2)},
+		{Name: "leftover", Get: gone(1000)},
+		{Name: "failed", Get: gone(0)},
+	}
+	results := []Result{
+		{Name: "gone", Status: StatusDeleted},
+		{Name: "gone-later", Status: StatusDeleted},
+		{Name: "leftover", Status: StatusDeleted},
+		{Name: "failed", Status: StatusFailed},
+	}
+	waitForDeletion(context.TODO(), items, results, nil, time.Millisecond, 100*time.Millisecond)
+	want := []string{StatusDeleted, StatusDeleted, StatusFailed, StatusFailed}
+	for i, r := range results {
+		if r.Status != want[i] {
+			t.Errorf("waitForDeletion() %s = %s, want %s", r.Name, r.Status, want[i])
+		}
+	}
+	if results[2].Error == "" {
+		t.Errorf("waitForDeletion() leftover error is not set")
+	}
+}
+
+func Test_waitForDeletionWithList(t *testing.T) {
+	items := []Item{{Name: "gone", ID: "1"}, {Name: "gone-later", ID: "2"}, {Name: "leftover", ID: "3"}}
+	results := []Result{
+		{Name: "gone", Status: StatusDeleted},
+		{Name: "gone-later", Status: StatusDeleted},
+		{Name: "leftover", Status: StatusDeleted},
+	}
+	calls := 0
+	list := func() (map[string]bool, error) {
+		calls++
+		if calls > 2 {
+			return map[string]bool{"3": true}, nil
+		}
+		return map[string]bool{"2": true, "3": true}, nil
+	}
+	waitForDeletion(context.TODO(), items, results, list, time.Millisecond, 100*time.Millisecond)
+	want := []string{StatusDeleted, StatusDeleted, StatusFailed}
+	for i, r := range results {
+		if r.Status != want[i] {
+			t.Errorf("waitForDeletion() %s = %s, want %s", r.Name, r.Status, want[i])
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package purge
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg"
+	"github.com/ppc64le-cloud/pvsadm/pkg/client"
+)
+
+const (
+	ActionDelete = "DELETE"
+	ActionSkip   = "SKIPPED"
+
+	// DoNotDeleteTag is the IBM Cloud tag protecting a resource from the purge
+	DoNotDeleteTag = "do-not-delete"
+)
+
+// Matcher matches the resources by the type(pvm-instance, volume, image, network, snapshot or cloud-connection) and ID
+type Matcher interface {
+	Matches(resourceType, id string) (bool, error)
+}
+
+// Protection decides the purge candidates which must never be deleted
+type Protection struct {
+	exclude *regexp.Regexp
+	ids     map[string]bool
+	// tagged matches the resources tagged with DoNotDeleteTag
+	tagged Matcher
+}
+
+// NewProtection returns the protection for the exclude regular expression, IDs listed in the file and the resources
+// matched by the tagged, all of them are optional
+func NewProtection(excludeExpr, idsFile string, tagged Matcher) (*Protection, error) {
+	p := &Protection{ids: map[string]bool{}, tagged: tagged}
+	if excludeExpr != "" {
+		r, err := regexp.Compile(excludeExpr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude regular expression: %v", err)
+		}
+		p.exclude = r
+	}
+	if idsFile != "" {
+		ids, err := readIDs(idsFile)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			p.ids[id] = true
+		}
+	}
+	return p, nil
+}
+
+// NewProtectionWithOptions returns the protection for the --exclude-regexp and --exclude-ids-file options along with
+// the DoNotDeleteTag tagged resources of the instance
+func NewProtectionWithOptions(pvmclient *client.PVMClient) (*Protection, error) {
+	tagged, err := pvmclient.NewTagFilter(pkg.Selector{{Key: DoNotDeleteTag, Operator: pkg.SelectorExists}})
+	if err != nil {
+		return nil, err
+	}
+	return NewProtection(pkg.Options.ExcludeExpr, pkg.Options.ExcludeIDsFile, tagged)
+}
+
+// Reason returns why the resource is protected, empty if the resource can be deleted
+func (p *Protection) Reason(resourceType, name, id string) (string, error) {
+	if p == nil {
+		return "", nil
+	}
+	if p.ids[id] {
+		return "listed in the exclude IDs file", nil
+	}
+	if p.exclude != nil && p.exclude.MatchString(name) {
+		return "matches the exclude regular expression", nil
+	}
+	if p.tagged != nil {
+		tagged, err := p.tagged.Matches(resourceType, id)
+		if err != nil {
+			return "", err
+		}
+		if tagged {
+			return "tagged with " + DoNotDeleteTag, nil
+		}
+	}
+	return "", nil
+}
+
+// Action returns the purge action for the protection reason
+func Action(reason string) string {
+	if reason != "" {
+		return ActionSkip
+	}
+	return ActionDelete
+}
+
+// Deletable returns the number of items not protected
+func Deletable(items ...[]Item) int {
+	var n int
+	for _, list := range items {
+		for _, item := range list {
+			if item.SkipReason == "" {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// readIDs reads the IDs from the file, one ID per line, empty lines and lines starting with # are ignored
+func readIDs(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the exclude IDs file: %v", err)
+	}
+	defer f.Close()
+
+	var ids []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		ids = append(ids, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the exclude IDs file: %v", err)
+	}
+	return ids, nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package purge
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeMatcher matches the resources by ID
+type fakeMatcher map[string]bool
+
+func (f fakeMatcher) Matches(_, id string) (bool, error) {
+	if id == "broken" {
+		return false, errors.New("failed to get the tags")
+	}
+	return f[id], nil
+}
+
+func TestProtection_Reason(t *testing.T) {
+	dir, err := ioutil.TempDir("", "protect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	idsFile := filepath.Join(dir, "ids.txt")
+	if err := ioutil.WriteFile(idsFile, []byte("# keep the bastion volume\nvol-1\n\n  vol-2  \n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := NewProtection("^bastion-.*", idsFile, fakeMatcher{"tagged": true})
+	if err != nil {
+		t.Fatalf("NewProtection() error = %v", err)
+	}
+	tests := []struct {
+		name    string
+		resName string
+		id      string
+		want    string
+		wantErr bool
+	}{
+		{"listed in the file", "vm-1", "vol-1", "listed in the exclude IDs file", false},
+		{"listed with spaces", "vm-2", "vol-2", "listed in the exclude IDs file", false},
+		{"matches the regexp", "bastion-1", "id-1", "matches the exclude regular expression", false},
+		{"tagged", "vm-3", "tagged", "tagged with " + DoNotDeleteTag, false},
+		{"not protected", "vm-4", "id-4", "", false},
+		{"tags lookup failed", "vm-5", "broken", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.Reason("volume", tt.resName, tt.id)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Reason() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Reason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewProtection(t *testing.T) {
+	if _, err := NewProtection("[", "", nil); err == nil {
+		t.Errorf("NewProtection() expected error for the invalid regular expression")
+	}
+	if _, err := NewProtection("", "/non/existent/file", nil); err == nil {
+		t.Errorf("NewProtection() expected error for the missing IDs file")
+	}
+	var p *Protection
+	if got, err := p.Reason("image", "img", "id"); got != "" || err != nil {
+		t.Errorf("Reason() = %q, %v for the nil protection, want empty", got, err)
+	}
+}
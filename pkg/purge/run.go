@@ -0,0 +1,292 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package purge
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"k8s.io/klog/v2"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg"
+	"github.com/ppc64le-cloud/pvsadm/pkg/audit"
+	"github.com/ppc64le-cloud/pvsadm/pkg/client"
+	"github.com/ppc64le-cloud/pvsadm/pkg/client/volume"
+	"github.com/ppc64le-cloud/pvsadm/pkg/utils"
+)
+
+// resourceTypes maps the policy kinds to the resource types of the CRN
+var resourceTypes = map[string]string{
+	"vms":      "pvm-instance",
+	"volumes":  "volume",
+	"images":   "image",
+	"networks": "network",
+}
+
+// PurgeRequest holds the options of the Run
+type PurgeRequest struct {
+	// Policy selects the resources to be purged per PowerVS instance and resource kind
+	Policy *pkg.Policy
+	// ExcludeExpr and ExcludeIDsFile protect the resources along with the DoNotDeleteTag, both are optional
+	ExcludeExpr    string
+	ExcludeIDsFile string
+	// DryRun returns the plan without deleting
+	DryRun bool
+	// IgnoreErrors continues with the deletion after a failure, a failure stops only its own instance otherwise
+	IgnoreErrors bool
+	// Parallel is the number of the resources of a kind deleted at a time, defaults to 1
+	Parallel int
+	// Wait waits up to the WaitTimeout for the deleted resources of a kind to be gone before moving on, the ones still
+	// present are reported as failed
+	Wait        bool
+	WaitTimeout time.Duration
+	// Confirm is called with the plan before the deletion unless DryRun, nothing is deleted if it returns false.
+	// Deleted without the confirmation if not set.
+	Confirm func(plan []*pkg.PurgeCandidate) bool
+}
+
+// PurgeResult is the outcome of the Run
+type PurgeResult struct {
+	// Plan holds the candidates of all the instances along with the action
+	Plan []*pkg.PurgeCandidate
+	// Results holds the outcome of the deletion in the order of the Plan, empty if nothing is deleted
+	Results []Result
+}
+
+// DeletableCandidates returns the number of candidates in the plan not protected
+func DeletableCandidates(plan []*pkg.PurgeCandidate) int {
+	var n int
+	for _, c := range plan {
+		if c.Reason == "" {
+			n++
+		}
+	}
+	return n
+}
+
+// Run evaluates the policy of the request for all the instances and deletes the candidates not protected, kind by
+// kind in the pkg.PolicyKinds order. Outcome of every candidate is recorded in the audit log, if set. A failure doesn't
+// stop the deletion in the other instances, no new deletion is started once the ctx is cancelled.
+func Run(ctx context.Context, c *client.Client, req PurgeRequest) (*PurgeResult, error) {
+	if req.Policy == nil {
+		return nil, fmt.Errorf("policy is required")
+	}
+	if err := req.Policy.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid policy: %v", err)
+	}
+
+	result := &PurgeResult{}
+	// keyed by the instance ID, names of the instances aren't unique
+	pvmclients := map[string]*client.PVMClient{}
+	for _, ins := range req.Policy.Instances {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		pvmclient, err := client.NewPVMClientWithEndpoints(c, ins.ID, ins.Name, c.Environment)
+		if err != nil {
+			return nil, err
+		}
+		pvmclients[pvmclient.InstanceID] = pvmclient
+		tagged, err := pvmclient.NewTagFilter(pkg.Selector{{Key: DoNotDeleteTag, Operator: pkg.SelectorExists}})
+		if err != nil {
+			return nil, err
+		}
+		protection, err := NewProtection(req.ExcludeExpr, req.ExcludeIDsFile, tagged)
+		if err != nil {
+			return nil, err
+		}
+
+		rules := ins.Rules()
+		for _, kind := range pkg.PolicyKinds {
+			rule, ok := rules[kind]
+			if !ok {
+				continue
+			}
+			candidates, err := listCandidates(pvmclient, kind)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get the list of %s for the instance %s: %v", kind, pvmclient.InstanceName, err)
+			}
+			for _, candidate := range rule.Filter(candidates) {
+				if candidate.Reason, err = protection.Reason(resourceTypes[kind], candidate.Name, candidate.ID); err != nil {
+					return nil, err
+				}
+				candidate.Action = Action(candidate.Reason)
+				result.Plan = append(result.Plan, candidate)
+			}
+		}
+	}
+
+	if req.DryRun {
+		return result, nil
+	}
+	if req.Confirm != nil && !req.Confirm(result.Plan) {
+		return result, nil
+	}
+	if DeletableCandidates(result.Plan) == 0 {
+		return result, nil
+	}
+
+	// no new deletion is started in an instance after a failure in it unless IgnoreErrors, left out candidates are
+	// NOT ATTEMPTED, the other instances carry on
+	deleteCtxs, stops := map[string]context.Context{}, map[string]context.CancelFunc{}
+	for id := range pvmclients {
+		deleteCtx, stop := context.WithCancel(ctx)
+		defer stop()
+		deleteCtxs[id] = deleteCtx
+		stops[id] = stop
+	}
+	// candidates of an instance and kind are contiguous in the plan, deleted together
+	var failed int
+	var failedInstances []string
+	for start := 0; start < len(result.Plan); {
+		end := start
+		for end < len(result.Plan) && result.Plan[end].InstanceID == result.Plan[start].InstanceID && result.Plan[end].Kind == result.Plan[start].Kind {
+			end++
+		}
+		instanceID := result.Plan[start].InstanceID
+		pvmclient := pvmclients[instanceID]
+		var items []Item
+		for _, candidate := range result.Plan[start:end] {
+			candidate := candidate
+			items = append(items, Item{
+				Name:       candidate.Name,
+				ID:         candidate.ID,
+				Delete:     func() error { return deleteCandidate(pvmclient, candidate) },
+				Get:        func() error { return getCandidate(pvmclient, candidate) },
+				SkipReason: candidate.Reason,
+			})
+		}
+		results := deleteAll(deleteCtxs[instanceID], items, req.Parallel, !req.IgnoreErrors)
+		if req.Wait && deleteCtxs[instanceID].Err() == nil && Deletable(items) != 0 {
+			klog.Infof("Waiting for the deleted %s of the instance %s to be gone", result.Plan[start].Kind, pvmclient.InstanceName)
+			waitForDeletion(deleteCtxs[instanceID], items, results, nil, WaitInterval, req.WaitTimeout)
+		}
+		var n int
+		for i, r := range results {
+			candidate := result.Plan[start+i]
+			value := candidate.Instance + ":" + candidate.Name
+			switch r.Status {
+			case StatusDeleted:
+				audit.Log(candidate.Kind, "delete", value)
+			case StatusFailed:
+				n++
+				klog.Infof("error occurred while deleting the %s: %s", candidate.Kind, r.Error)
+				audit.Log(candidate.Kind, "delete-failed", value)
+			case StatusSkipped:
+				audit.Log(candidate.Kind, "skip", value)
+			case StatusNotAttempted:
+				audit.Log(candidate.Kind, "not-attempted", value)
+			}
+		}
+		result.Results = append(result.Results, results...)
+		if n != 0 {
+			failed += n
+			if !utils.Contains(failedInstances, pvmclient.InstanceName) {
+				failedInstances = append(failedInstances, pvmclient.InstanceName)
+			}
+			if !req.IgnoreErrors {
+				stops[instanceID]()
+			}
+		}
+		start = end
+	}
+
+	if err := ctx.Err(); err != nil {
+		return result, fmt.Errorf("purge interrupted: %v", err)
+	}
+	if failed != 0 && !req.IgnoreErrors {
+		return result, fmt.Errorf("failed to delete %d resources in the instances: %s", failed, strings.Join(failedInstances, ", "))
+	}
+	return result, nil
+}
+
+// listCandidates returns all the resources of the kind in the PowerVS instance, volumes which are not in available
+// state are left out since they can't be deleted
+func listCandidates(pvmclient *client.PVMClient, kind string) ([]*pkg.PurgeCandidate, error) {
+	var candidates []*pkg.PurgeCandidate
+	add := func(id, name string, date strfmt.DateTime) {
+		candidates = append(candidates, &pkg.PurgeCandidate{Instance: pvmclient.InstanceName, InstanceID: pvmclient.InstanceID, Kind: kind, ID: id, Name: name, Date: date})
+	}
+	switch kind {
+	case "vms":
+		instances, err := pvmclient.InstanceClient.GetAllPurgeable(0, 0, "")
+		if err != nil {
+			return nil, err
+		}
+		for _, ins := range instances {
+			add(*ins.PvmInstanceID, *ins.ServerName, ins.CreationDate)
+		}
+	case "volumes":
+		volumes, err := pvmclient.VolumeClient.GetAllPurgeableByLastUpdateDate(0, 0, "")
+		if err != nil {
+			return nil, err
+		}
+		for _, vol := range volume.FilterByState(volumes, "available") {
+			add(*vol.VolumeID, *vol.Name, *vol.LastUpdateDate)
+		}
+	case "images":
+		images, err := pvmclient.ImgClient.GetAllPurgeable(0, 0, "")
+		if err != nil {
+			return nil, err
+		}
+		for _, image := range images {
+			add(*image.ImageID, *image.Name, *image.CreationDate)
+		}
+	case "networks":
+		networks, err := pvmclient.NetworkClient.GetAllPurgeable(0, 0, "")
+		if err != nil {
+			return nil, err
+		}
+		for _, network := range networks {
+			add(*network.NetworkID, *network.Name, strfmt.DateTime(time.Time{}))
+		}
+	default:
+		return nil, fmt.Errorf("unsupported resource kind: %s", kind)
+	}
+	return candidates, nil
+}
+
+func deleteCandidate(pvmclient *client.PVMClient, candidate *pkg.PurgeCandidate) error {
+	switch candidate.Kind {
+	case "vms":
+		return pvmclient.InstanceClient.Delete(candidate.ID)
+	case "volumes":
+		return pvmclient.VolumeClient.DeleteVolume(candidate.ID)
+	case "images":
+		return pvmclient.ImgClient.Delete(candidate.ID)
+	case "networks":
+		return pvmclient.NetworkClient.Delete(candidate.ID)
+	}
+	return fmt.Errorf("unsupported resource kind: %s", candidate.Kind)
+}
+
+func getCandidate(pvmclient *client.PVMClient, candidate *pkg.PurgeCandidate) (err error) {
+	switch candidate.Kind {
+	case "vms":
+		_, err = pvmclient.InstanceClient.Get(candidate.ID)
+	case "volumes":
+		_, err = pvmclient.VolumeClient.Get(candidate.ID)
+	case "images":
+		_, err = pvmclient.ImgClient.Get(candidate.ID)
+	case "networks":
+		_, err = pvmclient.NetworkClient.Get(candidate.ID)
+	default:
+		err = fmt.Errorf("unsupported resource kind: %s", candidate.Kind)
+	}
+	return err
+}
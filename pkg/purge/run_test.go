@@ -0,0 +1,52 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package purge
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg"
+)
+
+func TestRun_invalidRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		req  PurgeRequest
+	}{
+		{"policy missing", PurgeRequest{}},
+		{"policy without instances", PurgeRequest{Policy: &pkg.Policy{}}},
+		{"invalid rule", PurgeRequest{Policy: &pkg.Policy{Instances: []pkg.PolicyInstance{{Name: "a", VMs: &pkg.PolicyRule{KeepNewest: -1}}}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// validated before using the client
+			if _, err := Run(context.TODO(), nil, tt.req); err == nil {
+				t.Errorf("Run() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestDeletableCandidates(t *testing.T) {
+	plan := []*pkg.PurgeCandidate{
+		{Name: "a"},
+		{Name: "b", Reason: "tagged with " + DoNotDeleteTag},
+		{Name: "c"},
+	}
+	if got := DeletableCandidates(plan); got != 2 {
+		t.Errorf("DeletableCandidates() = %d, want 2", got)
+	}
+}
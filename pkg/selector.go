@@ -0,0 +1,118 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	SelectorEquals    = "="
+	SelectorNotEquals = "!="
+	SelectorExists    = "exists"
+	SelectorNotExists = "!"
+)
+
+// Requirement is a single condition of the Selector, IBM Cloud tags of the form key:value are matched against it
+type Requirement struct {
+	Key      string
+	Operator string
+	Value    string
+}
+
+// Selector selects the resources by the tags, all the requirements must match
+type Selector []Requirement
+
+// ParseSelector parses the comma separated requirements, supported forms are key=value, key==value, key!=value,
+// key(tag with the key exists) and !key(no tag with the key exists)
+func ParseSelector(s string) (Selector, error) {
+	var selector Selector
+	for _, r := range strings.Split(s, ",") {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			continue
+		}
+		var req Requirement
+		switch {
+		case strings.Contains(r, "!="):
+			parts := strings.SplitN(r, "!=", 2)
+			req = Requirement{Key: parts[0], Operator: SelectorNotEquals, Value: parts[1]}
+		case strings.Contains(r, "="):
+			parts := strings.SplitN(strings.Replace(r, "==", "=", 1), "=", 2)
+			req = Requirement{Key: parts[0], Operator: SelectorEquals, Value: parts[1]}
+		case strings.HasPrefix(r, "!"):
+			req = Requirement{Key: strings.TrimPrefix(r, "!"), Operator: SelectorNotExists}
+		default:
+			req = Requirement{Key: r, Operator: SelectorExists}
+		}
+		req.Key, req.Value = strings.TrimSpace(req.Key), strings.TrimSpace(req.Value)
+		if req.Key == "" || strings.ContainsAny(req.Key, "=!:") {
+			return nil, fmt.Errorf("invalid selector requirement: %q", r)
+		}
+		selector = append(selector, req)
+	}
+	return selector, nil
+}
+
+// Matches reports whether the tags satisfy all the requirements, tags are compared case insensitively
+func (s Selector) Matches(tags []string) bool {
+	for _, r := range s {
+		if !r.matches(tags) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r Requirement) matches(tags []string) bool {
+	var hasKey, hasValue bool
+	for _, tag := range tags {
+		parts := strings.SplitN(tag, ":", 2)
+		if !strings.EqualFold(strings.TrimSpace(parts[0]), r.Key) {
+			continue
+		}
+		hasKey = true
+		if len(parts) == 2 && strings.EqualFold(strings.TrimSpace(parts[1]), r.Value) {
+			hasValue = true
+		}
+	}
+	switch r.Operator {
+	case SelectorEquals:
+		return hasValue
+	case SelectorNotEquals:
+		return !hasValue
+	case SelectorExists:
+		return hasKey
+	case SelectorNotExists:
+		return !hasKey
+	}
+	return false
+}
+
+func (s Selector) String() string {
+	var reqs []string
+	for _, r := range s {
+		switch r.Operator {
+		case SelectorExists:
+			reqs = append(reqs, r.Key)
+		case SelectorNotExists:
+			reqs = append(reqs, "!"+r.Key)
+		default:
+			reqs = append(reqs, r.Key+r.Operator+r.Value)
+		}
+	}
+	return strings.Join(reqs, ",")
+}
@@ -0,0 +1,88 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSelector(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    Selector
+		wantErr bool
+	}{
+		{"empty", "", nil, false},
+		{
+			"all the operators",
+			"owner=ci, job==1234,env!=prod,keep,!protected",
+			Selector{
+				{Key: "owner", Operator: SelectorEquals, Value: "ci"},
+				{Key: "job", Operator: SelectorEquals, Value: "1234"},
+				{Key: "env", Operator: SelectorNotEquals, Value: "prod"},
+				{Key: "keep", Operator: SelectorExists},
+				{Key: "protected", Operator: SelectorNotExists},
+			},
+			false,
+		},
+		{"missing key", "=ci", nil, true},
+		{"invalid key", "owner:ci", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSelector(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseSelector() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSelector() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelector_Matches(t *testing.T) {
+	tags := []string{"owner:ci", "job:1234", "Keep"}
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{"", true},
+		{"owner=ci", true},
+		{"owner=CI", true},
+		{"owner=alice", false},
+		{"owner!=alice", true},
+		{"env!=prod", true},
+		{"owner=ci,job!=1234", false},
+		{"keep", true},
+		{"env", false},
+		{"!env", true},
+		{"!keep", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			s, err := ParseSelector(tt.selector)
+			if err != nil {
+				t.Fatalf("ParseSelector() error = %v", err)
+			}
+			if got := s.Matches(tags); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -35,3 +35,55 @@ type TargetItem struct {
 	StorageClass string `yaml:"storageClass"`
 	Region       string `yaml:"region"`
 }
+
+// PublishSpec is the spec of the pvsadm image publish, the image is converted, uploaded to the bucket and imported
+// into the workspaces
+type PublishSpec struct {
+	// Name of the OVA image, <name>.ova.gz, and the imported image unless Import.ImageName is set
+	Name string `yaml:"name"`
+	// File is the OVA image to be published, the Convert is skipped if set
+	File    string          `yaml:"file"`
+	Convert *PublishConvert `yaml:"convert"`
+	Upload  PublishUpload   `yaml:"upload"`
+	Import  PublishImport   `yaml:"import"`
+}
+
+// PublishConvert is the qcow2 to OVA conversion of the PublishSpec
+type PublishConvert struct {
+	URL            string `yaml:"url"`
+	Checksum       string `yaml:"checksum"`
+	Dist           string `yaml:"dist"`
+	Size           uint64 `yaml:"size"`
+	TargetDiskSize int64  `yaml:"targetDiskSize"`
+	OSPassword     string `yaml:"osPassword"`
+	RHNUser        string `yaml:"rhnUser"`
+	RHNPassword    string `yaml:"rhnPassword"`
+	PrepTemplate   string `yaml:"prepTemplate"`
+	TempDir        string `yaml:"tempDir"`
+	// OutputDir is the directory of the OVA image
+	OutputDir string `yaml:"outputDir"`
+}
+
+// PublishUpload is the upload to the bucket of the PublishSpec
+type PublishUpload struct {
+	Cos         string `yaml:"cos"`
+	Bucket      string `yaml:"bucket"`
+	Region      string `yaml:"region"`
+	Object      string `yaml:"object"`
+	PartSize    int64  `yaml:"partSize"`
+	Concurrency int    `yaml:"concurrency"`
+}
+
+// PublishImport is the import into the workspaces of the PublishSpec
+type PublishImport struct {
+	ImageName       string             `yaml:"imageName"`
+	StorageType     string             `yaml:"storageType"`
+	ServiceCredName string             `yaml:"serviceCredName"`
+	Workspaces      []PublishWorkspace `yaml:"workspaces"`
+}
+
+// PublishWorkspace is the PowerVS workspace selected by the name or ID
+type PublishWorkspace struct {
+	Name string `yaml:"name"`
+	ID   string `yaml:"id"`
+}
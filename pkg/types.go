@@ -18,4 +18,6 @@ import "time"
 
 const (
 	TIMEOUT = 60 * time.Minute
+	// DefaultMaxRetries is the default number of retries for the API calls failed with the transient errors
+	DefaultMaxRetries = 5
 )
@@ -16,10 +16,21 @@ package utils
 
 import (
 	"fmt"
+	"io"
+	"os"
+
 	"github.com/manifoldco/promptui"
 	"k8s.io/klog/v2"
 )
 
+// nopCloser keeps the prompt from closing the os.Stderr
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+// AskYesOrNo prompts on the stderr, keeps the stdout clean for the structured output
 func AskYesOrNo(message string) bool {
 	validate := func(input string) error {
 		if input != "yes" && input != "no" {
@@ -31,6 +42,7 @@ func AskYesOrNo(message string) bool {
 	prompt := promptui.Prompt{
 		Label:    message + "[yes/no]",
 		Validate: validate,
+		Stdout:   nopCloser{os.Stderr},
 	}
 
 	result, err := prompt.Run()
@@ -0,0 +1,122 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// ChecksumSHA256 is the algorithm of the checksums, the checksums are formatted as sha256:<hex digest>
+	ChecksumSHA256 = "sha256"
+	// ChecksumSuffix is the suffix of the checksum file written along with the file, in the sha256sum format
+	ChecksumSuffix = ".sha256"
+)
+
+// ParseChecksum returns the hex digest of the checksum formatted as sha256:<hex digest>
+func ParseChecksum(checksum string) (string, error) {
+	parts := strings.SplitN(checksum, ":", 2)
+	if len(parts) != 2 || parts[0] != ChecksumSHA256 {
+		return "", fmt.Errorf("invalid checksum: %s, expected format is %s:<hex digest>", checksum, ChecksumSHA256)
+	}
+	return parseDigest(parts[1])
+}
+
+func parseDigest(digest string) (string, error) {
+	digest = strings.ToLower(digest)
+	if b, err := hex.DecodeString(digest); err != nil || len(b) != sha256.Size {
+		return "", fmt.Errorf("invalid %s digest: %s", ChecksumSHA256, digest)
+	}
+	return digest, nil
+}
+
+// FileSHA256 returns the hex sha256 digest of the file, the progress is reported for the large files
+func FileSHA256(file string) (sum string, err error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	progress := NewProgress("Computing the checksum of "+filepath.Base(file), info.Size())
+	defer func() { progress.Done(err) }()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, progress.Reader(f)); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// VerifyChecksum fails if the sha256 digest of the file isn't the expected hex digest
+func VerifyChecksum(file, expected string) error {
+	sum, err := FileSHA256(file)
+	if err != nil {
+		return err
+	}
+	if sum != expected {
+		return fmt.Errorf("checksum mismatch for %s, got %s:%s, expected %s:%s", file, ChecksumSHA256, sum, ChecksumSHA256, expected)
+	}
+	return nil
+}
+
+// WriteChecksumFile writes the hex sha256 digest of the file into the file + ChecksumSuffix, in the sha256sum format
+func WriteChecksumFile(file, sum string) error {
+	return ioutil.WriteFile(file+ChecksumSuffix, []byte(fmt.Sprintf("%s  %s\n", sum, filepath.Base(file))), 0644)
+}
+
+// ReadChecksumFile returns the hex sha256 digest of the file from the file + ChecksumSuffix, empty if not present
+func ReadChecksumFile(file string) (string, error) {
+	data, err := ioutil.ReadFile(file + ChecksumSuffix)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return LookupChecksum(data, filepath.Base(file))
+}
+
+// LookupChecksum returns the hex sha256 digest of the file name from the sha256sum formatted sums, e.g. SHA256SUMS,
+// the BSD style SHA256 (<name>) = <digest> lines are supported as well
+func LookupChecksum(sums []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		// <digest>  <name> or <digest> *<name> in the binary mode
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return parseDigest(fields[0])
+		}
+		if len(fields) == 4 && fields[0] == "SHA256" && fields[1] == "("+name+")" && fields[2] == "=" {
+			return parseDigest(fields[3])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("checksum of %s not found", name)
+}
@@ -0,0 +1,115 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// sha256 digest of "rhcos image"
+const rhcosSum = "2ba71f2b20e0633c368ade621a36ece0dc1388fab5ca8ae0f27b0e765c87ff5c"
+
+func TestParseChecksum(t *testing.T) {
+	tests := []struct {
+		checksum string
+		want     string
+		wantErr  bool
+	}{
+		{checksum: "sha256:" + rhcosSum, want: rhcosSum},
+		{checksum: "sha256:" + strings.ToUpper(rhcosSum), want: rhcosSum},
+		{checksum: rhcosSum, wantErr: true},
+		{checksum: "md5:" + rhcosSum, wantErr: true},
+		{checksum: "sha256:abc", wantErr: true},
+		{checksum: "sha256:" + strings.Repeat("x", 64), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.checksum, func(t *testing.T) {
+			got, err := ParseChecksum(tt.checksum)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseChecksum() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseChecksum() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChecksumFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "rhcos.ova.gz")
+	if err := ioutil.WriteFile(file, []byte("rhcos image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if sum, err := ReadChecksumFile(file); err != nil || sum != "" {
+		t.Fatalf("ReadChecksumFile() without the checksum file = %q, %v, want empty", sum, err)
+	}
+
+	sum, err := FileSHA256(file)
+	if err != nil {
+		t.Fatalf("FileSHA256() error = %v", err)
+	}
+	if sum != rhcosSum {
+		t.Fatalf("FileSHA256() = %v, want %v", sum, rhcosSum)
+	}
+	if err := VerifyChecksum(file, rhcosSum); err != nil {
+		t.Errorf("VerifyChecksum() error = %v", err)
+	}
+	if err := VerifyChecksum(file, strings.Repeat("0", 64)); err == nil {
+		t.Errorf("VerifyChecksum() of the mismatched checksum succeeded, want an error")
+	}
+
+	if err := WriteChecksumFile(file, sum); err != nil {
+		t.Fatalf("WriteChecksumFile() error = %v", err)
+	}
+	data, err := ioutil.ReadFile(file + ChecksumSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := rhcosSum + "  rhcos.ova.gz\n"; string(data) != want {
+		t.Errorf("checksum file = %q, want %q", data, want)
+	}
+	if got, err := ReadChecksumFile(file); err != nil || got != sum {
+		t.Errorf("ReadChecksumFile() = %q, %v, want %q", got, err, sum)
+	}
+}
+
+func TestLookupChecksum(t *testing.T) {
+	sums := []byte(strings.Repeat("0", 64) + "  centos.qcow2\n" + rhcosSum + " *rhcos.qcow2.gz\n" +
+		"# CentOS-8.qcow2: 1 bytes\nSHA256 (CentOS-8.qcow2) = " + strings.Repeat("1", 64) + "\n")
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "centos.qcow2", want: strings.Repeat("0", 64)},
+		{name: "rhcos.qcow2.gz", want: rhcosSum},
+		{name: "CentOS-8.qcow2", want: strings.Repeat("1", 64)},
+		{name: "rhcos.qcow2", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LookupChecksum(sums, tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LookupChecksum() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("LookupChecksum() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
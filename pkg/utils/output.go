@@ -0,0 +1,171 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v2"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// Supported output formats
+const (
+	OutputTable      = "table"
+	OutputWide       = "wide"
+	OutputJSON       = "json"
+	OutputYAML       = "yaml"
+	OutputCSV        = "csv"
+	OutputJSONPath   = "jsonpath"
+	OutputGoTemplate = "go-template"
+)
+
+// OutputFormats is the list of formats accepted by the --output option
+var OutputFormats = []string{OutputTable, OutputWide, OutputJSON, OutputYAML, OutputCSV,
+	OutputJSONPath + "=<template>", OutputJSONPath + "-file=<file>", OutputGoTemplate + "=<template>", OutputGoTemplate + "-file=<file>"}
+
+// Output holds the format and the template(only for jsonpath and go-template) in which the resources are printed
+type Output struct {
+	Format   string
+	Template string
+}
+
+// ParseOutput parses the --output option value, e.g: json, jsonpath={.items[*].name}, go-template-file=list.tmpl
+func ParseOutput(value string) (*Output, error) {
+	if value == "" {
+		return &Output{Format: OutputTable}, nil
+	}
+	format, tmpl := value, ""
+	if i := strings.Index(value, "="); i != -1 {
+		format, tmpl = value[:i], value[i+1:]
+	}
+	switch format {
+	case OutputTable, OutputWide, OutputJSON, OutputYAML, OutputCSV:
+		if tmpl != "" {
+			return nil, fmt.Errorf("template is not supported for the %s output format", format)
+		}
+		return &Output{Format: format}, nil
+	case OutputJSONPath + "-file", OutputGoTemplate + "-file":
+		content, err := ioutil.ReadFile(tmpl)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the template file: %v", err)
+		}
+		format, tmpl = strings.TrimSuffix(format, "-file"), string(content)
+		fallthrough
+	case OutputJSONPath, OutputGoTemplate:
+		if tmpl == "" {
+			return nil, fmt.Errorf("template is required for the %s output format, e.g: %s=<template>", format, format)
+		}
+		return &Output{Format: format, Template: tmpl}, nil
+	}
+	return nil, fmt.Errorf("invalid output format: \"%s\", supported formats are: %s", value, strings.Join(OutputFormats, ", "))
+}
+
+// Structured returns true for the machine readable formats
+func (o *Output) Structured() bool {
+	return o.Format != OutputTable && o.Format != OutputWide
+}
+
+// print writes the rows in json, yaml, jsonpath or go-template format. Rows are converted into the generic json
+// representation first so that all the formats use the same field names as the PowerVS APIs, templates receive
+// the rows under the items key, e.g: {.items[*].name} or {{range .items}}{{.name}}{{end}}
+func (o *Output) print(w io.Writer, rows interface{}) error {
+	items, err := toGeneric(rows)
+	if err != nil {
+		return err
+	}
+	return o.write(w, items, map[string]interface{}{"items": items})
+}
+
+// printItem writes a single model, templates receive the item itself, e.g: {.name} or {{.name}}
+func (o *Output) printItem(w io.Writer, item interface{}) error {
+	var obj interface{}
+	content, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(content, &obj); err != nil {
+		return err
+	}
+	return o.write(w, obj, obj)
+}
+
+func (o *Output) write(w io.Writer, v, data interface{}) error {
+	switch o.Format {
+	case OutputJSON:
+		content, err := json.MarshalIndent(v, "", "    ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(content))
+		return err
+	case OutputYAML:
+		content, err := yaml.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(content)
+		return err
+	case OutputJSONPath:
+		j := jsonpath.New("output")
+		if err := j.Parse(o.Template); err != nil {
+			return fmt.Errorf("failed to parse the jsonpath template: %v", err)
+		}
+		return j.Execute(w, data)
+	case OutputGoTemplate:
+		t, err := template.New("output").Parse(o.Template)
+		if err != nil {
+			return fmt.Errorf("failed to parse the go-template: %v", err)
+		}
+		return t.Execute(w, data)
+	}
+	return fmt.Errorf("unsupported output format: %s", o.Format)
+}
+
+// toGeneric converts the rows into []interface{} via json marshalling, nil or non slice values result an empty list
+func toGeneric(rows interface{}) ([]interface{}, error) {
+	items := []interface{}{}
+	if rows == nil || reflect.TypeOf(rows).Kind() != reflect.Slice || reflect.ValueOf(rows).Len() == 0 {
+		return items, nil
+	}
+	content, err := json.Marshal(rows)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func writeCSV(w io.Writer, headers []string, data [][]string) error {
+	cw := csv.NewWriter(w)
+	if headers != nil {
+		if err := cw.Write(headers); err != nil {
+			return err
+		}
+	}
+	if err := cw.WriteAll(data); err != nil {
+		return err
+	}
+	return cw.Error()
+}
@@ -0,0 +1,162 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+type sample struct {
+	Name *string `json:"name"`
+	Href string  `json:"href"`
+	Size int64   `json:"size"`
+}
+
+func TestParseOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    Output
+		wantErr bool
+	}{
+		{"default", "", Output{Format: OutputTable}, false},
+		{"json", "json", Output{Format: OutputJSON}, false},
+		{"jsonpath", "jsonpath={.items[*].name}", Output{Format: OutputJSONPath, Template: "{.items[*].name}"}, false},
+		{"go-template", "go-template={{len .items}}", Output{Format: OutputGoTemplate, Template: "{{len .items}}"}, false},
+		{"jsonpath without template", "jsonpath", Output{}, true},
+		{"template for json", "json={.items}", Output{}, true},
+		{"invalid format", "xml", Output{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseOutput(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseOutput() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && *got != tt.want {
+				t.Errorf("ParseOutput() got = %v, want %v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTable_Render(t *testing.T) {
+	name := "vm-1"
+	rows := []*sample{{Name: &name, Href: "/vm-1", Size: 10}}
+	tests := []struct {
+		name   string
+		output string
+		rows   interface{}
+		want   string
+	}{
+		{"csv", "csv", rows, "Name,Size\nvm-1,10\n"},
+		{"json", "json", rows, "[\n    {\n        \"href\": \"/vm-1\",\n        \"name\": \"vm-1\",\n        \"size\": 10\n    }\n]\n"},
+		{"json with no data", "json", []*sample(nil), "[]\n"},
+		{"yaml", "yaml", rows, "- href: /vm-1\n  name: vm-1\n  size: 10\n"},
+		{"jsonpath", "jsonpath={.items[*].name}", rows, "vm-1"},
+		{"go-template", "go-template={{range .items}}{{.href}}{{end}}", rows, "/vm-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := ParseOutput(tt.output)
+			if err != nil {
+				t.Fatalf("ParseOutput() error = %v", err)
+			}
+			var buf bytes.Buffer
+			if err := NewTableWithOutput(&buf, output).Render(tt.rows, []string{"href"}); err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("Render() got = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTable_RenderTable(t *testing.T) {
+	name := "vm-1"
+	var buf bytes.Buffer
+	if err := NewTableWithOutput(&buf, &Output{Format: OutputWide}).Render([]*sample{{Name: &name, Href: "/vm-1"}}, []string{"href"}); err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "/vm-1") {
+		t.Errorf("Render() wide output doesn't contain the excluded field: %s", buf.String())
+	}
+}
+
+func TestTable_RenderEmbedded(t *testing.T) {
+	type decision struct {
+		*sample
+		Action string `json:"action"`
+	}
+	name := "vm-1"
+	rows := []decision{{&sample{Name: &name, Href: "/vm-1", Size: 10}, "SKIPPED"}}
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{"csv", "csv", "Name,Size,Action\nvm-1,10,SKIPPED\n"},
+		{"jsonpath", "jsonpath={.items[0].name} {.items[0].action}", "vm-1 SKIPPED"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := ParseOutput(tt.output)
+			if err != nil {
+				t.Fatalf("ParseOutput() error = %v", err)
+			}
+			var buf bytes.Buffer
+			if err := NewTableWithOutput(&buf, output).Render(rows, []string{"href"}); err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("Render() got = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTable_RenderItem(t *testing.T) {
+	name := "vm-1"
+	item := &sample{Name: &name, Href: "/vm-1", Size: 10}
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{"json", "json", "{\n    \"href\": \"/vm-1\",\n    \"name\": \"vm-1\",\n    \"size\": 10\n}\n"},
+		{"jsonpath", "jsonpath={.name}", "vm-1"},
+		{"go-template", "go-template={{.size}}", "10"},
+		{"csv", "csv", "Name,Size\nvm-1,10\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := ParseOutput(tt.output)
+			if err != nil {
+				t.Fatalf("ParseOutput() error = %v", err)
+			}
+			var buf bytes.Buffer
+			if err := NewTableWithOutput(&buf, output).RenderItem(item, []string{"href"}); err != nil {
+				t.Fatalf("RenderItem() error = %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("RenderItem() got = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
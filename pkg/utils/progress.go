@@ -0,0 +1,252 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+var (
+	// ProgressLogInterval is the interval between the progress log lines when the stderr isn't a terminal
+	ProgressLogInterval = 30 * time.Second
+	// progressRefresh is the interval between the redraws of the progress bar on the terminal
+	progressRefresh = 500 * time.Millisecond
+	// progressOut is the terminal of the progress bar, nil if the stderr isn't a terminal
+	progressOut = terminal(os.Stderr)
+)
+
+const progressBarWidth = 30
+
+// Progress reports the bytes transferred along with the rate and the ETA, as a progress bar redrawn on the terminal
+// or as the periodic log lines otherwise. Progress is safe for the concurrent use.
+type Progress struct {
+	title string
+	// total is 0 if unknown
+	total int64
+	// done includes the initial bytes transferred before the start, e.g. of the resumed upload
+	done    int64
+	initial int64
+	start   time.Time
+	stop    chan struct{}
+	wg      sync.WaitGroup
+	once    sync.Once
+}
+
+// NewProgress starts reporting the progress of the transfer of the total bytes, 0 if unknown, Done must be called
+// once the transfer is over
+func NewProgress(title string, total int64) *Progress {
+	if total < 0 {
+		total = 0
+	}
+	p := &Progress{title: title, total: total, start: time.Now(), stop: make(chan struct{})}
+	p.wg.Add(1)
+	go p.run()
+	return p
+}
+
+// Resume marks the bytes already transferred before the start, they aren't counted for the rate
+func (p *Progress) Resume(n int64) {
+	atomic.AddInt64(&p.initial, n)
+	atomic.AddInt64(&p.done, n)
+}
+
+// Add adds the transferred bytes
+func (p *Progress) Add(n int64) {
+	atomic.AddInt64(&p.done, n)
+}
+
+// Reader counts the bytes read from the r
+func (p *Progress) Reader(r io.Reader) io.Reader {
+	return &progressReader{r: r, p: p}
+}
+
+// Writer counts the bytes written to the w
+func (p *Progress) Writer(w io.Writer) io.Writer {
+	return &progressWriter{w: w, p: p}
+}
+
+// ReadSeeker counts the bytes read from the r, the bytes read again after seeking back, e.g. by the retries of the
+// request, are counted once
+func (p *Progress) ReadSeeker(r io.ReadSeeker) io.ReadSeeker {
+	return &progressReadSeeker{r: r, p: p}
+}
+
+// Done stops the reporting and logs the summary of the transfer, the err is the outcome of the transfer
+func (p *Progress) Done(err error) {
+	p.once.Do(func() {
+		close(p.stop)
+		p.wg.Wait()
+		if progressOut != nil {
+			fmt.Fprintf(progressOut, "\r%s\n", p.bar())
+		}
+		elapsed := time.Since(p.start)
+		done := atomic.LoadInt64(&p.done)
+		if err != nil {
+			klog.Infof("%s stopped after %s of %s in %s", p.title, FormatBytes(done), p.formatTotal(), elapsed.Round(time.Second))
+			return
+		}
+		klog.Infof("%s completed, %s in %s(%s/s)", p.title, FormatBytes(done), elapsed.Round(time.Second), FormatBytes(p.rate()))
+	})
+}
+
+func (p *Progress) run() {
+	defer p.wg.Done()
+	interval := ProgressLogInterval
+	if progressOut != nil {
+		interval = progressRefresh
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			if progressOut != nil {
+				fmt.Fprintf(progressOut, "\r%s", p.bar())
+			} else {
+				klog.Infof("%s: %s", p.title, p.status())
+			}
+		}
+	}
+}
+
+// rate returns the bytes transferred per second since the start
+func (p *Progress) rate() int64 {
+	elapsed := time.Since(p.start).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return int64(float64(atomic.LoadInt64(&p.done)-atomic.LoadInt64(&p.initial)) / elapsed)
+}
+
+// eta returns the estimated time to complete, 0 if unknown
+func (p *Progress) eta() time.Duration {
+	rate, done := p.rate(), atomic.LoadInt64(&p.done)
+	if p.total == 0 || rate == 0 || done >= p.total {
+		return 0
+	}
+	return time.Duration(float64(p.total-done)/float64(rate)) * time.Second
+}
+
+func (p *Progress) formatTotal() string {
+	if p.total == 0 {
+		return "unknown"
+	}
+	return FormatBytes(p.total)
+}
+
+// status returns the progress, e.g. 45% 1.2 GiB of 2.6 GiB, 25.3 MiB/s, ETA 1m2s
+func (p *Progress) status() string {
+	done := atomic.LoadInt64(&p.done)
+	if p.total == 0 {
+		return fmt.Sprintf("%s, %s/s", FormatBytes(done), FormatBytes(p.rate()))
+	}
+	s := fmt.Sprintf("%d%% %s of %s, %s/s", done*100/p.total, FormatBytes(done), FormatBytes(p.total), FormatBytes(p.rate()))
+	if eta := p.eta(); eta != 0 {
+		s += ", ETA " + eta.Round(time.Second).String()
+	}
+	return s
+}
+
+// bar returns the progress bar, the bar is left out if the total is unknown
+func (p *Progress) bar() string {
+	if p.total == 0 {
+		return fmt.Sprintf("%s %s", p.title, p.status())
+	}
+	filled := int(atomic.LoadInt64(&p.done) * progressBarWidth / p.total)
+	if filled > progressBarWidth {
+		filled = progressBarWidth
+	}
+	return fmt.Sprintf("%s [%s%s] %s", p.title, strings.Repeat("=", filled), strings.Repeat(" ", progressBarWidth-filled), p.status())
+}
+
+type progressReader struct {
+	r io.Reader
+	p *Progress
+}
+
+func (r *progressReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	r.p.Add(int64(n))
+	return n, err
+}
+
+type progressWriter struct {
+	w io.Writer
+	p *Progress
+}
+
+func (w *progressWriter) Write(b []byte) (int, error) {
+	n, err := w.w.Write(b)
+	w.p.Add(int64(n))
+	return n, err
+}
+
+type progressReadSeeker struct {
+	r io.ReadSeeker
+	p *Progress
+	// offset is the current offset, read is the highest offset read so far
+	offset, read int64
+}
+
+func (r *progressReadSeeker) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	r.offset += int64(n)
+	if r.offset > r.read {
+		r.p.Add(r.offset - r.read)
+		r.read = r.offset
+	}
+	return n, err
+}
+
+func (r *progressReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	n, err := r.r.Seek(offset, whence)
+	if err == nil {
+		r.offset = n
+	}
+	return n, err
+}
+
+// FormatBytes formats the bytes in the binary units, e.g. 1.5 GiB
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// terminal returns the f if it is a terminal, nil otherwise
+func terminal(f *os.File) *os.File {
+	info, err := f.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return nil
+	}
+	return f
+}
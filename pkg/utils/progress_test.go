@@ -0,0 +1,87 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestProgress(t *testing.T) {
+	data := []byte(strings.Repeat("x", 1000))
+	p := NewProgress("Uploading rhcos.ova.gz", 3000)
+	defer p.Done(nil)
+
+	if _, err := io.Copy(ioutil.Discard, p.Reader(bytes.NewReader(data))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Writer(ioutil.Discard).Write(data); err != nil {
+		t.Fatal(err)
+	}
+	// the bytes read again after seeking back are not counted
+	rs := p.ReadSeeker(bytes.NewReader(data))
+	for i := 0; i < 2; i++ {
+		if _, err := io.Copy(ioutil.Discard, rs); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := rs.Seek(0, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if p.done != 3000 {
+		t.Errorf("done = %d, want 3000", p.done)
+	}
+	if status := p.status(); !strings.HasPrefix(status, "100% 2.9 KiB of 2.9 KiB") {
+		t.Errorf("status() = %q, want 100%% 2.9 KiB of 2.9 KiB", status)
+	}
+	if bar := p.bar(); !strings.Contains(bar, "["+strings.Repeat("=", progressBarWidth)+"]") {
+		t.Errorf("bar() = %q, want the full bar", bar)
+	}
+}
+
+func TestProgressResume(t *testing.T) {
+	p := NewProgress("Uploading rhcos.ova.gz", 0)
+	defer p.Done(nil)
+	p.Resume(500)
+	p.Add(100)
+	if p.done != 600 || p.initial != 500 {
+		t.Errorf("done, initial = %d, %d, want 600, 500", p.done, p.initial)
+	}
+	if bar := p.bar(); strings.Contains(bar, "[") || !strings.Contains(bar, "600 B") {
+		t.Errorf("bar() = %q, want the bytes without the bar for the unknown total", bar)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{5 * 1024 * 1024, "5.0 MiB"},
+		{3 * 1024 * 1024 * 1024 / 2, "1.5 GiB"},
+	}
+	for _, tt := range tests {
+		if got := FormatBytes(tt.n); got != tt.want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2021 IBM Corp
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"k8s.io/klog/v2"
+)
+
+// ExitInterrupted is the exit status of the commands interrupted with SIGINT or SIGTERM
+const ExitInterrupted = 130
+
+// InterruptContext returns the context cancelled on the first SIGINT or SIGTERM, letting the commands stop scheduling
+// the new work and wrap up the in-flight one. Second signal exits immediately with the ExitInterrupted.
+func InterruptContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	c := make(chan os.Signal, 2)
+	signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-c:
+		case <-ctx.Done():
+			signal.Stop(c)
+			return
+		}
+		klog.Infof("Received an interrupt, finishing the in-flight operations, interrupt again to exit immediately")
+		cancel()
+		<-c
+		klog.Infof("Received a second interrupt, exiting")
+		os.Exit(ExitInterrupted)
+	}()
+	return ctx, cancel
+}
+
+// Interrupted tells whether the context is cancelled
+func Interrupted(ctx context.Context) bool {
+	return ctx.Err() == context.Canceled
+}
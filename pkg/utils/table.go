@@ -16,23 +16,43 @@ package utils
 
 import (
 	"fmt"
-	"github.com/go-openapi/strfmt"
-	"github.com/olekukonko/tablewriter"
+	"io"
 	"os"
 	"reflect"
 	"strconv"
 	"strings"
 	"sync"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/olekukonko/tablewriter"
+
+	"github.com/ppc64le-cloud/pvsadm/pkg"
 )
 
 type Table struct {
 	*tablewriter.Table
 	doOnce sync.Once
+	writer io.Writer
+	output *Output
 }
 
+// NewTable returns a table writing to the stdout in the output format set via the --output option
 func NewTable() *Table {
-	t := &Table{}
-	t.Table = tablewriter.NewWriter(os.Stdout)
+	output, err := ParseOutput(pkg.Options.Output)
+	if err != nil {
+		// Output format is validated while parsing the options, fallback to the table format here
+		output = &Output{Format: OutputTable}
+	}
+	return NewTableWithOutput(os.Stdout, output)
+}
+
+// NewTableWithOutput returns a table writing to w in the output format mentioned
+func NewTableWithOutput(w io.Writer, output *Output) *Table {
+	t := &Table{
+		writer: w,
+		output: output,
+	}
+	t.Table = tablewriter.NewWriter(w)
 	return t
 }
 
@@ -42,32 +62,113 @@ func (t *Table) SetHeader(keys []string) {
 	})
 }
 
-func (t *Table) Render(rows interface{}, exclude []string) {
-	noData := true
-	switch reflect.TypeOf(rows).Kind() {
-	case reflect.Slice:
-		s := reflect.ValueOf(rows)
-		for i := 0; i < s.Len(); i++ {
-			noData = false
-			var headers []string
-			val := s.Index(i).Elem()
-			var row []string
-			for i := 0; i < val.NumField(); i++ {
-				if f := strings.ToLower(val.Type().Field(i).Name); Contains(exclude, f) {
-					continue
-				}
-				headers = append(headers, val.Type().Field(i).Name)
-				content := reflect.Indirect(reflect.ValueOf(val.Field(i).Interface()))
-				row = append(row, getcontent(content))
-			}
-			t.SetHeader(headers)
-			t.Append(row)
-		}
+// Structured returns true if the table is going to be rendered in a machine readable format
+func (t *Table) Structured() bool {
+	return t.output.Structured()
+}
+
+// Wide returns true if the table is going to be rendered in the wide format, i.e. with the additional columns
+func (t *Table) Wide() bool {
+	return t.output.Format == OutputWide
+}
+
+// Render prints the rows(slice of model pointers) in the output format selected for the table, fields mentioned
+// in the exclude list are skipped for the table and csv formats
+func (t *Table) Render(rows interface{}, exclude []string) error {
+	switch t.output.Format {
+	case OutputJSON, OutputYAML, OutputJSONPath, OutputGoTemplate:
+		return t.output.print(t.writer, rows)
+	case OutputWide:
+		exclude = nil
 	}
-	if noData {
-		fmt.Println("\n--NO DATA FOUND--")
+
+	headers, data := tabulate(rows, exclude)
+	if t.output.Format == OutputCSV {
+		return writeCSV(t.writer, headers, data)
 	}
+	if len(data) == 0 {
+		fmt.Fprintln(t.writer, "\n--NO DATA FOUND--")
+	}
+	t.SetHeader(headers)
+	t.AppendBulk(data)
 	t.Table.Render()
+	return nil
+}
+
+// RenderItem prints a single model(e.g: a resource fetched by ID) in the output format selected for the table, the
+// table format lists one field per row
+func (t *Table) RenderItem(item interface{}, exclude []string) error {
+	switch t.output.Format {
+	case OutputJSON, OutputYAML, OutputJSONPath, OutputGoTemplate:
+		return t.output.printItem(t.writer, item)
+	case OutputCSV:
+		return t.Render(sliceOf(item), exclude)
+	case OutputWide:
+		exclude = nil
+	}
+
+	headers, data := tabulate(sliceOf(item), exclude)
+	if len(data) == 0 {
+		fmt.Fprintln(t.writer, "\n--NO DATA FOUND--")
+	}
+	t.SetHeader([]string{"Field", "Value"})
+	for _, row := range data {
+		for i := range row {
+			t.Append([]string{headers[i], row[i]})
+		}
+	}
+	t.Table.Render()
+	return nil
+}
+
+// sliceOf wraps the item into a slice of its own type
+func sliceOf(item interface{}) interface{} {
+	if item == nil {
+		return nil
+	}
+	s := reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(item)), 0, 1)
+	return reflect.Append(s, reflect.ValueOf(item)).Interface()
+}
+
+// tabulate reflects over the slice of models and returns the headers and the rows with the fields in string format
+func tabulate(rows interface{}, exclude []string) (headers []string, data [][]string) {
+	if rows == nil || reflect.TypeOf(rows).Kind() != reflect.Slice {
+		return
+	}
+	s := reflect.ValueOf(rows)
+	for i := 0; i < s.Len(); i++ {
+		val := reflect.Indirect(s.Index(i))
+		if val.Kind() != reflect.Struct {
+			continue
+		}
+		h, row := fields(val, exclude)
+		if headers == nil {
+			headers = h
+		}
+		data = append(data, row)
+	}
+	return
+}
+
+// fields returns the names and the values of the struct fields, fields of the embedded structs are flattened
+func fields(val reflect.Value, exclude []string) (headers []string, row []string) {
+	for i := 0; i < val.NumField(); i++ {
+		field := val.Type().Field(i)
+		if field.Anonymous {
+			if embedded := reflect.Indirect(val.Field(i)); embedded.Kind() == reflect.Struct {
+				h, r := fields(embedded, exclude)
+				headers, row = append(headers, h...), append(row, r...)
+				continue
+			}
+		}
+		if f := strings.ToLower(field.Name); Contains(exclude, f) {
+			continue
+		}
+		headers = append(headers, field.Name)
+		content := reflect.Indirect(reflect.ValueOf(val.Field(i).Interface()))
+		row = append(row, getcontent(content))
+	}
+	return
 }
 
 func getcontent(value reflect.Value) (strVal string) {
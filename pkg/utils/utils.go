@@ -14,7 +14,10 @@
 
 package utils
 
-import "strconv"
+import (
+	"reflect"
+	"strconv"
+)
 
 func FormatProcessor(proc *float64) string {
 	return strconv.FormatFloat(*proc, 'f', -1, 64)
@@ -24,6 +27,11 @@ func FormatMemory(memory *float64) string {
 	return strconv.FormatFloat(*memory, 'f', -1, 64)
 }
 
+// FormatValue returns the value in the same format as the table cells, empty for the nil pointers
+func FormatValue(v interface{}) string {
+	return getcontent(reflect.Indirect(reflect.ValueOf(v)))
+}
+
 func Contains(s []string, e string) bool {
 	for _, a := range s {
 		if a == e {
@@ -15,6 +15,7 @@
 package sync
 
 import (
+	"context"
 	"errors"
 	"io/fs"
 	"io/ioutil"
@@ -142,7 +143,7 @@ func createSpecFile(spec []pkg.Spec) error {
 // Create Cloud Object Storage Service instance
 func createCOSInstance(instanceName string) error {
 	klog.Infoln("STEP: Creating COS instance :", instanceName)
-	bxCli, err := client.NewClientWithEnv(APIKey, client.DefaultEnv, Debug)
+	bxCli, err := client.NewClientWithEnv(context.TODO(), APIKey, client.DefaultEnv, Debug)
 	if err != nil {
 		klog.Errorf("ERROR: %v", err)
 		return err
@@ -176,7 +177,7 @@ func createCOSInstance(instanceName string) error {
 // Delete Cloud Object Storage Service instance
 func deleteCOSInstance(instanceName string) error {
 	klog.Infoln("STEP: Deleting COS instance", instanceName)
-	bxCli, err := client.NewClientWithEnv(APIKey, client.DefaultEnv, Debug)
+	bxCli, err := client.NewClientWithEnv(context.TODO(), APIKey, client.DefaultEnv, Debug)
 	if err != nil {
 		klog.Errorf("ERROR: %v", err)
 		return err
@@ -208,7 +209,7 @@ func deleteCOSInstance(instanceName string) error {
 // Create S3 bucket in the given region and storage class
 func createBucket(bucketName string, cos string, region string, storageClass string) error {
 	klog.Infof("STEP: Creating Bucket %s in region %s in COS %s storageClass %s", bucketName, region, cos, storageClass)
-	bxCli, err := client.NewClientWithEnv(APIKey, client.DefaultEnv, Debug)
+	bxCli, err := client.NewClientWithEnv(context.TODO(), APIKey, client.DefaultEnv, Debug)
 	if err != nil {
 		klog.Errorf("ERROR: %v", err)
 		return err
@@ -295,7 +296,7 @@ func deleteTempFiles() error {
 func uploadWorker(s3Cli *client.S3Client, bucketName string, workerId int, filepaths <-chan string, results chan<- bool) {
 	for filepath := range filepaths {
 		fileName := strings.Split(filepath, "/")[len(strings.Split(filepath, "/"))-1]
-		err := s3Cli.UploadObject(filepath, fileName, bucketName)
+		err := s3Cli.UploadObject(context.TODO(), filepath, fileName, bucketName)
 		if err != nil {
 			klog.Errorf("ERROR: %v, File %s upload failed", err, filepath)
 			results <- false
@@ -314,7 +315,7 @@ func uploadObjects(src pkg.Source) error {
 		return err
 	}
 
-	bxCli, err := client.NewClientWithEnv(APIKey, client.DefaultEnv, Debug)
+	bxCli, err := client.NewClientWithEnv(context.TODO(), APIKey, client.DefaultEnv, Debug)
 	if err != nil {
 		klog.Errorf("ERROR: %v", err)
 		return err
@@ -351,7 +352,7 @@ func uploadObjects(src pkg.Source) error {
 // Verify the copied Objects exists in the target bucket
 func verifyBucketObjects(tgt pkg.TargetItem, cos string, files []fs.FileInfo, regex string) error {
 	klog.Infoln("STEP: Verify objects in Bucket ", tgt.Bucket)
-	bxCli, err := client.NewClientWithEnv(APIKey, client.DefaultEnv, Debug)
+	bxCli, err := client.NewClientWithEnv(context.TODO(), APIKey, client.DefaultEnv, Debug)
 	if err != nil {
 		klog.Errorf("ERROR: %v", err)
 		return err